@@ -140,13 +140,17 @@ func AddDevFlags(cmd *cobra.Command) {
 
 func AddRunDeployFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolVar(&opts.Tail, "tail", false, "Stream logs from deployed objects")
+	cmd.Flags().StringVar(&opts.Selector, "selector", "", "Only deploy the kustomize resources matching this label selector")
+	cmd.Flags().StringSliceVar(&opts.ImageOverrides, "image-override", nil, "Deploy this image instead of the built one, as name=image, with the kustomize deployer")
+	cmd.Flags().BoolVar(&opts.RefreshKustomizeCache, "refresh-kustomize-cache", false, "Empty the kustomize cache directory before the first kustomize build")
 }
 
 func AddRunDevFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&opts.ConfigurationFile, "filename", "f", "skaffold.yaml", "Filename or URL to the pipeline file")
 	cmd.Flags().BoolVar(&opts.Notification, "toot", false, "Emit a terminal beep after the deploy is complete")
 	cmd.Flags().StringArrayVarP(&opts.Profiles, "profile", "p", nil, "Activate profiles by name")
-	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "", "Run Helm deployments in the specified namespace")
+	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "", "Run Helm, kubectl and kustomize deployments in the specified namespace")
+	cmd.Flags().StringVar(&opts.OverlaySelector, "overlay-selector", "", "Only deploy the kustomize overlays matching this label selector")
 }
 
 func AddFixFlags(cmd *cobra.Command) {
@@ -35,6 +35,7 @@ func NewCmdDelete(out io.Writer) *cobra.Command {
 		},
 	}
 	AddRunDevFlags(cmd)
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Only print the resources that would be deleted")
 	return cmd
 }
 
@@ -28,7 +28,9 @@ import (
 )
 
 var (
-	images []string
+	images     []string
+	plan       bool
+	planFormat string
 )
 
 // NewCmdDeploy describes the CLI command to deploy artifacts.
@@ -45,6 +47,8 @@ func NewCmdDeploy(out io.Writer) *cobra.Command {
 	AddRunDeployFlags(cmd)
 	cmd.Flags().StringSliceVar(&images, "images", nil, "A list of images to deploy")
 	cmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "Suppress the deploy output")
+	cmd.Flags().BoolVar(&plan, "plan", false, "Print what the deploy would change, without changing the cluster")
+	cmd.Flags().StringVar(&planFormat, "plan-format", "text", "Format of the plan: text or json")
 	return cmd
 }
 
@@ -75,6 +79,10 @@ func runDeploy(out io.Writer) error {
 		})
 	}
 
+	if plan {
+		return r.Plan(ctx, out, builds, planFormat)
+	}
+
 	if _, err := r.Deploy(ctx, deployOut, builds); err != nil {
 		return err
 	}
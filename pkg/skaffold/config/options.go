@@ -23,15 +23,21 @@ import (
 // SkaffoldOptions are options that are set by command line arguments not included
 // in the config file itself
 type SkaffoldOptions struct {
-	ConfigurationFile string
-	Cleanup           bool
-	Notification      bool
-	Tail              bool
-	Profiles          []string
-	CustomTag         string
-	Namespace         string
-	Watch             []string
-	WatchPollInterval int
+	ConfigurationFile     string
+	Cleanup               bool
+	Notification          bool
+	Tail                  bool
+	Profiles              []string
+	CustomTag             string
+	Namespace             string
+	Watch                 []string
+	WatchPollInterval     int
+	OverlaySelector       string
+	DryRun                bool
+	Selector              string
+	DevMode               bool
+	ImageOverrides        []string
+	RefreshKustomizeCache bool
 }
 
 // Labels returns a map of labels to be applied to all deployed
@@ -45,6 +45,11 @@ const (
 	HelmOverridesFilename = "skaffold-overrides.yaml"
 
 	DefaultKustomizationPath = "."
+	DefaultReadinessTimeout  = "5m"
+	DefaultDeployLockName    = "skaffold-deploy-lock"
+
+	DefaultDevProbeInitialDelaySeconds = 0
+	DefaultDevProbePeriodSeconds       = 2
 
 	DefaultKanikoImage      = "gcr.io/kaniko-project/executor:v0.2.0@sha256:bebe80bb97950d88b8d8eab315a58e0bc50307135cf25147d7e0b8f3db50a84a"
 	DefaultKanikoSecretName = "kaniko-secret"
@@ -59,11 +64,17 @@ var DefaultKubectlManifests = []string{"k8s/*.yaml"}
 
 var LatestDownloadURL = fmt.Sprintf("https://storage.googleapis.com/skaffold/releases/latest/skaffold-%s-%s", runtime.GOOS, runtime.GOARCH)
 
+// SkipLabelsAnnotation, set to "true" on a resource, stops skaffold from labelling it.
+// Without the skaffold labels, the resource is invisible to label-based prune
+// and to cleanupByLabel: it must be deleted by other means.
+const SkipLabelsAnnotation = "skaffold.dev/skip-labels"
+
 var Labels = struct {
 	TagPolicy        string
 	Deployer         string
 	Builder          string
 	DockerAPIVersion string
+	Kustomization    string
 	DefaultLabels    map[string]string
 }{
 	DefaultLabels: map[string]string{
@@ -73,4 +84,5 @@ var Labels = struct {
 	Deployer:         "skaffold-deployer",
 	Builder:          "skaffold-builder",
 	DockerAPIVersion: "docker-api-version",
+	Kustomization:    "skaffold-kustomization",
 }
@@ -0,0 +1,52 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+)
+
+func applyOutputFormat(cfg *v1alpha3.ApplyOutput) string {
+	if cfg == nil {
+		return ""
+	}
+	if cfg.Format == "" {
+		return "json"
+	}
+	return cfg.Format
+}
+
+// writeAppliedObjects writes the applied objects to a file, as a List.
+func writeAppliedObjects(path string, objects []map[string]interface{}) error {
+	if objects == nil {
+		objects = []map[string]interface{}{}
+	}
+
+	buf, err := json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      objects,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(buf, '\n'), 0644)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/version"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// deployBundleVersion is the version of the bundle format.
+const deployBundleVersion = "skaffold/deploy-bundle/v1"
+
+// deployBundle is everything a deploy gave to `kubectl apply`, so that it can
+// be replayed without rendering the manifests again. The kubectl environment
+// isn't recorded since it often holds credentials.
+type deployBundle struct {
+	Version         string                `yaml:"version"`
+	SkaffoldVersion string                `yaml:"skaffoldVersion,omitempty"`
+	KubeContext     string                `yaml:"kubeContext,omitempty"`
+	Namespace       string                `yaml:"namespace,omitempty"`
+	Flags           v1alpha3.KubectlFlags `yaml:"flags,omitempty"`
+	Builds          []bundledArtifact     `yaml:"builds,omitempty"`
+	Manifests       string                `yaml:"manifests"`
+}
+
+type bundledArtifact struct {
+	ImageName string `yaml:"imageName"`
+	Tag       string `yaml:"tag"`
+}
+
+// recordBundle writes the manifests about to be applied, the builds and the kubectl flags to a bundle.
+func (k *KustomizeDeployer) recordBundle(path string, manifests kubectl.ManifestList, builds []build.Artifact) error {
+	bundle := deployBundle{
+		Version:         deployBundleVersion,
+		SkaffoldVersion: version.Get().Version,
+		KubeContext:     k.kubectl.KubeContext,
+		Namespace:       k.kubectl.Namespace,
+		Flags: v1alpha3.KubectlFlags{
+			Global: k.kubectl.Flags.Global,
+			Apply:  k.kubectl.Flags.Apply,
+			Delete: k.kubectl.Flags.Delete,
+		},
+		Manifests: manifests.String(),
+	}
+	for _, b := range builds {
+		bundle.Builds = append(bundle.Builds, bundledArtifact{ImageName: b.ImageName, Tag: b.Tag})
+	}
+
+	buf, err := yaml.Marshal(bundle)
+	if err != nil {
+		return errors.Wrap(err, "marshalling deploy bundle")
+	}
+
+	return ioutil.WriteFile(path, buf, 0644)
+}
+
+// replayBundle reads the manifests and the builds of a bundle and applies
+// them with the recorded kubectl flags and namespace. The current kube
+// context is kept, so that a bundle can be replayed on another cluster.
+func (k *KustomizeDeployer) replayBundle(out io.Writer, path string) (kubectl.ManifestList, []build.Artifact, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "reading deploy bundle")
+	}
+
+	var bundle deployBundle
+	if err := yaml.UnmarshalStrict(buf, &bundle); err != nil {
+		return nil, nil, errors.Wrapf(err, "parsing deploy bundle %s", path)
+	}
+	if bundle.Version != deployBundleVersion {
+		return nil, nil, errors.Errorf("unsupported deploy bundle version %q in %s, expected %s", bundle.Version, path, deployBundleVersion)
+	}
+
+	color.Default.Fprintln(out, "Replaying the deploy recorded in", path, "by skaffold", bundle.SkaffoldVersion, "on kube context", bundle.KubeContext)
+
+	k.kubectl.Namespace = bundle.Namespace
+	k.kubectl.Flags.Global = bundle.Flags.Global
+	k.kubectl.Flags.Apply = bundle.Flags.Apply
+	k.kubectl.Flags.Delete = bundle.Flags.Delete
+
+	var manifests kubectl.ManifestList
+	manifests.Append([]byte(bundle.Manifests))
+
+	var builds []build.Artifact
+	for _, b := range bundle.Builds {
+		builds = append(builds, build.Artifact{ImageName: b.ImageName, Tag: b.Tag})
+	}
+
+	return manifests, builds, nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestKustomizeRecordAndReplayBundle(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kustomize build "+tmpDir.Root(), deploymentWebYAML, nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext --v=6 apply --prune=false -f -", nil),
+	)
+
+	cfg := &v1alpha3.KustomizeDeploy{
+		KustomizePath: ".",
+		Flags:         v1alpha3.KubectlFlags{Global: []string{"--v=6"}, Apply: []string{"--prune=false"}, Env: []string{"TOKEN=secret"}},
+		Bundle:        &v1alpha3.DeployBundle{Record: "bundle.yaml"},
+	}
+	builds := []build.Artifact{{ImageName: "leeroy-web", Tag: "leeroy-web:v1"}}
+
+	k := NewKustomizeDeployer(tmpDir.Root(), cfg, testKubeContext, &config.SkaffoldOptions{Namespace: "ns"})
+	_, err := k.Deploy(context.Background(), ioutil.Discard, builds)
+	testutil.CheckError(t, false, err)
+
+	recorded, err := ioutil.ReadFile(tmpDir.Path("bundle.yaml"))
+	testutil.CheckError(t, false, err)
+
+	var bundle deployBundle
+	testutil.CheckError(t, false, yaml.Unmarshal(recorded, &bundle))
+	testutil.CheckDeepEqual(t, deployBundleVersion, bundle.Version)
+	testutil.CheckDeepEqual(t, "ns", bundle.Namespace)
+	testutil.CheckDeepEqual(t, []bundledArtifact{{ImageName: "leeroy-web", Tag: "leeroy-web:v1"}}, bundle.Builds)
+	testutil.CheckDeepEqual(t, []string(nil), bundle.Flags.Env)
+
+	// The replay doesn't run kustomize and uses the recorded flags and namespace.
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmd("kubectl --context other-context --v=6 apply --prune=false -f -", nil),
+	)
+
+	replayCfg := &v1alpha3.KustomizeDeploy{
+		KustomizePath: ".",
+		Bundle:        &v1alpha3.DeployBundle{Replay: "bundle.yaml"},
+	}
+	k = NewKustomizeDeployer(tmpDir.Root(), replayCfg, "other-context", &config.SkaffoldOptions{})
+	deployed, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+
+	testutil.CheckError(t, false, err)
+	testutil.CheckDeepEqual(t, 1, len(deployed))
+}
+
+func TestReplayBundleVersion(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("bundle.yaml", "version: skaffold/deploy-bundle/v0\nmanifests: \"\"\n")
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{}, testKubeContext, &config.SkaffoldOptions{})
+	_, _, err := k.replayBundle(ioutil.Discard, tmpDir.Path("bundle.yaml"))
+
+	testutil.CheckError(t, true, err)
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+)
+
+// namespacedDeployer is implemented by the deployers that know the namespaces
+// they deploy to. An empty namespace is the default namespace of the kube context.
+type namespacedDeployer interface {
+	Namespaces() []string
+}
+
+// namespacesOf returns the namespaces of a deployer. Deployers that don't
+// know theirs are assumed to deploy to the default namespace.
+func namespacesOf(deployer Deployer) []string {
+	if d, ok := deployer.(namespacedDeployer); ok {
+		if namespaces := d.Namespaces(); len(namespaces) > 0 {
+			return namespaces
+		}
+	}
+
+	return []string{""}
+}
+
+// namespaceGroups groups the indexes of the deployers that share a namespace,
+// directly or through another deployer. Groups and the deployers of a group
+// are in the order of the deployers.
+func namespaceGroups(deployers []Deployer) [][]int {
+	group := make([]int, len(deployers))
+	for i := range group {
+		group[i] = i
+	}
+
+	var find func(int) int
+	find = func(i int) int {
+		if group[i] != i {
+			group[i] = find(group[i])
+		}
+		return group[i]
+	}
+
+	owner := map[string]int{}
+	for i, deployer := range deployers {
+		for _, ns := range namespacesOf(deployer) {
+			other, found := owner[ns]
+			if !found {
+				owner[ns] = i
+				continue
+			}
+
+			// The lowest index is the root so that groups keep their order.
+			a, b := find(i), find(other)
+			if a < b {
+				group[b] = a
+			} else {
+				group[a] = b
+			}
+		}
+	}
+
+	var groups [][]int
+	index := map[int]int{}
+	for i := range deployers {
+		root := find(i)
+		if _, found := index[root]; !found {
+			index[root] = len(groups)
+			groups = append(groups, nil)
+		}
+		groups[index[root]] = append(groups[index[root]], i)
+	}
+
+	return groups
+}
+
+// deployConcurrently runs the deployers of different namespaces in parallel,
+// at most concurrency at a time. Deployers that share a namespace are run one
+// after the other, in order, to avoid concurrent applies to the same resources.
+// The artifacts are returned in the order of the deployers, and the errors of
+// every failed deployer are reported.
+func (m *multiDeployer) deployConcurrently(ctx context.Context, out io.Writer, builds []build.Artifact) ([]Artifact, error) {
+	artifacts := make([][]Artifact, len(m.deployers))
+	errs := make([]error, len(m.deployers))
+	out = &syncWriter{out: out}
+
+	slots := make(chan struct{}, m.concurrency)
+	var wg sync.WaitGroup
+	for _, group := range namespaceGroups(m.deployers) {
+		wg.Add(1)
+		go func(group []int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			for _, i := range group {
+				artifacts[i], errs[i] = m.deployers[i].Deploy(ctx, out, builds)
+				if errs[i] != nil {
+					return
+				}
+			}
+		}(group)
+	}
+	wg.Wait()
+
+	var failures []string
+	var firstErr error
+	for _, err := range errs {
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failures = append(failures, err.Error())
+		}
+	}
+	switch len(failures) {
+	case 0:
+	case 1:
+		return nil, firstErr
+	default:
+		return nil, fmt.Errorf("%d deployers failed: %s", len(failures), strings.Join(failures, "; "))
+	}
+
+	results := []Artifact{}
+	for _, a := range artifacts {
+		results = append(results, a...)
+	}
+
+	return results, nil
+}
+
+// syncWriter serializes the writes of deployers that run concurrently.
+type syncWriter struct {
+	lock sync.Mutex
+	out  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.out.Write(p)
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+// namespaceDeployer records how many deployers of the same test run at once.
+type namespaceDeployer struct {
+	namespaces []string
+	namespace  string
+	err        error
+	running    *concurrencyRecorder
+}
+
+func (d *namespaceDeployer) Labels() map[string]string { return nil }
+
+func (d *namespaceDeployer) Dependencies() ([]string, error) { return nil, nil }
+
+func (d *namespaceDeployer) Cleanup(context.Context, io.Writer) error { return nil }
+
+func (d *namespaceDeployer) Namespaces() []string { return d.namespaces }
+
+func (d *namespaceDeployer) Deploy(ctx context.Context, out io.Writer, builds []build.Artifact) ([]Artifact, error) {
+	d.running.start(d.namespaces)
+	time.Sleep(10 * time.Millisecond)
+	d.running.stop(d.namespaces)
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	return []Artifact{{Namespace: d.namespace}}, nil
+}
+
+type concurrencyRecorder struct {
+	lock       sync.Mutex
+	running    int
+	max        int
+	namespaces map[string]int
+	conflicts  int
+}
+
+func (r *concurrencyRecorder) start(namespaces []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.running++
+	if r.running > r.max {
+		r.max = r.running
+	}
+	for _, ns := range namespaces {
+		r.namespaces[ns]++
+		if r.namespaces[ns] > 1 {
+			r.conflicts++
+		}
+	}
+}
+
+func (r *concurrencyRecorder) stop(namespaces []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.running--
+	for _, ns := range namespaces {
+		r.namespaces[ns]--
+	}
+}
+
+func TestMultiDeployerConcurrency(t *testing.T) {
+	var tests = []struct {
+		description string
+		namespaces  [][]string
+		concurrency int
+		expectedMax int
+	}{
+		{
+			description: "sequential",
+			namespaces:  [][]string{{"a"}, {"b"}, {"c"}},
+			concurrency: 1,
+			expectedMax: 1,
+		},
+		{
+			description: "independent deployers",
+			namespaces:  [][]string{{"a"}, {"b"}, {"c"}},
+			concurrency: 3,
+			expectedMax: 3,
+		},
+		{
+			description: "bounded",
+			namespaces:  [][]string{{"a"}, {"b"}, {"c"}, {"d"}},
+			concurrency: 2,
+			expectedMax: 2,
+		},
+		{
+			description: "same namespace is serialized",
+			namespaces:  [][]string{{"a"}, {"a"}, {"a"}},
+			concurrency: 3,
+			expectedMax: 1,
+		},
+		{
+			description: "namespaces shared through another deployer",
+			namespaces:  [][]string{{"a"}, {"a", "b"}, {"b"}, {"c"}},
+			concurrency: 4,
+			expectedMax: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			running := &concurrencyRecorder{namespaces: map[string]int{}}
+
+			var deployers []Deployer
+			var expected []Artifact
+			for i, namespaces := range test.namespaces {
+				name := string(rune('0' + i))
+				deployers = append(deployers, &namespaceDeployer{namespaces: namespaces, namespace: name, running: running})
+				expected = append(expected, Artifact{Namespace: name})
+			}
+
+			artifacts, err := NewMultiDeployer(deployers, test.concurrency).Deploy(context.Background(), ioutil.Discard, nil)
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, expected, artifacts)
+			testutil.CheckDeepEqual(t, test.expectedMax, running.max)
+			testutil.CheckDeepEqual(t, 0, running.conflicts)
+		})
+	}
+}
+
+func TestMultiDeployerConcurrentErrors(t *testing.T) {
+	running := &concurrencyRecorder{namespaces: map[string]int{}}
+	deployers := []Deployer{
+		&namespaceDeployer{namespaces: []string{"a"}, err: errors.New("helm failed"), running: running},
+		&namespaceDeployer{namespaces: []string{"b"}, running: running},
+		&namespaceDeployer{namespaces: []string{"c"}, err: errors.New("apply failed"), running: running},
+	}
+
+	_, err := NewMultiDeployer(deployers, 3).Deploy(context.Background(), ioutil.Discard, nil)
+
+	testutil.CheckErrorAndDeepEqual(t, true, err, "2 deployers failed: helm failed; apply failed", err.Error())
+}
+
+func TestNamespaceGroups(t *testing.T) {
+	deployers := []Deployer{
+		&namespaceDeployer{namespaces: []string{"a"}},
+		&namespaceDeployer{namespaces: []string{"b"}},
+		&namespaceDeployer{namespaces: []string{"c", "a"}},
+		&namespaceDeployer{},
+		NewHelmDeployer(&v1alpha3.HelmDeploy{}, testKubeContext, ""),
+	}
+
+	testutil.CheckDeepEqual(t, [][]int{{0, 2}, {1}, {3, 4}}, namespaceGroups(deployers))
+}
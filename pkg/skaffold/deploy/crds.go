@@ -0,0 +1,73 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/pkg/errors"
+)
+
+// buildCRDs builds the kustomization of the crdsPath.
+func (k *KustomizeDeployer) buildCRDs(ctx context.Context) (kubectl.ManifestList, error) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return nil, errors.Wrap(err, "finding current directory")
+	}
+
+	out, err := kustomizeBuild(ctx, k.crdsPath(), k.kustomizeEnv(), k.kustomizeArgs(), workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests kubectl.ManifestList
+	manifests.Append(out)
+	return manifests, nil
+}
+
+// applyCRDs applies the CRDs of the crdsPath before the app, and waits
+// for them to be established so that the app's custom resources are accepted.
+func (k *KustomizeDeployer) applyCRDs(ctx context.Context, out io.Writer) error {
+	manifests, err := k.buildCRDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	updated, err := k.crdsKubectl.Apply(ctx, out, manifests)
+	if err != nil {
+		return err
+	}
+
+	return k.crdsKubectl.WaitForEstablished(ctx, out, updated)
+}
+
+// deleteCRDs deletes the CRDs of the crdsPath, and with them every custom resource they define.
+func (k *KustomizeDeployer) deleteCRDs(ctx context.Context, out io.Writer) error {
+	manifests, err := k.buildCRDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	return k.crdsKubectl.Delete(ctx, out, manifests)
+}
+
+func (k *KustomizeDeployer) crdsPath() string {
+	return resolveKustomizePath(k.workingDir, k.CRDsPath)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const crontabCRDYAML = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: crontabs.stable.example.com
+spec:
+  group: stable.example.com
+  names:
+    kind: CronTab
+`
+
+func TestKustomizeDeployCRDsFirst(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+		testutil.NewFakeCmdOut("kustomize build crds", crontabCRDYAML, nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=Established --timeout=1m customresourcedefinition/crontabs.stable.example.com", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+		testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+		testutil.NewFakeCmdOut("kustomize build crds", crontabCRDYAML, nil),
+	)
+
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: ".", CRDsPath: "crds"}, testKubeContext, &config.SkaffoldOptions{})
+	_, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+	testutil.CheckError(t, false, err)
+
+	// Unchanged CRDs and app are not applied again.
+	_, err = k.Deploy(context.Background(), ioutil.Discard, nil)
+	testutil.CheckError(t, false, err)
+}
+
+func TestKustomizeCleanupCRDs(t *testing.T) {
+	var tests = []struct {
+		description string
+		deleteCRDs  bool
+		command     util.Command
+	}{
+		{
+			description: "keep CRDs",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext delete --ignore-not-found=true -f -", nil),
+			),
+		},
+		{
+			description: "delete CRDs",
+			deleteCRDs:  true,
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext delete --ignore-not-found=true -f -", nil),
+				testutil.NewFakeCmdOut("kustomize build crds", crontabCRDYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext delete --ignore-not-found=true -f -", nil),
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: ".", CRDsPath: "crds", DeleteCRDs: test.deleteCRDs}, testKubeContext, &config.SkaffoldOptions{})
+			err := k.Cleanup(context.Background(), ioutil.Discard)
+
+			testutil.CheckError(t, false, err)
+		})
+	}
+}
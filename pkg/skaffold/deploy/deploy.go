@@ -48,13 +48,25 @@ type Deployer interface {
 	Cleanup(context.Context, io.Writer) error
 }
 
+// ConfigExporter is implemented by the deployers that can export their
+// effective configuration, after profiles and defaults are applied.
+type ConfigExporter interface {
+	// ExportConfig marshals the configuration as the `deploy` section of skaffold.yaml.
+	ExportConfig() ([]byte, error)
+}
+
 type multiDeployer struct {
-	deployers []Deployer
+	deployers   []Deployer
+	concurrency int
 }
 
-func NewMultiDeployer(deployers []Deployer) Deployer {
+// NewMultiDeployer returns a Deployer that runs the given deployers in order
+// or, with a concurrency greater than one, that many at a time. Deployers that
+// share a namespace are always run in order.
+func NewMultiDeployer(deployers []Deployer, concurrency int) Deployer {
 	return &multiDeployer{
-		deployers: deployers,
+		deployers:   deployers,
+		concurrency: concurrency,
 	}
 }
 
@@ -70,6 +82,10 @@ func (m *multiDeployer) Labels() map[string]string {
 }
 
 func (m *multiDeployer) Deploy(ctx context.Context, out io.Writer, builds []build.Artifact) ([]Artifact, error) {
+	if m.concurrency > 1 {
+		return m.deployConcurrently(ctx, out, builds)
+	}
+
 	results := []Artifact{}
 	for _, deployer := range m.deployers {
 		a, err := deployer.Deploy(ctx, out, builds)
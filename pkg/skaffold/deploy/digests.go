@@ -0,0 +1,43 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+)
+
+// reportPulledImages prints the digests that the cluster pulled for the built
+// images, and warns about the images deployed by digest that were pulled with
+// another digest.
+func reportPulledImages(out io.Writer, pulled []kubectl.PulledImage) {
+	if len(pulled) == 0 {
+		return
+	}
+
+	color.Default.Fprintln(out, "Images pulled by the cluster:")
+	for _, p := range pulled {
+		color.Default.Fprintf(out, " - %s: %s %s\n", p.Workload, p.Image, p.Digest)
+
+		if i := strings.LastIndex(p.Image, "@"); i >= 0 && strings.HasPrefix(p.Digest, "sha256:") && p.Image[i+1:] != p.Digest {
+			color.Yellow.Fprintf(out, "Warning: %s was deployed with digest %s but the cluster pulled %s\n", p.Workload, p.Image[i+1:], p.Digest)
+		}
+	}
+}
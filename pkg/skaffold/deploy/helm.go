@@ -58,6 +58,24 @@ func NewHelmDeployer(cfg *v1alpha3.HelmDeploy, kubeContext string, namespace str
 	}
 }
 
+// Namespaces returns the namespaces of the releases.
+func (h *HelmDeployer) Namespaces() []string {
+	if h.namespace != "" {
+		return []string{h.namespace}
+	}
+
+	var namespaces []string
+	for _, r := range h.Releases {
+		namespaces = append(namespaces, r.Namespace)
+	}
+	return namespaces
+}
+
+// ExportConfig marshals the effective helm configuration.
+func (h *HelmDeployer) ExportConfig() ([]byte, error) {
+	return yaml.Marshal(v1alpha3.DeployType{HelmDeploy: h.HelmDeploy})
+}
+
 func (h *HelmDeployer) Labels() map[string]string {
 	return map[string]string{
 		constants.Labels.Deployer: "helm",
@@ -317,10 +335,16 @@ func (h *HelmDeployer) getReleaseInfo(ctx context.Context, release string) (*buf
 func (h *HelmDeployer) getDeployResults(ctx context.Context, namespace string, release string) []Artifact {
 	b, err := h.getReleaseInfo(ctx, release)
 	if err != nil {
-		logrus.Warnf(err.Error())
+		logrus.Warnln(err)
+		return nil
+	}
+
+	deployed, err := parseReleaseInfo(namespace, b, false)
+	if err != nil {
+		logrus.Warnln(err)
 		return nil
 	}
-	return parseReleaseInfo(namespace, b)
+	return deployed
 }
 
 func (h *HelmDeployer) deleteRelease(ctx context.Context, out io.Writer, r v1alpha3.HelmRelease) error {
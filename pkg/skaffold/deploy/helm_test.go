@@ -28,6 +28,7 @@ import (
 	"testing"
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
@@ -235,12 +236,28 @@ HOOKS:
 MANIFEST:
 `
 
-// TestMain disables logrus output before running tests.
+// TestMain disables logrus output and caches the cluster-scoped kinds
+// of the test kube contexts before running tests.
 func TestMain(m *testing.M) {
 	logrus.SetOutput(ioutil.Discard)
+	for _, kubeContext := range []string{testKubeContext, "other-context", "plan", "prune"} {
+		cacheClusterScopedKinds(kubeContext)
+	}
 	os.Exit(m.Run())
 }
 
+// cacheClusterScopedKinds lists the cluster-scoped kinds of a kube context
+// once, so that the tests don't have to expect `kubectl api-resources`.
+func cacheClusterScopedKinds(kubeContext string) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmdOut("kubectl --context "+kubeContext+" api-resources --namespaced=false --no-headers", "namespaces ns v1 false Namespace\n", nil)
+
+	cli := kubectl.CLI{KubeContext: kubeContext}
+	if _, err := cli.ClusterScopedKinds(context.Background(), nil); err != nil {
+		panic(err)
+	}
+}
+
 func TestHelmDeploy(t *testing.T) {
 	var tests = []struct {
 		description string
@@ -0,0 +1,83 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// runPostDeployHook runs the post-deploy commands. The deployed images
+// and namespaces are given to the commands with environment variables:
+//   SKAFFOLD_IMAGES: comma separated list of image=tag
+//   SKAFFOLD_NAMESPACES: comma separated list of namespaces
+func runPostDeployHook(ctx context.Context, out io.Writer, hook *v1alpha3.PostDeployHook, builds []build.Artifact, deployed kubectl.ManifestList) error {
+	var images []string
+	for _, b := range builds {
+		images = append(images, b.ImageName+"="+b.Tag)
+	}
+
+	env := append(os.Environ(),
+		"SKAFFOLD_IMAGES="+strings.Join(images, ","),
+		"SKAFFOLD_NAMESPACES="+strings.Join(namespaces(deployed), ","),
+	)
+
+	for _, command := range hook.Commands {
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Env = env
+		cmd.Stdout = out
+		cmd.Stderr = out
+
+		if err := util.RunCmd(cmd); err != nil {
+			if hook.FailOnError {
+				return errors.Wrapf(err, "running post-deploy command %s", command)
+			}
+			logrus.Warnf("post-deploy command %s failed: %s", command, err)
+		}
+	}
+
+	return nil
+}
+
+// namespaces returns the sorted list of namespaces declared by the manifests.
+func namespaces(manifests kubectl.ManifestList) []string {
+	seen := map[string]bool{}
+	for _, r := range manifests.Resources() {
+		if r.Namespace != "" {
+			seen[r.Namespace] = true
+		}
+	}
+
+	var namespaces []string
+	for ns := range seen {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	return namespaces
+}
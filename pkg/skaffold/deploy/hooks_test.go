@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+type recordingCmd struct {
+	commands []string
+	env      []string
+	err      error
+}
+
+func (r *recordingCmd) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	return nil, r.RunCmd(cmd)
+}
+
+func (r *recordingCmd) RunCmd(cmd *exec.Cmd) error {
+	r.commands = append(r.commands, strings.Join(cmd.Args, " "))
+	r.env = cmd.Env
+	return r.err
+}
+
+func TestRunPostDeployHook(t *testing.T) {
+	var tests = []struct {
+		description string
+		hook        *v1alpha3.PostDeployHook
+		err         error
+		shouldErr   bool
+	}{
+		{
+			description: "success",
+			hook:        &v1alpha3.PostDeployHook{Commands: []string{"./smoke-test.sh", "notify"}},
+		},
+		{
+			description: "ignored failure",
+			hook:        &v1alpha3.PostDeployHook{Commands: []string{"./smoke-test.sh", "notify"}},
+			err:         errors.New("exit status 1"),
+		},
+		{
+			description: "failure",
+			hook:        &v1alpha3.PostDeployHook{Commands: []string{"./smoke-test.sh"}, FailOnError: true},
+			err:         errors.New("exit status 1"),
+			shouldErr:   true,
+		},
+	}
+
+	deployed := kubectl.ManifestList{
+		[]byte("kind: Pod\nmetadata:\n  name: a\n  namespace: ns2\n"),
+		[]byte("kind: Pod\nmetadata:\n  name: b\n  namespace: ns1\n"),
+		[]byte("kind: Pod\nmetadata:\n  name: c\n  namespace: ns1\n"),
+	}
+	builds := []build.Artifact{{ImageName: "web", Tag: "web:v1"}, {ImageName: "app", Tag: "app:v2"}}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			cmd := &recordingCmd{err: test.err}
+			util.DefaultExecCommand = cmd
+
+			err := runPostDeployHook(context.Background(), ioutil.Discard, test.hook, builds, deployed)
+
+			testutil.CheckError(t, test.shouldErr, err)
+			if !test.shouldErr {
+				testutil.CheckDeepEqual(t, len(test.hook.Commands), len(cmd.commands))
+			}
+			testutil.CheckDeepEqual(t, []string{
+				"SKAFFOLD_IMAGES=web=web:v1,app=app:v2",
+				"SKAFFOLD_NAMESPACES=ns1,ns2",
+			}, cmd.env[len(cmd.env)-2:])
+		})
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	kubectx "github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/context"
+	"github.com/sirupsen/logrus"
+)
+
+// currentKubeConfig loads the kubeconfig that the kube context is looked up in.
+var currentKubeConfig = kubectx.CurrentConfig
+
+// checkKubeContext checks, on first use, that the kube context is defined in
+// the kubeconfig, so that a typo is reported clearly instead of being an opaque
+// kubectl error. Contexts that can't be listed are not checked.
+func (k *KustomizeDeployer) checkKubeContext() error {
+	if k.SkipContextCheck || k.contextChecked || k.kubectl.KubeContext == "" {
+		return nil
+	}
+
+	for _, flag := range k.Flags.Global {
+		if strings.HasPrefix(flag, "--kubeconfig") {
+			logrus.Debugln("Not checking the kube context of a kubeconfig given as a kubectl flag")
+			return nil
+		}
+	}
+
+	cfg, err := currentKubeConfig()
+	if err != nil || len(cfg.Contexts) == 0 {
+		logrus.Debugln("Unable to list the kube contexts, not checking that", k.kubectl.KubeContext, "exists:", err)
+		return nil
+	}
+
+	if _, found := cfg.Contexts[k.kubectl.KubeContext]; !found {
+		var available []string
+		for name := range cfg.Contexts {
+			available = append(available, name)
+		}
+		sort.Strings(available)
+
+		return fmt.Errorf("kube context %q doesn't exist in the kubeconfig, available contexts are: %s", k.kubectl.KubeContext, strings.Join(available, ", "))
+	}
+
+	k.contextChecked = true
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestCheckKubeContext(t *testing.T) {
+	kubeConfig := clientcmdapi.Config{Contexts: map[string]*clientcmdapi.Context{
+		"minikube":      {},
+		"gke_prod":      {},
+		testKubeContext: {},
+	}}
+
+	var tests = []struct {
+		description string
+		kubeContext string
+		cfg         v1alpha3.KustomizeDeploy
+		kubeConfig  clientcmdapi.Config
+		configErr   error
+		expectedErr string
+	}{
+		{
+			description: "existing context",
+			kubeContext: "minikube",
+			kubeConfig:  kubeConfig,
+		},
+		{
+			description: "missing context",
+			kubeContext: "minikub",
+			kubeConfig:  kubeConfig,
+			expectedErr: `kube context "minikub" doesn't exist in the kubeconfig, available contexts are: gke_prod, kubecontext, minikube`,
+		},
+		{
+			description: "skipped",
+			kubeContext: "exec-plugin",
+			cfg:         v1alpha3.KustomizeDeploy{SkipContextCheck: true},
+			kubeConfig:  kubeConfig,
+		},
+		{
+			description: "kubeconfig given as a flag",
+			kubeContext: "other",
+			cfg:         v1alpha3.KustomizeDeploy{Flags: v1alpha3.KubectlFlags{Global: []string{"--kubeconfig=other.yaml"}}},
+			kubeConfig:  kubeConfig,
+		},
+		{
+			description: "contexts can't be listed",
+			kubeContext: "minikub",
+			configErr:   errors.New("loading kubeconfig"),
+		},
+		{
+			description: "no contexts",
+			kubeContext: "minikub",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(f func() (clientcmdapi.Config, error)) { currentKubeConfig = f }(currentKubeConfig)
+			currentKubeConfig = func() (clientcmdapi.Config, error) { return test.kubeConfig, test.configErr }
+
+			k := NewKustomizeDeployer("", &test.cfg, test.kubeContext, &config.SkaffoldOptions{})
+			err := k.checkKubeContext()
+
+			testutil.CheckError(t, test.expectedErr != "", err)
+			if test.expectedErr != "" && err != nil {
+				testutil.CheckDeepEqual(t, test.expectedErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestCleanupMissingKubeContext(t *testing.T) {
+	defer func(f func() (clientcmdapi.Config, error)) { currentKubeConfig = f }(currentKubeConfig)
+	currentKubeConfig = func() (clientcmdapi.Config, error) {
+		return clientcmdapi.Config{Contexts: map[string]*clientcmdapi.Context{"minikube": {}}}, nil
+	}
+
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{}, "minikub", &config.SkaffoldOptions{})
+	err := k.Cleanup(context.Background(), ioutil.Discard)
+
+	testutil.CheckError(t, true, err)
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
 )
 
 // KubectlDeployer deploys workflows using kubectl CLI.
@@ -56,6 +57,16 @@ func NewKubectlDeployer(workingDir string, cfg *v1alpha3.KubectlDeploy, kubeCont
 	}
 }
 
+// Namespaces returns the namespace that the manifests are applied to.
+func (k *KubectlDeployer) Namespaces() []string {
+	return []string{k.kubectl.Namespace}
+}
+
+// ExportConfig marshals the effective kubectl configuration.
+func (k *KubectlDeployer) ExportConfig() ([]byte, error) {
+	return yaml.Marshal(v1alpha3.DeployType{KubectlDeploy: k.KubectlDeploy})
+}
+
 func (k *KubectlDeployer) Labels() map[string]string {
 	return map[string]string{
 		constants.Labels.Deployer: "kubectl",
@@ -76,7 +87,7 @@ func (k *KubectlDeployer) Deploy(ctx context.Context, out io.Writer, builds []bu
 		return nil, nil
 	}
 
-	manifests, err = manifests.ReplaceImages(builds)
+	manifests, err = manifests.ReplaceImages(builds, kubectl.ReplaceOptions{})
 	if err != nil {
 		return nil, errors.Wrap(err, "replacing images in manifests")
 	}
@@ -86,7 +97,7 @@ func (k *KubectlDeployer) Deploy(ctx context.Context, out io.Writer, builds []bu
 		return nil, errors.Wrap(err, "apply")
 	}
 
-	return parseManifestsForDeploys(updated)
+	return parseManifestsForDeploys(updated, k.StrictParsing)
 }
 
 // Cleanup deletes what was deployed by calling Deploy.
@@ -128,13 +139,15 @@ func (k *KubectlDeployer) manifestFiles(manifests []string) ([]string, error) {
 	return filteredManifests, nil
 }
 
-func parseManifestsForDeploys(manifests kubectl.ManifestList) ([]Artifact, error) {
-	results := []Artifact{}
-	for _, manifest := range manifests {
-		b := bufio.NewReader(bytes.NewReader(manifest))
-		results = append(results, parseReleaseInfo("", b)...)
+// parseManifestsForDeploys decodes the deployed manifests. Documents that
+// can't be decoded are skipped so that a successful deploy doesn't fail,
+// unless strict is set.
+func parseManifestsForDeploys(manifests kubectl.ManifestList, strict bool) ([]Artifact, error) {
+	deployed, err := parseReleaseInfo("", bufio.NewReader(manifests.Reader()), strict)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing deployed manifests")
 	}
-	return results, nil
+	return deployed, nil
 }
 
 // readManifests reads the manifests to deploy/delete.
@@ -0,0 +1,56 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// adopt takes over, on the first apply only, the resources that match the
+// adopt selector. They are applied server-side with `--force-conflicts` so
+// that skaffold becomes the manager of the fields owned by other tools.
+// It returns the manifests that are left to apply normally.
+func (c *CLI) adopt(ctx context.Context, out io.Writer, manifests ManifestList) (ManifestList, error) {
+	if c.Adopt == nil || c.adopted {
+		return manifests, nil
+	}
+
+	selector, err := labels.Parse(c.Adopt.Selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing adopt selector %s", c.Adopt.Selector)
+	}
+
+	adopted := manifests.SelectByLabels(selector)
+	if len(adopted) > 0 {
+		for _, r := range adopted.Resources() {
+			color.Default.Fprintln(out, "Adopting", strings.ToLower(r.Kind)+"/"+r.Name)
+		}
+
+		if err := c.applyAll(ctx, out, adopted, applyMode{serverSide: true, forceConflicts: true}); err != nil {
+			return nil, errors.Wrap(err, "adopting resources")
+		}
+	}
+	c.adopted = true
+
+	return adopted.Diff(manifests), nil
+}
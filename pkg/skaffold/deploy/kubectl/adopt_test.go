@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestApplyAdoptsOnce(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kubectl --context kubecontext get --ignore-not-found=true -o yaml -f -", "", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply --server-side --force-conflicts -f -", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+	)
+
+	adopted := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  labels:\n    app: web\n  name: web\n")
+	other := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: other\n")
+
+	cli := &CLI{KubeContext: "kubecontext", Adopt: &v1alpha3.Adopt{Selector: "app=web"}}
+	var out bytes.Buffer
+	_, err := cli.Apply(context.Background(), &out, ManifestList{adopted, other})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Adopting service/web\n", out.String())
+
+	// Adopted resources are then applied normally
+	changed := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  labels:\n    app: web\n  name: web\nspec: {}\n")
+	_, err = cli.Apply(context.Background(), &out, ManifestList{changed, other})
+
+	testutil.CheckError(t, false, err)
+}
+
+func TestAdoptInvalidSelector(t *testing.T) {
+	cli := &CLI{KubeContext: "kubecontext", Adopt: &v1alpha3.Adopt{Selector: "app in (web"}}
+	_, err := cli.adopt(context.Background(), &bytes.Buffer{}, ManifestList{[]byte(serviceYAML)})
+
+	testutil.CheckError(t, true, err)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// KustomizationAnnotation records the kustomization that produced a resource.
+const KustomizationAnnotation = "skaffold.dev/kustomization"
+
+// SetAnnotation sets an annotation on every resource, merged with the
+// annotations it already has. The order of keys is preserved and manifests
+// that already carry the same value are returned byte for byte.
+func (l *ManifestList) SetAnnotation(key, value string) (ManifestList, error) {
+	var updated ManifestList
+
+	for _, manifest := range *l {
+		var m yaml.MapSlice
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		if len(m) == 0 || !setAnnotation(&m, key, value) {
+			updated = append(updated, manifest)
+			continue
+		}
+
+		updatedManifest, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling yaml")
+		}
+
+		updated = append(updated, updatedManifest)
+	}
+
+	return updated, nil
+}
+
+func setAnnotation(m *yaml.MapSlice, key, value string) bool {
+	current, _ := mapSliceValue(*m, "metadata")
+	metadata, _ := current.(yaml.MapSlice)
+
+	current, _ = mapSliceValue(metadata, "annotations")
+	annotations, _ := current.(yaml.MapSlice)
+
+	if current, ok := mapSliceValue(annotations, key); ok && current == value {
+		return false
+	}
+
+	setMapSliceValue(&annotations, key, value)
+	setMapSliceValue(&metadata, "annotations", annotations)
+	setMapSliceValue(m, "metadata", metadata)
+	return true
+}
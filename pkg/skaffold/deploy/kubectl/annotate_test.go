@@ -0,0 +1,79 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestSetAnnotation(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: no-annotations
+spec:
+  containers:
+  - image: example
+    name: example
+`), []byte(`apiVersion: v1
+kind: Service
+metadata:
+  annotations:
+    other: kept
+  name: other-annotations
+`), []byte(`apiVersion: v1
+kind: Namespace
+metadata:
+  annotations:
+    skaffold.dev/kustomization: overlays/dev
+  # already annotated
+  name: annotated
+`)}
+
+	expected := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: no-annotations
+  annotations:
+    skaffold.dev/kustomization: overlays/dev
+spec:
+  containers:
+  - image: example
+    name: example
+`), []byte(`apiVersion: v1
+kind: Service
+metadata:
+  annotations:
+    other: kept
+    skaffold.dev/kustomization: overlays/dev
+  name: other-annotations
+`), manifests[2]}
+
+	resultManifest, err := manifests.SetAnnotation(KustomizationAnnotation, "overlays/dev")
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
+}
+
+func TestSetAnnotationInvalidManifest(t *testing.T) {
+	manifests := ManifestList{[]byte("INVALID")}
+
+	_, err := manifests.SetAnnotation(KustomizationAnnotation, ".")
+
+	testutil.CheckError(t, true, err)
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Annotations that override the deploy settings for a single resource.
+// They are removed before the resource is applied, except for NoPruneAnnotation
+// which prune reads on the live resources.
+const (
+	// ApplyStrategyAnnotation is either `server-side` or `client-side`.
+	ApplyStrategyAnnotation = "skaffold.dev/apply-strategy"
+	// NoPruneAnnotation, set to "true", stops cleanup and prune from deleting the resource.
+	NoPruneAnnotation = "skaffold.dev/no-prune"
+	// CreateOnlyAnnotation, set to "true", only applies the resource if it
+	// doesn't exist in the cluster yet. Existing resources are never updated.
+	CreateOnlyAnnotation = "skaffold.dev/create-only"
+	// SkipAnnotation, set to "true", never applies nor deletes the resource, for
+	// informational or test-only documents of the rendered manifests.
+	SkipAnnotation = "skaffold.dev/skip"
+)
+
+const (
+	strategyServerSide = "server-side"
+	strategyClientSide = "client-side"
+)
+
+var skaffoldAnnotations = []string{ApplyStrategyAnnotation, CreateOnlyAnnotation}
+
+// stripSkaffoldAnnotations removes the skaffold annotations from the manifests.
+// It returns the apply strategy of the resources that declare one, keyed by
+// their stripped manifest. Manifests that are left untouched are returned byte for byte.
+func (l *ManifestList) stripSkaffoldAnnotations() (ManifestList, map[string]string, error) {
+	var updated ManifestList
+	strategies := map[string]string{}
+
+	for _, manifest := range *l {
+		var m yaml.MapSlice
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		value, _ := mapSliceValue(m, "metadata")
+		metadata, _ := value.(yaml.MapSlice)
+		value, _ = mapSliceValue(metadata, "annotations")
+		annotations, _ := value.(yaml.MapSlice)
+
+		value, _ = mapSliceValue(annotations, ApplyStrategyAnnotation)
+		strategy, _ := value.(string)
+		switch strategy {
+		case "", strategyServerSide, strategyClientSide:
+		default:
+			r := resourceOf(manifest)
+			return nil, nil, fmt.Errorf("invalid %s %q on %s/%s: should be %s or %s", ApplyStrategyAnnotation, strategy, strings.ToLower(r.Kind), r.Name, strategyServerSide, strategyClientSide)
+		}
+
+		changed := false
+		for _, key := range skaffoldAnnotations {
+			if deleteMapSliceValue(&annotations, key) {
+				changed = true
+			}
+		}
+
+		if !changed {
+			updated = append(updated, manifest)
+			continue
+		}
+
+		if len(annotations) == 0 {
+			deleteMapSliceValue(&metadata, "annotations")
+		} else {
+			setMapSliceValue(&metadata, "annotations", annotations)
+		}
+		setMapSliceValue(&m, "metadata", metadata)
+
+		updatedManifest, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "marshalling yaml")
+		}
+
+		if strategy != "" {
+			strategies[string(updatedManifest)] = strategy
+		}
+		updated = append(updated, updatedManifest)
+	}
+
+	return updated, strategies, nil
+}
+
+// applyWithStrategies applies the resources with a declared apply strategy
+// separately from the others.
+func (c *CLI) applyWithStrategies(ctx context.Context, out io.Writer, manifests ManifestList, strategies map[string]string) error {
+	var defaults, serverSide, clientSide ManifestList
+	for _, manifest := range manifests {
+		switch strategies[string(manifest)] {
+		case strategyServerSide:
+			serverSide = append(serverSide, manifest)
+		case strategyClientSide:
+			clientSide = append(clientSide, manifest)
+		default:
+			defaults = append(defaults, manifest)
+		}
+	}
+
+	if len(defaults) > 0 {
+		if err := c.applyAll(ctx, out, defaults, c.defaultApplyMode()); err != nil {
+			return err
+		}
+	}
+	if len(serverSide) > 0 {
+		if err := c.applyAll(ctx, out, serverSide, applyMode{serverSide: true}); err != nil {
+			return err
+		}
+	}
+	if len(clientSide) > 0 {
+		if err := c.applyAll(ctx, out, clientSide, applyMode{serverSide: false}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withoutNoPrune removes the resources that cleanup shouldn't delete.
+func (l *ManifestList) withoutNoPrune(out io.Writer) ManifestList {
+	var pruned ManifestList
+
+	for _, manifest := range *l {
+		var m struct {
+			Metadata struct {
+				Annotations map[string]string `yaml:"annotations"`
+			} `yaml:"metadata"`
+		}
+		if err := yaml.Unmarshal(manifest, &m); err == nil && m.Metadata.Annotations[NoPruneAnnotation] == "true" {
+			r := resourceOf(manifest)
+			color.Default.Fprintln(out, "Not deleting", strings.ToLower(r.Kind)+"/"+r.Name+": annotated with", NoPruneAnnotation)
+			continue
+		}
+
+		pruned = append(pruned, manifest)
+	}
+
+	return pruned
+}
+
+// withoutSkipped removes the resources annotated to be skipped, reporting
+// them with the action, like "applying", that won't happen.
+func (l *ManifestList) withoutSkipped(out io.Writer, action string) ManifestList {
+	var kept ManifestList
+
+	for _, manifest := range *l {
+		if skipped(manifest) {
+			r := resourceOf(manifest)
+			color.Default.Fprintln(out, "Not "+action, strings.ToLower(r.Kind)+"/"+r.Name+": annotated with", SkipAnnotation)
+			continue
+		}
+
+		kept = append(kept, manifest)
+	}
+
+	return kept
+}
+
+// skipped checks if a manifest is annotated to be skipped.
+func skipped(manifest []byte) bool {
+	var m struct {
+		Metadata struct {
+			Annotations map[string]string `yaml:"annotations"`
+		} `yaml:"metadata"`
+	}
+	return yaml.Unmarshal(manifest, &m) == nil && m.Metadata.Annotations[SkipAnnotation] == "true"
+}
+
+// createOnlyResources lists the resources annotated to be created only.
+func (l *ManifestList) createOnlyResources() map[Resource]bool {
+	resources := map[Resource]bool{}
+
+	for _, manifest := range *l {
+		var m struct {
+			Metadata struct {
+				Annotations map[string]string `yaml:"annotations"`
+			} `yaml:"metadata"`
+		}
+		if err := yaml.Unmarshal(manifest, &m); err == nil && m.Metadata.Annotations[CreateOnlyAnnotation] == "true" {
+			resources[resourceOf(manifest)] = true
+		}
+	}
+
+	return resources
+}
+
+// withoutExisting removes the create-only resources that already exist in the cluster.
+func (c *CLI) withoutExisting(ctx context.Context, out io.Writer, manifests ManifestList, createOnly map[Resource]bool) (ManifestList, error) {
+	if len(createOnly) == 0 {
+		return manifests, nil
+	}
+
+	var absent ManifestList
+	for _, manifest := range manifests {
+		r := resourceOf(manifest)
+		if !createOnly[r] {
+			absent = append(absent, manifest)
+			continue
+		}
+
+		single := ManifestList{manifest}
+		live, err := c.runOut(ctx, single.Reader(), "", "get", nil, "--ignore-not-found=true", "-o", "name", "-f", "-")
+		if err != nil {
+			return nil, errors.Wrapf(err, "checking if %s/%s exists", strings.ToLower(r.Kind), r.Name)
+		}
+
+		if len(bytes.TrimSpace(live)) > 0 {
+			color.Default.Fprintln(out, "Not applying", strings.ToLower(r.Kind)+"/"+r.Name+": it already exists and is annotated with", CreateOnlyAnnotation)
+			continue
+		}
+
+		absent = append(absent, manifest)
+	}
+
+	return absent, nil
+}
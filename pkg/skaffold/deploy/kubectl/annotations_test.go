@@ -0,0 +1,186 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const serverSideYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  annotations:
+    skaffold.dev/apply-strategy: server-side
+  name: config
+`
+
+const noPruneYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  annotations:
+    owner: team
+    skaffold.dev/no-prune: "true"
+  name: keep
+`
+
+func TestStripSkaffoldAnnotations(t *testing.T) {
+	createOnlyYAML := `kind: ConfigMap
+apiVersion: v1
+metadata:
+  name: once
+  annotations:
+    skaffold.dev/create-only: "true"
+    owner: team
+data:
+  b: "2"
+  a: "1"
+`
+	manifests := ManifestList{[]byte(serverSideYAML), []byte(noPruneYAML), []byte(createOnlyYAML), []byte(podYAML)}
+
+	stripped, strategies, err := manifests.stripSkaffoldAnnotations()
+
+	// The no-prune annotation is kept for prune to find it on the live resource.
+	testutil.CheckErrorAndDeepEqual(t, false, err, ManifestList{[]byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`), []byte(noPruneYAML), []byte(`kind: ConfigMap
+apiVersion: v1
+metadata:
+  name: once
+  annotations:
+    owner: team
+data:
+  b: "2"
+  a: "1"
+`), []byte(podYAML)}, stripped)
+	testutil.CheckDeepEqual(t, map[string]string{string(stripped[0]): "server-side"}, strategies)
+}
+
+func TestStripSkaffoldAnnotationsInvalidStrategy(t *testing.T) {
+	manifests := ManifestList{[]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  annotations:\n    skaffold.dev/apply-strategy: replace\n")}
+
+	_, _, err := manifests.stripSkaffoldAnnotations()
+
+	testutil.CheckError(t, true, err)
+}
+
+func TestApplyStrategyAnnotation(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+		testutil.NewFakeCmdOut(getLive, "", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply --server-side -f -", nil),
+	)
+
+	cli := &CLI{KubeContext: "kubecontext"}
+	_, err := cli.Apply(context.Background(), ioutil.Discard, ManifestList{[]byte(podYAML), []byte(serverSideYAML)})
+
+	testutil.CheckError(t, false, err)
+	testutil.CheckDeepEqual(t, false, cli.ServerSideApply)
+}
+
+func TestDeleteNoPrune(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmd("kubectl --context kubecontext delete --ignore-not-found=true -f -", nil)
+
+	var out bytes.Buffer
+	cli := &CLI{KubeContext: "kubecontext"}
+	err := cli.Delete(context.Background(), &out, ManifestList{[]byte(podYAML), []byte(noPruneYAML)})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Not deleting configmap/keep: annotated with skaffold.dev/no-prune\n", out.String())
+}
+
+func TestDeleteSkipped(t *testing.T) {
+	skipped := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  annotations:\n    skaffold.dev/skip: \"true\"\n  name: test-fixtures\n")
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	recorder := &recordingApply{}
+	util.DefaultExecCommand = recorder
+
+	var out bytes.Buffer
+	cli := &CLI{KubeContext: "kubecontext"}
+	err := cli.Delete(context.Background(), &out, ManifestList{skipped, []byte(podYAML)})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Not deleting configmap/test-fixtures: annotated with skaffold.dev/skip\n", out.String())
+	testutil.CheckDeepEqual(t, 1, len(recorder.applied))
+	testutil.CheckDeepEqual(t, false, strings.Contains(recorder.applied[0], "test-fixtures"))
+}
+
+func TestDeleteOnlySkipped(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds()
+
+	cli := &CLI{KubeContext: "kubecontext"}
+	err := cli.Delete(context.Background(), ioutil.Discard, ManifestList{[]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  annotations:\n    skaffold.dev/skip: \"true\"\n  name: test-fixtures\n")})
+
+	testutil.CheckError(t, false, err)
+}
+
+func TestApplyCreateOnly(t *testing.T) {
+	createOnly := func(name string) []byte {
+		return []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  annotations:\n    skaffold.dev/create-only: \"true\"\n  name: " + name + "\n")
+	}
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kubectl --context kubecontext get --ignore-not-found=true -o name -f -", "namespace/existing\n", nil),
+		testutil.NewFakeCmdOut("kubectl --context kubecontext get --ignore-not-found=true -o name -f -", "", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+	)
+
+	var out bytes.Buffer
+	cli := &CLI{KubeContext: "kubecontext"}
+	_, err := cli.Apply(context.Background(), &out, ManifestList{createOnly("existing"), createOnly("absent"), []byte(podYAML)})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Not applying namespace/existing: it already exists and is annotated with skaffold.dev/create-only\n", out.String())
+}
+
+func TestApplySkipped(t *testing.T) {
+	skipped := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  annotations:\n    skaffold.dev/skip: \"true\"\n  name: test-fixtures\n")
+	notSkipped := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  annotations:\n    skaffold.dev/skip: \"false\"\n  name: config\n")
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	recorder := &recordingApply{}
+	util.DefaultExecCommand = recorder
+
+	var out bytes.Buffer
+	cli := &CLI{KubeContext: "kubecontext"}
+	updated, err := cli.Apply(context.Background(), &out, ManifestList{skipped, []byte(podYAML), notSkipped})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Not applying configmap/test-fixtures: annotated with skaffold.dev/skip\n", out.String())
+	testutil.CheckDeepEqual(t, 1, len(recorder.applied))
+	testutil.CheckDeepEqual(t, false, strings.Contains(recorder.applied[0], "test-fixtures"))
+	testutil.CheckDeepEqual(t, 2, len(updated))
+}
+
+func TestApplyOnlySkipped(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds()
+
+	cli := &CLI{KubeContext: "kubecontext"}
+	updated, err := cli.Apply(context.Background(), ioutil.Discard, ManifestList{[]byte("kind: Secret\nmetadata:\n  annotations:\n    skaffold.dev/skip: \"true\"\n  name: test\n")})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, ManifestList(nil), updated)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/pkg/errors"
+)
+
+// servedAPIVersions caches, per kube context, the api versions served by the cluster.
+var servedAPIVersions = struct {
+	sync.Mutex
+	byContext map[string]map[string]bool
+}{
+	byContext: map[string]map[string]bool{},
+}
+
+// SkipUnsupportedAPIs removes the resources whose apiVersion isn't served
+// by the cluster, with a warning. Custom resources defined by the list itself
+// are kept since their definitions are applied along with them.
+func (c *CLI) SkipUnsupportedAPIs(ctx context.Context, out io.Writer, manifests ManifestList) (ManifestList, error) {
+	served, err := c.servedAPIVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	definedKinds, err := manifests.customResourceKinds()
+	if err != nil {
+		return nil, err
+	}
+
+	return manifests.Filter(func(r Resource) bool {
+		if r.APIVersion == "" || served[r.APIVersion] || definedKinds[groupKind(r)] {
+			return true
+		}
+
+		color.Yellow.Fprintf(out, "Skipping %s/%s: %s is not served by the cluster\n", strings.ToLower(r.Kind), r.Name, r.APIVersion)
+		return false
+	}), nil
+}
+
+// servedAPIVersions lists the api versions served by the cluster, once per kube context.
+func (c *CLI) servedAPIVersions(ctx context.Context) (map[string]bool, error) {
+	servedAPIVersions.Lock()
+	defer servedAPIVersions.Unlock()
+
+	if served, found := servedAPIVersions.byContext[c.KubeContext]; found {
+		return served, nil
+	}
+
+	buf, err := c.runOut(ctx, nil, "", "api-versions", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing api versions served by the cluster")
+	}
+
+	served := map[string]bool{}
+	for _, version := range strings.Fields(string(buf)) {
+		served[version] = true
+	}
+
+	servedAPIVersions.byContext[c.KubeContext] = served
+	return served, nil
+}
+
+// servedAPIResources caches, per kube context and flags, the resources listed by `kubectl api-resources`.
+var servedAPIResources = struct {
+	sync.Mutex
+	byQuery map[string][][]string
+}{
+	byQuery: map[string][][]string{},
+}
+
+// apiResources lists the rows printed by `kubectl api-resources`, split in
+// fields, once per kube context and flags.
+func (c *CLI) apiResources(ctx context.Context, flags ...string) ([][]string, error) {
+	servedAPIResources.Lock()
+	defer servedAPIResources.Unlock()
+
+	query := c.KubeContext + " " + strings.Join(flags, " ")
+	if rows, found := servedAPIResources.byQuery[query]; found {
+		return rows, nil
+	}
+
+	buf, err := c.runOut(ctx, nil, "", "api-resources", nil, flags...)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing api resources served by the cluster")
+	}
+
+	var rows [][]string
+	for _, line := range strings.Split(string(buf), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			rows = append(rows, fields)
+		}
+	}
+
+	servedAPIResources.byQuery[query] = rows
+	return rows, nil
+}
+
+// deletableResources lists, as `resource.group`, the namespaced resources
+// that can be listed and deleted.
+func (c *CLI) deletableResources(ctx context.Context) ([]string, error) {
+	rows, err := c.apiResources(ctx, "--namespaced=true", "--verbs=delete,list", "-o", "name")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, row := range rows {
+		names = append(names, row[0])
+	}
+
+	return names, nil
+}
+
+// ClusterScopedKinds lists the kinds that don't live in a namespace: those
+// served by the cluster and the custom resources defined by the list itself
+// with a Cluster scope.
+func (c *CLI) ClusterScopedKinds(ctx context.Context, manifests ManifestList) (map[string]bool, error) {
+	rows, err := c.apiResources(ctx, "--namespaced=false", "--no-headers")
+	if err != nil {
+		return nil, err
+	}
+
+	kinds, err := manifests.clusterScopedCustomResourceKinds()
+	if err != nil {
+		return nil, err
+	}
+
+	// KIND is the last column, whatever columns the version of kubectl prints before it.
+	for _, row := range rows {
+		kinds[row[len(row)-1]] = true
+	}
+
+	return kinds, nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestSkipUnsupportedAPIs(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmdOut("kubectl --context apis api-versions", "apiextensions.k8s.io/v1beta1\nv1\npolicy/v1beta1\n", nil)
+
+	pdb := []byte(`apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+`)
+	pdbBeta := []byte(`apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+`)
+	manifests := ManifestList{[]byte(podYAML), pdb, pdbBeta, []byte(crdYAML), []byte(crYAML)}
+
+	cli := &CLI{KubeContext: "apis"}
+	filtered, err := cli.SkipUnsupportedAPIs(context.Background(), ioutil.Discard, manifests)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, ManifestList{[]byte(podYAML), pdbBeta, []byte(crdYAML), []byte(crYAML)}, filtered)
+
+	// The served api versions are cached for the kube context.
+	util.DefaultExecCommand = testutil.NewFakeCmds()
+	_, err = cli.SkipUnsupportedAPIs(context.Background(), ioutil.Discard, manifests)
+
+	testutil.CheckError(t, false, err)
+}
+
+func TestDeletableResources(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmdOut("kubectl --context deletable api-resources --namespaced=true --verbs=delete,list -o name", "configmaps\npods\ndeployments.apps\n", nil)
+
+	cli := &CLI{KubeContext: "deletable"}
+	resources, err := cli.deletableResources(context.Background())
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"configmaps", "pods", "deployments.apps"}, resources)
+
+	// The api resources are cached for the kube context.
+	util.DefaultExecCommand = testutil.NewFakeCmds()
+	_, err = cli.deletableResources(context.Background())
+
+	testutil.CheckError(t, false, err)
+}
+
+func TestClusterScopedKinds(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmdOut("kubectl --context scoped api-resources --namespaced=false --no-headers", `namespaces                        ns                                  false        Namespace
+runtimeclasses                                 node.k8s.io          false        RuntimeClass
+apiservices                                    apiregistration.k8s.io false      APIService
+`, nil)
+
+	clusterCRD := []byte(`apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: clusterissuers.example.com
+spec:
+  group: example.com
+  scope: Cluster
+  names:
+    kind: ClusterIssuer
+`)
+	manifests := ManifestList{[]byte(podYAML), []byte(crdYAML), clusterCRD}
+
+	cli := &CLI{KubeContext: "scoped"}
+	kinds, err := cli.ClusterScopedKinds(context.Background(), manifests)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, map[string]bool{"Namespace": true, "RuntimeClass": true, "APIService": true, "ClusterIssuer": true}, kinds)
+
+	// The api resources are cached for the kube context.
+	util.DefaultExecCommand = testutil.NewFakeCmds()
+	_, err = cli.ClusterScopedKinds(context.Background(), manifests)
+
+	testutil.CheckError(t, false, err)
+}
+
+// fakeClusterScopedKinds caches the kinds that the cluster of a kube context doesn't namespace.
+func fakeClusterScopedKinds(kubeContext string, kinds ...string) {
+	var rows [][]string
+	for _, kind := range kinds {
+		rows = append(rows, []string{strings.ToLower(kind) + "s", "false", kind})
+	}
+
+	servedAPIResources.Lock()
+	defer servedAPIResources.Unlock()
+	servedAPIResources.byQuery[kubeContext+" --namespaced=false --no-headers"] = rows
+}
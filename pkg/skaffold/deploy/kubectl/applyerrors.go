@@ -0,0 +1,61 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ApplyError lists every resource that a `kubectl apply` failed to apply.
+type ApplyError struct {
+	// Errors are the errors reported by kubectl, one per resource.
+	Errors []string
+	// Output is the raw output of kubectl.
+	Output string
+}
+
+func (e *ApplyError) Error() string {
+	return fmt.Sprintf("kubectl apply: %d resources failed:\n - %s", len(e.Errors), strings.Join(e.Errors, "\n - "))
+}
+
+var invalidResource = regexp.MustCompile(`^The \S+ "[^"]*" is invalid`)
+
+// applyErrors collects the per-resource errors from the output of `kubectl apply`.
+// kubectl applies every resource it can and reports the failures as it goes.
+func applyErrors(output []byte) []string {
+	var errs []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "Error from server"),
+			strings.HasPrefix(line, "error when"),
+			strings.HasPrefix(line, "error validating"),
+			strings.HasPrefix(line, "error: "),
+			invalidResource.MatchString(line):
+			errs = append(errs, line)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"github.com/pkg/errors"
+)
+
+const failedApplyOutput = `service/leeroy-web configured
+Error from server (Forbidden): error when creating "STDIN": deployments.apps "web" is forbidden: exceeded quota
+The Service "api" is invalid: spec.ports[0].port: Invalid value: 0: must be between 1 and 65535
+`
+
+// failingApply fakes a `kubectl apply` that prints its output and fails.
+type failingApply struct {
+	output string
+}
+
+func (f *failingApply) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	return nil, nil
+}
+
+func (f *failingApply) RunCmd(cmd *exec.Cmd) error {
+	fmt.Fprint(cmd.Stdout, f.output)
+	return fmt.Errorf("exit status 1")
+}
+
+func TestApplyErrors(t *testing.T) {
+	errs := applyErrors([]byte(failedApplyOutput))
+
+	testutil.CheckDeepEqual(t, []string{
+		`Error from server (Forbidden): error when creating "STDIN": deployments.apps "web" is forbidden: exceeded quota`,
+		`The Service "api" is invalid: spec.ports[0].port: Invalid value: 0: must be between 1 and 65535`,
+	}, errs)
+}
+
+func TestApplyAggregatesErrors(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = &failingApply{output: failedApplyOutput}
+
+	cli := &CLI{KubeContext: "kubecontext"}
+	var out bytes.Buffer
+	_, err := cli.Apply(context.Background(), &out, ManifestList{[]byte(podYAML), []byte(serviceYAML)})
+
+	applyErr, ok := errors.Cause(err).(*ApplyError)
+	if !ok {
+		t.Fatalf("expected an ApplyError, got %v", err)
+	}
+	testutil.CheckDeepEqual(t, 2, len(applyErr.Errors))
+	testutil.CheckDeepEqual(t, failedApplyOutput, applyErr.Output)
+	testutil.CheckDeepEqual(t, failedApplyOutput, out.String())
+}
+
+func TestApplySingleErrorIsWrapped(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = &failingApply{output: "error: no objects passed to apply\n"}
+
+	cli := &CLI{KubeContext: "kubecontext"}
+	_, err := cli.Apply(context.Background(), &bytes.Buffer{}, ManifestList{[]byte(podYAML)})
+
+	testutil.CheckErrorAndDeepEqual(t, true, err, "kubectl apply: exit status 1", err.Error())
+}
+
+func TestApplyAggregatesErrorsWithoutValidation(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = &failingApply{output: failedApplyOutput}
+
+	cli := &CLI{KubeContext: "kubecontext", Validation: "false"}
+	_, err := cli.Apply(context.Background(), &bytes.Buffer{}, ManifestList{[]byte(podYAML), []byte(serviceYAML)})
+
+	applyErr, ok := errors.Cause(err).(*ApplyError)
+	if !ok {
+		t.Fatalf("expected an ApplyError, got %v", err)
+	}
+	testutil.CheckDeepEqual(t, 2, len(applyErr.Errors))
+	testutil.CheckDeepEqual(t, true, strings.HasPrefix(err.Error(), "schema validation is disabled"))
+}
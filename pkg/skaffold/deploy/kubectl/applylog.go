@@ -0,0 +1,150 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Formats of the `kubectl apply` output.
+const (
+	// ApplyLogRaw prints the output of kubectl as is.
+	ApplyLogRaw = "raw"
+	// ApplyLogPrefixed prints one `[kind/name] action` line per applied resource.
+	ApplyLogPrefixed = "prefixed"
+)
+
+// ApplyEvent is a resource reported by `kubectl apply`, with what happened to it:
+// created, configured, unchanged, serverside-applied...
+type ApplyEvent struct {
+	Resource Resource
+	Action   string
+	DryRun   bool
+}
+
+// applyLine matches the lines of `kubectl apply`, either `deployment.apps/web configured`
+// or, with older versions, `deployment.apps "web" configured`, optionally followed by
+// a dry run marker.
+var applyLine = regexp.MustCompile(`^([a-zA-Z0-9.-]+)(?:/(\S+)| "([^"]+)") ([a-z-]+)( \((?:server )?dry run\))?$`)
+
+func parseApplyLine(line string) (ApplyEvent, bool) {
+	match := applyLine.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return ApplyEvent{}, false
+	}
+
+	// Drop the API group of `deployment.apps`.
+	kind := strings.SplitN(match[1], ".", 2)[0]
+	name := match[2]
+	if name == "" {
+		name = match[3]
+	}
+
+	return ApplyEvent{
+		Resource: Resource{Kind: kind, Name: name},
+		Action:   match[4],
+		DryRun:   match[5] != "",
+	}, true
+}
+
+// applyLogWriter parses the output of `kubectl apply` line by line and correlates
+// each line to one of the applied manifests. Lines that aren't about a resource,
+// like warnings, are always written as is.
+type applyLogWriter struct {
+	out       io.Writer
+	format    string
+	onEvent   func(ApplyEvent)
+	resources []Resource
+	buf       bytes.Buffer
+}
+
+func newApplyLogWriter(out io.Writer, format string, onEvent func(ApplyEvent), manifests ManifestList) *applyLogWriter {
+	return &applyLogWriter{
+		out:       out,
+		format:    format,
+		onEvent:   onEvent,
+		resources: manifests.Resources(),
+	}
+}
+
+func (w *applyLogWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+
+		line := string(w.buf.Next(i + 1))
+		if err := w.writeLine(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush writes the last line if it doesn't end with a new line.
+func (w *applyLogWriter) Flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	line := w.buf.String()
+	w.buf.Reset()
+	return w.writeLine(line + "\n")
+}
+
+func (w *applyLogWriter) writeLine(line string) error {
+	event, found := parseApplyLine(line)
+	if !found {
+		_, err := io.WriteString(w.out, line)
+		return err
+	}
+
+	event.Resource = w.correlate(event.Resource)
+	if w.onEvent != nil {
+		w.onEvent(event)
+	}
+
+	if w.format != ApplyLogPrefixed {
+		_, err := io.WriteString(w.out, line)
+		return err
+	}
+
+	action := event.Action
+	if event.DryRun {
+		action += " (dry run)"
+	}
+	_, err := fmt.Fprintf(w.out, "[%s/%s] %s\n", strings.ToLower(event.Resource.Kind), event.Resource.Name, action)
+	return err
+}
+
+// correlate finds the manifest that kubectl reports about. kubectl only prints
+// the lowercase kind and the name, so the first matching resource wins.
+func (w *applyLogWriter) correlate(reported Resource) Resource {
+	for _, r := range w.resources {
+		if strings.EqualFold(r.Kind, reported.Kind) && r.Name == reported.Name {
+			return r
+		}
+	}
+
+	return reported
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestParseApplyLine(t *testing.T) {
+	var tests = []struct {
+		description string
+		line        string
+		expected    ApplyEvent
+		found       bool
+	}{
+		{
+			description: "current format",
+			line:        "deployment.apps/web configured",
+			expected:    ApplyEvent{Resource: Resource{Kind: "deployment", Name: "web"}, Action: "configured"},
+			found:       true,
+		},
+		{
+			description: "core group",
+			line:        "service/web created\n",
+			expected:    ApplyEvent{Resource: Resource{Kind: "service", Name: "web"}, Action: "created"},
+			found:       true,
+		},
+		{
+			description: "older format",
+			line:        `deployment.apps "web" unchanged`,
+			expected:    ApplyEvent{Resource: Resource{Kind: "deployment", Name: "web"}, Action: "unchanged"},
+			found:       true,
+		},
+		{
+			description: "server-side",
+			line:        "configmap/config serverside-applied",
+			expected:    ApplyEvent{Resource: Resource{Kind: "configmap", Name: "config"}, Action: "serverside-applied"},
+			found:       true,
+		},
+		{
+			description: "dry run",
+			line:        "pod/leeroy-web created (server dry run)",
+			expected:    ApplyEvent{Resource: Resource{Kind: "pod", Name: "leeroy-web"}, Action: "created", DryRun: true},
+			found:       true,
+		},
+		{
+			description: "warning",
+			line:        "Warning: resource pods/leeroy-web is missing the last-applied-configuration annotation",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			event, found := parseApplyLine(test.line)
+
+			testutil.CheckDeepEqual(t, test.found, found)
+			testutil.CheckDeepEqual(t, test.expected, event)
+		})
+	}
+}
+
+func TestApplyLogPrefixed(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = &jsonApply{output: "Warning: deprecated\npod/leeroy-web created\nservice \"leeroy-web\" unchanged"}
+
+	var events []ApplyEvent
+	cli := &CLI{
+		KubeContext:    "kubecontext",
+		ApplyLogFormat: ApplyLogPrefixed,
+		OnApplyEvent:   func(e ApplyEvent) { events = append(events, e) },
+	}
+
+	var out bytes.Buffer
+	_, err := cli.Apply(context.Background(), &out, ManifestList{[]byte(podYAML), []byte(serviceYAML)})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Warning: deprecated\n[pod/leeroy-web] created\n[service/leeroy-web] unchanged\n", out.String())
+	testutil.CheckDeepEqual(t, []ApplyEvent{
+		{Resource: Resource{APIVersion: "v1", Kind: "Pod", Namespace: "ns", Name: "leeroy-web"}, Action: "created"},
+		{Resource: Resource{APIVersion: "v1", Kind: "Service", Name: "leeroy-web"}, Action: "unchanged"},
+	}, events)
+}
+
+func TestApplyLogRawWithEvents(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = &jsonApply{output: "pod/leeroy-web created\n"}
+
+	var events []ApplyEvent
+	cli := &CLI{KubeContext: "kubecontext", OnApplyEvent: func(e ApplyEvent) { events = append(events, e) }}
+
+	var out bytes.Buffer
+	_, err := cli.Apply(context.Background(), &out, ManifestList{[]byte(podYAML)})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "pod/leeroy-web created\n", out.String())
+	testutil.CheckDeepEqual(t, 1, len(events))
+}
+
+func TestApplyLogInvalidFormat(t *testing.T) {
+	cli := &CLI{KubeContext: "kubecontext", ApplyLogFormat: "fancy"}
+
+	_, err := cli.Apply(context.Background(), &bytes.Buffer{}, ManifestList{[]byte(podYAML)})
+
+	testutil.CheckError(t, true, err)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// AppliedObjects returns the objects returned by the API server during the
+// last Apply, with their uid and resourceVersion. It's only recorded when
+// ApplyOutput is `json`.
+func (c *CLI) AppliedObjects() []map[string]interface{} {
+	return c.appliedObjects
+}
+
+// recordAppliedObjects decodes the output of `kubectl apply -o json`: either
+// a single object or a List of objects.
+func (c *CLI) recordAppliedObjects(output []byte) error {
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(output, &object); err != nil {
+		return errors.Wrap(err, "reading kubectl apply output")
+	}
+
+	items, isList := object["items"].([]interface{})
+	if object["kind"] != "List" || !isList {
+		c.appliedObjects = append(c.appliedObjects, object)
+		return nil
+	}
+
+	for _, item := range items {
+		if o, ok := item.(map[string]interface{}); ok {
+			c.appliedObjects = append(c.appliedObjects, o)
+		}
+	}
+
+	return nil
+}
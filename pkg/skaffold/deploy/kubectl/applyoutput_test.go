@@ -0,0 +1,90 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+// jsonApply fakes a `kubectl apply -o json` that prints the given objects.
+type jsonApply struct {
+	output  string
+	command string
+}
+
+func (f *jsonApply) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	return nil, nil
+}
+
+func (f *jsonApply) RunCmd(cmd *exec.Cmd) error {
+	f.command = strings.Join(cmd.Args, " ")
+	fmt.Fprint(cmd.Stdout, f.output)
+	return nil
+}
+
+func TestApplyOutputJSON(t *testing.T) {
+	var tests = []struct {
+		description string
+		output      string
+		expected    []map[string]interface{}
+	}{
+		{
+			description: "single object",
+			output:      `{"kind": "Pod", "metadata": {"name": "leeroy-web", "uid": "1234"}}`,
+			expected: []map[string]interface{}{
+				{"kind": "Pod", "metadata": map[string]interface{}{"name": "leeroy-web", "uid": "1234"}},
+			},
+		},
+		{
+			description: "list",
+			output:      `{"kind": "List", "items": [{"kind": "Pod"}, {"kind": "Service"}]}`,
+			expected:    []map[string]interface{}{{"kind": "Pod"}, {"kind": "Service"}},
+		},
+	}
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			fake := &jsonApply{output: test.output}
+			util.DefaultExecCommand = fake
+
+			cli := &CLI{KubeContext: "kubecontext", ApplyOutput: "json"}
+			var out bytes.Buffer
+			_, err := cli.Apply(context.Background(), &out, ManifestList{[]byte(podYAML)})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, cli.AppliedObjects())
+			testutil.CheckDeepEqual(t, "kubectl --context kubecontext apply -o json -f -", fake.command)
+			testutil.CheckDeepEqual(t, "", out.String())
+		})
+	}
+}
+
+func TestApplyOutputInvalid(t *testing.T) {
+	cli := &CLI{KubeContext: "kubecontext", ApplyOutput: "yaml"}
+	_, err := cli.Apply(context.Background(), &bytes.Buffer{}, ManifestList{[]byte(podYAML)})
+
+	testutil.CheckError(t, true, err)
+}
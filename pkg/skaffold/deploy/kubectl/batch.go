@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// applyInBatches applies the manifests in sequential batches, for API servers
+// that throttle large applies. CustomResourceDefinitions go first, so that
+// they are applied before the resources that use them, even across batches.
+func (c *CLI) applyInBatches(ctx context.Context, out io.Writer, manifests ManifestList, validation string, mode applyMode, extraArgs ...string) error {
+	crds := manifests.Select(customResourceDefinitions)
+	others := manifests.Filter(func(r Resource) bool {
+		return r.GroupVersionKind().GroupKind() != customResourceDefinitions.GroupKind()
+	})
+	ordered := append(crds, others...)
+
+	batches := ordered.batches(c.ApplyBatchSize)
+	for i, batch := range batches {
+		if err := c.runApply(ctx, out, batch, validation, mode, extraArgs...); err != nil {
+			var names []string
+			for _, r := range batch.Resources() {
+				names = append(names, strings.ToLower(r.Kind)+"/"+r.Name)
+			}
+			return errors.Wrapf(err, "applying batch %d/%d (%s): previous batches were applied", i+1, len(batches), strings.Join(names, ", "))
+		}
+	}
+
+	return nil
+}
+
+// batches splits the list into batches of at most size manifests.
+func (l *ManifestList) batches(size int) []ManifestList {
+	var batches []ManifestList
+
+	list := *l
+	for len(list) > size {
+		batches = append(batches, list[:size])
+		list = list[size:]
+	}
+	if len(list) > 0 {
+		batches = append(batches, list)
+	}
+
+	return batches
+}
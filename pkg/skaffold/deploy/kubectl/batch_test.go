@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestBatches(t *testing.T) {
+	manifests := ManifestList{[]byte("a"), []byte("b"), []byte("c")}
+
+	testutil.CheckDeepEqual(t, []ManifestList{{[]byte("a"), []byte("b")}, {[]byte("c")}}, manifests.batches(2))
+	testutil.CheckDeepEqual(t, []ManifestList{manifests}, manifests.batches(3))
+}
+
+// recordingApply records the manifests given to each `kubectl apply`.
+type recordingApply struct {
+	applied []string
+	err     error
+}
+
+func (r *recordingApply) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	return nil, fmt.Errorf("unexpected command: %s", cmd.Args)
+}
+
+func (r *recordingApply) RunCmd(cmd *exec.Cmd) error {
+	in, _ := ioutil.ReadAll(cmd.Stdin)
+	r.applied = append(r.applied, string(in))
+	if len(r.applied) == 2 {
+		return r.err
+	}
+	return nil
+}
+
+func TestApplyInBatches(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	recorder := &recordingApply{}
+	util.DefaultExecCommand = recorder
+
+	manifests := ManifestList{[]byte(podYAML), []byte(serviceYAML), []byte(crdYAML)}
+	cli := &CLI{KubeContext: "kubecontext", ApplyBatchSize: 2}
+	_, err := cli.Apply(context.Background(), ioutil.Discard, manifests)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, 2, len(recorder.applied))
+	if !strings.Contains(recorder.applied[0], "kind: CustomResourceDefinition") {
+		t.Errorf("CustomResourceDefinitions should be applied first, got: %s", recorder.applied[0])
+	}
+}
+
+func TestApplyInBatchesFailure(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = &recordingApply{err: fmt.Errorf("throttled")}
+
+	manifests := ManifestList{[]byte(podYAML), []byte(serviceYAML), []byte(crYAML)}
+	cli := &CLI{KubeContext: "kubecontext", ApplyBatchSize: 2}
+	_, err := cli.Apply(context.Background(), ioutil.Discard, manifests)
+
+	testutil.CheckError(t, true, err)
+	if !strings.Contains(err.Error(), "applying batch 2/2 (crontab/my-crontab)") {
+		t.Errorf("error should report the failed batch, got: %s", err)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// applyByNamespace applies the resources of each namespace with a separate
+// `kubectl apply`, cluster-scoped resources first. Every namespace is applied,
+// even if some fail. It returns the manifests of the namespaces that failed.
+func (c *CLI) applyByNamespace(ctx context.Context, out io.Writer, manifests ManifestList, strategies map[string]string) (ManifestList, error) {
+	byNamespace := map[string]ManifestList{}
+	for _, manifest := range manifests {
+		namespace := resourceOf(manifest).Namespace
+		byNamespace[namespace] = append(byNamespace[namespace], manifest)
+	}
+
+	var namespaces []string
+	for namespace := range byNamespace {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	var failed ManifestList
+	var errs []string
+	for _, namespace := range namespaces {
+		if err := c.applyWithStrategies(ctx, out, byNamespace[namespace], strategies); err != nil {
+			failed = append(failed, byNamespace[namespace]...)
+
+			if namespace == "" {
+				errs = append(errs, fmt.Sprintf("cluster-scoped resources: %s", err))
+			} else {
+				errs = append(errs, fmt.Sprintf("namespace %s: %s", namespace, err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return failed, fmt.Errorf("applying %d of %d namespaces failed:\n%s", len(errs), len(namespaces), strings.Join(errs, "\n"))
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+// forbiddenNamespace fails the applies that contain a resource of the given namespace.
+type forbiddenNamespace struct {
+	namespace string
+	applied   []string
+}
+
+func (f *forbiddenNamespace) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	return nil, f.RunCmd(cmd)
+}
+
+func (f *forbiddenNamespace) RunCmd(cmd *exec.Cmd) error {
+	in, err := ioutil.ReadAll(cmd.Stdin)
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(string(in), "namespace: "+f.namespace) {
+		return fmt.Errorf("forbidden")
+	}
+
+	f.applied = append(f.applied, string(in))
+	return nil
+}
+
+func TestApplyByNamespace(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	fake := &forbiddenNamespace{namespace: "forbidden"}
+	util.DefaultExecCommand = fake
+
+	allowed := []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: allowed\n  namespace: allowed")
+	forbidden := []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: forbidden\n  namespace: forbidden")
+	namespace := []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: allowed")
+	manifests := ManifestList{allowed, forbidden, namespace}
+
+	cli := &CLI{KubeContext: "kubecontext", ApplyByNamespace: true}
+	_, err := cli.Apply(context.Background(), ioutil.Discard, manifests)
+
+	testutil.CheckError(t, true, err)
+	if !strings.Contains(err.Error(), "namespace forbidden: ") {
+		t.Errorf("expected the forbidden namespace to be reported, got %v", err)
+	}
+	testutil.CheckDeepEqual(t, []string{string(namespace), string(allowed)}, fake.applied)
+
+	// Only the failed namespace is applied again.
+	fake.namespace = "none"
+	fake.applied = nil
+	updated, err := cli.Apply(context.Background(), ioutil.Discard, manifests)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, ManifestList{forbidden}, updated)
+	testutil.CheckDeepEqual(t, []string{string(forbidden)}, fake.applied)
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/constants"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// for testing
+var claimPollInterval = 2 * time.Second
+
+// claimRefs lists the PersistentVolumeClaims that the pod template of
+// a workload, or a Pod, mounts as volumes.
+func claimRefs(manifest []byte) ([]string, error) {
+	type volumes struct {
+		Volumes []struct {
+			PersistentVolumeClaim *struct {
+				ClaimName string `yaml:"claimName"`
+			} `yaml:"persistentVolumeClaim"`
+		} `yaml:"volumes"`
+	}
+	var m struct {
+		Spec struct {
+			volumes  `yaml:",inline"`
+			Template struct {
+				Spec volumes `yaml:"spec"`
+			} `yaml:"template"`
+		} `yaml:"spec"`
+	}
+	if err := yaml.Unmarshal(manifest, &m); err != nil {
+		return nil, err
+	}
+
+	var claims []string
+	for _, v := range append(m.Spec.Volumes, m.Spec.Template.Spec.Volumes...) {
+		if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName != "" {
+			claims = append(claims, v.PersistentVolumeClaim.ClaimName)
+		}
+	}
+
+	return claims, nil
+}
+
+// claimsOf lists the PersistentVolumeClaims of the manifests.
+func claimsOf(manifests ManifestList) map[Resource]bool {
+	claims := map[Resource]bool{}
+	for _, r := range manifests.Resources() {
+		if r.Kind == "PersistentVolumeClaim" {
+			claims[configRef(r.Kind, r.Namespace, r.Name)] = true
+		}
+	}
+
+	return claims
+}
+
+// renderedClaims returns the claims mounted by a manifest that are part of the render.
+func renderedClaims(rendered map[Resource]bool, manifest []byte) []string {
+	if len(rendered) == 0 {
+		return nil
+	}
+
+	r := resourceOf(manifest)
+	refs, err := claimRefs(manifest)
+	if err != nil {
+		logrus.Debugln("Unable to read the volumes of", r.Name, err)
+		return nil
+	}
+
+	var claims []string
+	for _, claim := range refs {
+		if rendered[configRef("PersistentVolumeClaim", r.Namespace, claim)] {
+			claims = append(claims, claim)
+		}
+	}
+
+	return claims
+}
+
+// waitForClaims waits for the claims mounted by a resource to be bound,
+// since its pods can't be scheduled until then. Claims of a storage class that
+// binds volumes only when a pod uses them are not waited on.
+func (c *CLI) waitForClaims(ctx context.Context, out io.Writer, check readinessCheck, cfg v1alpha3.ReadinessConfig) error {
+	timeout := cfg.ResourceTimeout
+	if timeout == "" {
+		timeout = cfg.Timeout
+	}
+	if timeout == "" {
+		timeout = constants.DefaultReadinessTimeout
+	}
+	duration, err := time.ParseDuration(timeout)
+	if err != nil {
+		return errors.Wrapf(err, "parsing timeout %q", timeout)
+	}
+
+	for _, claim := range check.claims {
+		name := "persistentvolumeclaim/" + claim
+		color.Default.Fprintln(out, "Waiting for", name, "to be bound...")
+
+		if err := c.waitForClaim(ctx, out, check.namespace, name, duration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *CLI) waitForClaim(ctx context.Context, out io.Writer, namespace, name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	modeChecked := false
+
+	for {
+		claim, err := c.getClaim(ctx, namespace, name)
+		if err != nil {
+			return errors.Wrapf(err, "getting %s", name)
+		}
+		if claim.Status.Phase == "Bound" {
+			return nil
+		}
+
+		if !modeChecked && claim.Spec.StorageClassName != "" {
+			modeChecked = true
+			if c.bindsOnFirstConsumer(ctx, claim.Spec.StorageClassName) {
+				color.Default.Fprintln(out, "Not waiting for", name+": storage class", claim.Spec.StorageClassName, "binds it when a pod uses it")
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			storageClass := "without a storage class"
+			if claim.Spec.StorageClassName != "" {
+				storageClass = "with storage class " + claim.Spec.StorageClassName
+			}
+			return fmt.Errorf("%s is not bound after %s: it is %s, %s", name, timeout, claim.Status.Phase, storageClass)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(claimPollInterval):
+		}
+	}
+}
+
+type liveClaim struct {
+	Spec struct {
+		StorageClassName string `yaml:"storageClassName"`
+	} `yaml:"spec"`
+	Status struct {
+		Phase string `yaml:"phase"`
+	} `yaml:"status"`
+}
+
+func (c *CLI) getClaim(ctx context.Context, namespace, name string) (*liveClaim, error) {
+	buf, err := c.runOut(ctx, nil, namespace, "get", nil, name, "-o", "yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	claim := &liveClaim{}
+	if err := yaml.Unmarshal(buf, claim); err != nil {
+		return nil, err
+	}
+
+	return claim, nil
+}
+
+// bindsOnFirstConsumer checks if a storage class delays binding until a pod uses the claim.
+func (c *CLI) bindsOnFirstConsumer(ctx context.Context, storageClass string) bool {
+	buf, err := c.runOut(ctx, nil, "", "get", nil, "storageclass/"+storageClass, "-o", "yaml")
+	if err != nil {
+		logrus.Debugln("Unable to get storage class", storageClass, err)
+		return false
+	}
+
+	var sc struct {
+		VolumeBindingMode string `yaml:"volumeBindingMode"`
+	}
+	if err := yaml.Unmarshal(buf, &sc); err != nil {
+		return false
+	}
+
+	return sc.VolumeBindingMode == "WaitForFirstConsumer"
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+var claimManifests = ManifestList{
+	[]byte("apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata:\n  name: data\n  namespace: ns\n"),
+	[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: db
+  namespace: ns
+spec:
+  template:
+    spec:
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+      - name: external
+        persistentVolumeClaim:
+          claimName: not-rendered
+`),
+}
+
+// pendingClaim fakes a cluster where the claim is never bound.
+type pendingClaim struct{}
+
+func (f *pendingClaim) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	return []byte("status:\n  phase: Pending\n"), nil
+}
+
+func (f *pendingClaim) RunCmd(cmd *exec.Cmd) error {
+	return nil
+}
+
+const (
+	getClaim    = "kubectl --context kubecontext --namespace ns get persistentvolumeclaim/data -o yaml"
+	getStandard = "kubectl --context kubecontext get storageclass/standard -o yaml"
+	rolloutDB   = "kubectl --context kubecontext --namespace ns rollout status deployment/db --timeout=1s"
+)
+
+func TestWaitForClaims(t *testing.T) {
+	defer func(d time.Duration) { claimPollInterval = d }(claimPollInterval)
+	claimPollInterval = time.Millisecond
+
+	var tests = []struct {
+		description string
+		timeout     string
+		command     util.Command
+		expectedOut string
+		shouldErr   bool
+	}{
+		{
+			description: "bound claim",
+			timeout:     "1s",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getClaim, "spec:\n  storageClassName: standard\nstatus:\n  phase: Pending\n", nil),
+				testutil.NewFakeCmdOut(getStandard, "volumeBindingMode: Immediate\n", nil),
+				testutil.NewFakeCmdOut(getClaim, "spec:\n  storageClassName: standard\nstatus:\n  phase: Bound\n", nil),
+				testutil.NewFakeCmd(rolloutDB, nil),
+			),
+			expectedOut: "Not waiting for persistentvolumeclaim/data: no readiness check for kind PersistentVolumeClaim\n" +
+				"Waiting for deployment/db to be ready (1/1)...\n" +
+				"Waiting for persistentvolumeclaim/data to be bound...\n",
+		},
+		{
+			description: "bound on first consumer",
+			timeout:     "1s",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getClaim, "spec:\n  storageClassName: standard\nstatus:\n  phase: Pending\n", nil),
+				testutil.NewFakeCmdOut(getStandard, "volumeBindingMode: WaitForFirstConsumer\n", nil),
+				testutil.NewFakeCmd(rolloutDB, nil),
+			),
+			expectedOut: "Not waiting for persistentvolumeclaim/data: no readiness check for kind PersistentVolumeClaim\n" +
+				"Waiting for deployment/db to be ready (1/1)...\n" +
+				"Waiting for persistentvolumeclaim/data to be bound...\n" +
+				"Not waiting for persistentvolumeclaim/data: storage class standard binds it when a pod uses it\n",
+		},
+		{
+			description: "claim never bound",
+			timeout:     "10ms",
+			command:     &pendingClaim{},
+			expectedOut: "Not waiting for persistentvolumeclaim/data: no readiness check for kind PersistentVolumeClaim\n" +
+				"Waiting for deployment/db to be ready (1/1)...\n" +
+				"Waiting for persistentvolumeclaim/data to be bound...\n" +
+				"deployment/db is not ready: persistentvolumeclaim/data is not bound after 10ms: it is Pending, without a storage class\n",
+			shouldErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			var out bytes.Buffer
+			cli := &CLI{KubeContext: "kubecontext"}
+			err := cli.WaitForReadiness(context.Background(), &out, claimManifests, nil, v1alpha3.ReadinessConfig{ResourceTimeout: test.timeout, WaitForClaims: true})
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expectedOut, out.String())
+		})
+	}
+}
+
+func TestClaimRefs(t *testing.T) {
+	claims, err := claimRefs([]byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: debug\nspec:\n  volumes:\n  - name: data\n    persistentVolumeClaim:\n      claimName: data\n  - name: tmp\n    emptyDir: {}\n"))
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"data"}, claims)
+}
@@ -17,15 +17,22 @@ limitations under the License.
 package kubectl
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"strings"
 	"sync"
 
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // CLI holds parameters to run kubectl.
@@ -34,43 +41,483 @@ type CLI struct {
 	KubeContext string
 	Flags       v1alpha3.KubectlFlags
 
-	version       ClientVersion
-	versionOnce   sync.Once
-	previousApply ManifestList
+	// DeleteGracePeriodSeconds overrides the grace period of deletions.
+	// Nil or negative values keep the kubectl default.
+	DeleteGracePeriodSeconds *int
+	ForceDelete              bool
+
+	// DeleteNamespaces deletes the Namespace objects of the manifests. They
+	// are preserved by default since deleting a namespace deletes everything
+	// it contains, including resources that skaffold didn't create.
+	DeleteNamespaces bool
+
+	// DeletePropagationPolicy is passed to `kubectl delete --cascade`. It is one of
+	// `background`, `foreground` or `orphan`. Empty keeps the kubectl default.
+	DeletePropagationPolicy string
+
+	// DryRun only prints the resources that Delete would delete.
+	DryRun bool
+
+	// Validation is passed to `kubectl apply --validate`. It is one of
+	// `true`, `false` or `strict`. Empty keeps the kubectl default.
+	Validation string
+
+	// ServerSideApply applies manifests with `kubectl apply --server-side`.
+	ServerSideApply bool
+
+	// CRDApply applies custom resources after their definitions.
+	CRDApply *v1alpha3.CRDApply
+
+	// NoOverwriteKinds lists the kinds that are applied with `--overwrite=false`.
+	NoOverwriteKinds []string
+
+	// ApplyBatchSize splits applies into batches of at most that many manifests.
+	// Zero applies every manifest at once.
+	ApplyBatchSize int
+
+	// ServerDryRun validates the manifests with `kubectl apply --dry-run=server`
+	// and only applies them if the API server accepts them all.
+	ServerDryRun bool
+
+	// ApplyByNamespace applies the resources of each namespace separately,
+	// so that a forbidden namespace doesn't prevent the others from being applied.
+	ApplyByNamespace bool
+
+	// ApplyRateLimit limits the rate of `kubectl apply` commands, when
+	// an apply is split in several ones.
+	ApplyRateLimit *v1alpha3.ApplyRateLimit
+
+	// RestartOnConfigChange restarts the rollout of the workloads that read
+	// a ConfigMap or a Secret modified by the apply.
+	RestartOnConfigChange bool
+
+	// Adopt takes over the resources created by other tools on the first apply.
+	Adopt *v1alpha3.Adopt
+
+	// ApplyOutput is passed to `kubectl apply -o`. Only `json` is supported:
+	// the objects returned by the API server are then recorded instead of printed.
+	ApplyOutput string
+
+	// DriftSelector, when set, warns before an apply about the resources that
+	// were changed in the cluster since skaffold applied them. Only the live
+	// resources matching the selector are checked.
+	DriftSelector labels.Selector
+
+	// ForceApply re-creates the resources that can't be updated in place
+	// with `kubectl apply --force`.
+	ForceApply bool
+
+	// ApplyCascade is passed to `kubectl apply --cascade` when applying with
+	// ForceApply: it decides what happens to the dependents of the re-created
+	// resources. It is one of `background`, `foreground` or `orphan`.
+	ApplyCascade string
+
+	// ApplyLogFormat is how the output of `kubectl apply` is printed: `raw`,
+	// the default, or `prefixed` with the kind and name of each resource.
+	ApplyLogFormat string
+
+	// OnApplyEvent, when set, is called for every resource reported by `kubectl apply`.
+	OnApplyEvent func(ApplyEvent)
+
+	// ServerSideFallback applies server-side the resources that a client-side
+	// apply fails to apply because of the size of the last-applied annotation.
+	ServerSideFallback bool
+
+	// RecreateImmutable deletes the immutable ConfigMaps and Secrets whose
+	// content changed before the apply, which then recreates them.
+	RecreateImmutable bool
+
+	// PruneFieldManager is passed to `kubectl apply --server-side --field-manager`.
+	// Leftovers of previous deploys are then only pruned if that field manager
+	// manages them, so that resources labelled by skaffold but owned by another
+	// tool are kept.
+	PruneFieldManager string
+
+	// HistoryConfigMap is the name of the ConfigMap that records the deploys.
+	// It's never deleted as a leftover of a previous deploy.
+	HistoryConfigMap string
+
+	version        ClientVersion
+	versionOnce    sync.Once
+	previousApply  ManifestList
+	limiter        *rate.Limiter
+	limiterOnce    sync.Once
+	throttled      bool
+	adopted        bool
+	appliedObjects []map[string]interface{}
 }
 
 // Delete runs `kubectl delete` on a list of manifests.
 func (c *CLI) Delete(ctx context.Context, out io.Writer, manifests ManifestList) error {
-	if err := c.Run(ctx, manifests.Reader(), out, "delete", c.Flags.Delete, "--ignore-not-found=true", "-f", "-"); err != nil {
+	// Skipped resources were never applied.
+	manifests = manifests.withoutSkipped(out, "deleting")
+	manifests = manifests.withoutNoPrune(out)
+	if !c.DeleteNamespaces {
+		manifests = manifests.withoutNamespaces(out)
+	}
+	if len(manifests) == 0 {
+		return nil
+	}
+
+	manifests, err := c.setDefaultNamespace(ctx, manifests)
+	if err != nil {
+		return errors.Wrap(err, "setting default namespace")
+	}
+
+	if c.DryRun {
+		printDeletions(out, manifests)
+		return nil
+	}
+
+	args, err := c.deleteArgs()
+	if err != nil {
+		return err
+	}
+	args = append(args, "-f", "-")
+
+	if err := c.run(ctx, manifests.Reader(), out, "", "delete", c.Flags.Delete, args...); err != nil {
 		return errors.Wrap(err, "kubectl delete")
 	}
 
 	return nil
 }
 
+// deleteArgs returns the flags of `kubectl delete`.
+func (c *CLI) deleteArgs() ([]string, error) {
+	args := []string{"--ignore-not-found=true"}
+	if c.DeleteGracePeriodSeconds != nil && *c.DeleteGracePeriodSeconds >= 0 {
+		args = append(args, fmt.Sprintf("--grace-period=%d", *c.DeleteGracePeriodSeconds))
+	}
+	if c.ForceDelete {
+		args = append(args, "--force")
+	}
+
+	switch c.DeletePropagationPolicy {
+	case "":
+	case "background", "foreground", "orphan":
+		args = append(args, "--cascade="+c.DeletePropagationPolicy)
+	default:
+		return nil, fmt.Errorf("invalid delete propagation policy %q: should be one of background, foreground or orphan", c.DeletePropagationPolicy)
+	}
+
+	return args, nil
+}
+
+// validateForceApply checks the apply flags that re-create resources.
+func (c *CLI) validateForceApply() error {
+	switch c.ApplyCascade {
+	case "":
+	case "background", "foreground", "orphan":
+		if !c.ForceApply {
+			return fmt.Errorf("apply cascade %q is only used when forcing the apply", c.ApplyCascade)
+		}
+	default:
+		return fmt.Errorf("invalid apply cascade %q: should be one of background, foreground or orphan", c.ApplyCascade)
+	}
+
+	if c.ForceApply && c.ServerSideApply {
+		return errors.New("forcing the apply is not supported with server-side apply")
+	}
+
+	return nil
+}
+
+// printDeletions lists the resources that would be deleted.
+func printDeletions(out io.Writer, manifests ManifestList) {
+	for _, r := range manifests.Resources() {
+		if r.Kind == "" || r.Name == "" {
+			continue
+		}
+
+		name := strings.ToLower(r.Kind) + "/" + r.Name
+		if r.Namespace != "" {
+			color.Default.Fprintln(out, "Would delete", name, "in namespace", r.Namespace)
+		} else {
+			color.Default.Fprintln(out, "Would delete", name)
+		}
+	}
+}
+
 // Apply runs `kubectl apply` on a list of manifests.
 func (c *CLI) Apply(ctx context.Context, out io.Writer, manifests ManifestList) (ManifestList, error) {
+	switch c.Validation {
+	case "", "true", "false", "strict":
+	default:
+		return nil, fmt.Errorf("invalid validation %q: should be one of true, false or strict", c.Validation)
+	}
+
+	switch c.ApplyOutput {
+	case "", "json":
+	default:
+		return nil, fmt.Errorf("invalid apply output %q: should be json or empty", c.ApplyOutput)
+	}
+	c.appliedObjects = nil
+
+	if err := c.validateForceApply(); err != nil {
+		return nil, err
+	}
+
+	if err := c.validatePrune(); err != nil {
+		return nil, err
+	}
+
+	switch c.ApplyLogFormat {
+	case "", ApplyLogRaw, ApplyLogPrefixed:
+	default:
+		return nil, fmt.Errorf("invalid apply log format %q: should be %s or %s", c.ApplyLogFormat, ApplyLogRaw, ApplyLogPrefixed)
+	}
+
+	manifests = manifests.withoutSkipped(out, "applying")
+	if len(manifests) == 0 {
+		return nil, nil
+	}
+
+	manifests, err := c.setDefaultNamespace(ctx, manifests)
+	if err != nil {
+		return nil, errors.Wrap(err, "setting default namespace")
+	}
+
+	createOnly := manifests.createOnlyResources()
+	manifests, strategies, err := manifests.stripSkaffoldAnnotations()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading skaffold annotations")
+	}
+
 	// Only redeploy modified or new manifests
 	// TODO(dgageot): should we delete a manifest that was deployed and is not anymore?
 	updated := c.previousApply.Diff(manifests)
 	logrus.Debugln(len(manifests), "manifests to deploy.", len(updated), "are updated or new")
-	c.previousApply = manifests
 	if len(updated) == 0 {
 		return nil, nil
 	}
 
-	if err := c.Run(ctx, updated.Reader(), out, "apply", c.Flags.Apply, "-f", "-"); err != nil {
-		return nil, errors.Wrap(err, "kubectl apply")
+	if c.ServerDryRun {
+		if err := c.serverDryRun(ctx, updated); err != nil {
+			return nil, err
+		}
+	}
+
+	c.warnDrift(ctx, out, updated)
+
+	// Changes are only visible before the apply.
+	var changedConfigs map[Resource]bool
+	if c.RestartOnConfigChange {
+		changedConfigs, err = c.changedConfigs(ctx, updated)
+		if err != nil {
+			return nil, errors.Wrap(err, "finding changed configs")
+		}
+	}
+
+	toApply, err := c.adopt(ctx, out, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	toApply, err = c.withoutExisting(ctx, out, toApply, createOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.RecreateImmutable {
+		if err := c.deleteChangedImmutable(ctx, out, toApply); err != nil {
+			return nil, errors.Wrap(err, "recreating immutable resources")
+		}
+	}
+
+	previousApply := c.previousApply
+	c.previousApply = manifests
+
+	if c.ApplyByNamespace {
+		failed, err := c.applyByNamespace(ctx, out, toApply, strategies)
+		if err != nil {
+			// Retry the failed namespaces on next deploy.
+			c.previousApply = failed.Diff(manifests)
+			return nil, err
+		}
+	} else if err := c.applyWithStrategies(ctx, out, toApply, strategies); err != nil {
+		// Apply everything again on next deploy.
+		c.previousApply = previousApply
+		return nil, err
+	}
+
+	if err := c.restartConfigConsumers(ctx, out, manifests, updated, changedConfigs); err != nil {
+		return nil, err
 	}
 
 	return updated, nil
 }
 
+// ForgetApplied makes the next Apply apply every manifest, including
+// those that didn't change since the previous Apply.
+func (c *CLI) ForgetApplied() {
+	c.previousApply = nil
+}
+
+// applyMode holds the options that can change from one apply to the other,
+// like the resources with a declared apply strategy.
+type applyMode struct {
+	serverSide     bool
+	forceConflicts bool
+}
+
+// defaultApplyMode is the apply mode configured for the CLI.
+func (c *CLI) defaultApplyMode() applyMode {
+	return applyMode{serverSide: c.ServerSideApply}
+}
+
+// applyAll applies the manifests, custom resources last if configured.
+func (c *CLI) applyAll(ctx context.Context, out io.Writer, manifests ManifestList, mode applyMode) error {
+	if c.CRDApply != nil {
+		return c.applyCustomResourcesLast(ctx, out, manifests, mode)
+	}
+
+	return c.apply(ctx, out, manifests, c.Validation, mode)
+}
+
+// apply runs `kubectl apply` with the given validation.
+func (c *CLI) apply(ctx context.Context, out io.Writer, manifests ManifestList, validation string, mode applyMode) error {
+	if len(c.NoOverwriteKinds) > 0 {
+		return c.applyWithoutOverwrite(ctx, out, manifests, validation, mode)
+	}
+
+	return c.applyWith(ctx, out, manifests, validation, mode)
+}
+
+func (c *CLI) applyWith(ctx context.Context, out io.Writer, manifests ManifestList, validation string, mode applyMode, extraArgs ...string) error {
+	if c.ApplyBatchSize > 0 && len(manifests) > c.ApplyBatchSize {
+		return c.applyInBatches(ctx, out, manifests, validation, mode, extraArgs...)
+	}
+
+	return c.runApply(ctx, out, manifests, validation, mode, extraArgs...)
+}
+
+func (c *CLI) runApply(ctx context.Context, out io.Writer, manifests ManifestList, validation string, mode applyMode, extraArgs ...string) error {
+	args := append([]string(nil), extraArgs...)
+	if validation != "" {
+		args = append(args, "--validate="+validation)
+	}
+
+	var serverSideArgs []string
+	if mode.serverSide {
+		serverSideArgs = c.serverSideApplyArgs(ctx, out, manifests)
+		args = append(args, serverSideArgs...)
+		if mode.forceConflicts && len(serverSideArgs) == 1 {
+			args = append(args, "--force-conflicts")
+		}
+		if c.PruneFieldManager != "" {
+			args = append(args, "--field-manager="+c.PruneFieldManager)
+		}
+	}
+	if c.ForceApply {
+		args = append(args, "--force")
+		if c.ApplyCascade != "" {
+			args = append(args, "--cascade="+c.ApplyCascade)
+		}
+	}
+	if c.ApplyOutput == "json" {
+		args = append(args, "-o", "json")
+	}
+	args = append(args, "-f", "-")
+
+	if err := c.waitForApplyRateLimit(ctx, out); err != nil {
+		return errors.Wrap(err, "waiting for apply rate limit")
+	}
+
+	stdout := out
+	var log *applyLogWriter
+	if c.ApplyLogFormat == ApplyLogPrefixed || c.OnApplyEvent != nil {
+		log = newApplyLogWriter(out, c.ApplyLogFormat, c.OnApplyEvent, manifests)
+		stdout = log
+	}
+
+	var output, objects bytes.Buffer
+	cmd := c.command(ctx, "", "apply", c.Flags.Apply, args...)
+	cmd.Stdin = manifests.Reader()
+	cmd.Stdout = io.MultiWriter(stdout, &output)
+	cmd.Stderr = io.MultiWriter(out, &output)
+	if c.ApplyOutput == "json" {
+		cmd.Stdout = &objects
+	}
+
+	err := util.RunCmd(cmd)
+	if log != nil {
+		if flushErr := log.Flush(); flushErr != nil && err == nil {
+			err = flushErr
+		}
+	}
+	if err != nil && c.ServerSideFallback && !mode.serverSide {
+		err = c.applyOversizedServerSide(ctx, out, output.Bytes(), manifests, validation, err)
+	}
+	if err != nil {
+		// Report every failure rather than only the exit status.
+		if errs := applyErrors(output.Bytes()); len(errs) > 1 {
+			err = &ApplyError{Errors: errs, Output: output.String()}
+		} else {
+			err = errors.Wrap(err, "kubectl apply")
+		}
+		if validation == "false" {
+			return errors.Wrap(err, "schema validation is disabled: invalid manifests are only caught by the API server")
+		}
+		return err
+	}
+
+	if c.ApplyOutput == "json" {
+		if err := c.recordAppliedObjects(objects.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if len(serverSideArgs) > 1 {
+		if err := c.removeLastApplied(ctx, out, manifests); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setDefaultNamespace moves the resources that don't declare a namespace
+// to the configured one. This is used instead of a global `--namespace` flag
+// that would otherwise relocate every resource.
+func (c *CLI) setDefaultNamespace(ctx context.Context, manifests ManifestList) (ManifestList, error) {
+	if c.Namespace == "" {
+		return manifests, nil
+	}
+
+	clusterScoped, err := c.ClusterScopedKinds(ctx, manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifests.SetDefaultNamespace(c.Namespace, clusterScoped)
+}
+
 // Run shells out kubectl CLI.
 func (c *CLI) Run(ctx context.Context, in io.Reader, out io.Writer, command string, commandFlags []string, arg ...string) error {
+	return c.run(ctx, in, out, c.Namespace, command, commandFlags, arg...)
+}
+
+func (c *CLI) run(ctx context.Context, in io.Reader, out io.Writer, namespace string, command string, commandFlags []string, arg ...string) error {
+	cmd := c.command(ctx, namespace, command, commandFlags, arg...)
+	cmd.Stdin = in
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	return util.RunCmd(cmd)
+}
+
+// runOut shells out kubectl CLI and returns its output.
+func (c *CLI) runOut(ctx context.Context, in io.Reader, namespace string, command string, commandFlags []string, arg ...string) ([]byte, error) {
+	cmd := c.command(ctx, namespace, command, commandFlags, arg...)
+	cmd.Stdin = in
+
+	return util.RunCmdOut(cmd)
+}
+
+func (c *CLI) command(ctx context.Context, namespace string, command string, commandFlags []string, arg ...string) *exec.Cmd {
 	args := []string{"--context", c.KubeContext}
-	if c.Namespace != "" {
-		args = append(args, "--namespace", c.Namespace)
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
 	}
 	args = append(args, c.Flags.Global...)
 	args = append(args, command)
@@ -78,9 +525,11 @@ func (c *CLI) Run(ctx context.Context, in io.Reader, out io.Writer, command stri
 	args = append(args, arg...)
 
 	cmd := exec.CommandContext(ctx, "kubectl", args...)
-	cmd.Stdin = in
-	cmd.Stdout = out
-	cmd.Stderr = out
+	if len(c.Flags.Env) > 0 {
+		// Exec credential plugins run as children of kubectl and need the
+		// inherited environment, PATH and HOME included.
+		cmd.Env = append(os.Environ(), c.Flags.Env...)
+	}
 
-	return util.RunCmd(cmd)
+	return cmd
 }
@@ -0,0 +1,53 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestCommandWithProxyFlags(t *testing.T) {
+	cli := &CLI{
+		KubeContext: "kubecontext",
+		Namespace:   "ns",
+		Flags: v1alpha3.KubectlFlags{
+			Global: []string{"--server=https://proxy.internal:8443", "--tls-server-name=cluster.internal"},
+			Apply:  []string{"--wait"},
+			Env:    []string{"HTTPS_PROXY=http://proxy.internal:3128"},
+		},
+	}
+
+	cmd := cli.command(context.Background(), "ns", "apply", cli.Flags.Apply, "-f", "-")
+
+	testutil.CheckDeepEqual(t, "kubectl --context kubecontext --namespace ns --server=https://proxy.internal:8443 --tls-server-name=cluster.internal apply --wait -f -", strings.Join(cmd.Args, " "))
+	testutil.CheckDeepEqual(t, append(os.Environ(), "HTTPS_PROXY=http://proxy.internal:3128"), cmd.Env)
+}
+
+func TestCommandInheritsEnvironment(t *testing.T) {
+	cli := &CLI{KubeContext: "kubecontext"}
+
+	cmd := cli.command(context.Background(), "", "delete", nil, "-f", "-")
+
+	// A nil env means the environment of skaffold is inherited.
+	testutil.CheckDeepEqual(t, []string(nil), cmd.Env)
+}
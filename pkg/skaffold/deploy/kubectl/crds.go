@@ -0,0 +1,140 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// for testing
+var crdEstablishedTimeout = "1m"
+
+// customResourceDefinitions selects the CustomResourceDefinitions of every version.
+var customResourceDefinitions = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+
+// applyCustomResourcesLast applies the custom resources in a second pass,
+// with validation disabled, since their schema is only registered once the
+// CustomResourceDefinitions of the first pass are applied.
+// Optionally, the custom resources are applied a third time, with validation,
+// when the definitions are established.
+func (c *CLI) applyCustomResourcesLast(ctx context.Context, out io.Writer, manifests ManifestList, mode applyMode) error {
+	definedKinds, err := manifests.customResourceKinds()
+	if err != nil {
+		return err
+	}
+
+	isCustomResource := func(r Resource) bool { return definedKinds[groupKind(r)] }
+	customResources := manifests.Filter(isCustomResource)
+	if len(customResources) == 0 {
+		return c.apply(ctx, out, manifests, c.Validation, mode)
+	}
+
+	others := manifests.Filter(func(r Resource) bool { return !isCustomResource(r) })
+	if err := c.apply(ctx, out, others, c.Validation, mode); err != nil {
+		return err
+	}
+
+	color.Default.Fprintln(out, "Applying custom resources without validation, until their definitions are established")
+	if err := c.apply(ctx, out, customResources, "false", mode); err != nil {
+		return err
+	}
+
+	if !c.CRDApply.Revalidate {
+		return nil
+	}
+
+	if err := c.WaitForEstablished(ctx, out, others); err != nil {
+		return err
+	}
+
+	color.Default.Fprintln(out, "Applying custom resources with validation")
+	return c.apply(ctx, out, customResources, c.Validation, mode)
+}
+
+// WaitForEstablished waits for the CustomResourceDefinitions of the list to be established.
+func (c *CLI) WaitForEstablished(ctx context.Context, out io.Writer, manifests ManifestList) error {
+	crds := manifests.Select(customResourceDefinitions)
+	for _, r := range crds.Resources() {
+		if err := c.run(ctx, nil, out, "", "wait", nil, "--for=condition=Established", "--timeout="+crdEstablishedTimeout, "customresourcedefinition/"+r.Name); err != nil {
+			return errors.Wrapf(err, "waiting for %s to be established", r.Name)
+		}
+	}
+
+	return nil
+}
+
+// customResourceKinds lists the group/kind of the resources
+// defined by the CustomResourceDefinitions of the list.
+func (l *ManifestList) customResourceKinds() (map[string]bool, error) {
+	kinds := map[string]bool{}
+
+	for _, manifest := range l.Select(customResourceDefinitions) {
+		var crd struct {
+			Spec struct {
+				Group string `yaml:"group"`
+				Names struct {
+					Kind string `yaml:"kind"`
+				} `yaml:"names"`
+			} `yaml:"spec"`
+		}
+		if err := yaml.Unmarshal(manifest, &crd); err != nil {
+			return nil, errors.Wrap(err, "reading CustomResourceDefinition")
+		}
+
+		kinds[crd.Spec.Group+"/"+crd.Spec.Names.Kind] = true
+	}
+
+	return kinds, nil
+}
+
+// clusterScopedCustomResourceKinds lists the kinds of the resources defined
+// by the CustomResourceDefinitions of the list with a Cluster scope.
+func (l *ManifestList) clusterScopedCustomResourceKinds() (map[string]bool, error) {
+	kinds := map[string]bool{}
+
+	for _, manifest := range l.Select(customResourceDefinitions) {
+		var crd struct {
+			Spec struct {
+				Scope string `yaml:"scope"`
+				Names struct {
+					Kind string `yaml:"kind"`
+				} `yaml:"names"`
+			} `yaml:"spec"`
+		}
+		if err := yaml.Unmarshal(manifest, &crd); err != nil {
+			return nil, errors.Wrap(err, "reading CustomResourceDefinition")
+		}
+
+		if crd.Spec.Scope == "Cluster" {
+			kinds[crd.Spec.Names.Kind] = true
+		}
+	}
+
+	return kinds, nil
+}
+
+// groupKind returns the group/kind of a resource.
+func groupKind(r Resource) string {
+	gvk := r.GroupVersionKind()
+	return gvk.Group + "/" + gvk.Kind
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const crdYAML = `apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: crontabs.example.com
+spec:
+  group: example.com
+  names:
+    kind: CronTab
+`
+
+const crYAML = `apiVersion: example.com/v1
+kind: CronTab
+metadata:
+  name: my-crontab
+`
+
+func TestApplyCustomResourcesLast(t *testing.T) {
+	var tests = []struct {
+		description string
+		manifests   ManifestList
+		revalidate  bool
+		command     util.Command
+	}{
+		{
+			description: "no custom resources",
+			manifests:   ManifestList{[]byte(podYAML)},
+			command:     testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+		},
+		{
+			description: "two passes",
+			manifests:   ManifestList{[]byte(crdYAML), []byte(crYAML)},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply --validate=false -f -", nil),
+			),
+		},
+		{
+			description: "revalidate",
+			manifests:   ManifestList{[]byte(crdYAML), []byte(crYAML)},
+			revalidate:  true,
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply --validate=false -f -", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=Established --timeout=1m customresourcedefinition/crontabs.example.com", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			cli := &CLI{KubeContext: "kubecontext", CRDApply: &v1alpha3.CRDApply{Revalidate: test.revalidate}}
+			_, err := cli.Apply(context.Background(), ioutil.Discard, test.manifests)
+
+			testutil.CheckError(t, false, err)
+		})
+	}
+}
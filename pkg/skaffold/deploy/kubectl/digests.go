@@ -0,0 +1,159 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// PulledImage is an image that the running pods of a workload pulled.
+type PulledImage struct {
+	Workload string
+	Image    string
+	Digest   string
+}
+
+// PulledImages collects, from the status of their running containers, the
+// digests that the cluster pulled for the built images of each workload.
+// It is best-effort: workloads without running pods, or whose pods can't
+// be listed, are skipped.
+func (c *CLI) PulledImages(ctx context.Context, manifests ManifestList, builds []build.Artifact) []PulledImage {
+	built := map[string]bool{}
+	for _, b := range builds {
+		built[b.Tag] = true
+	}
+
+	var pulled []PulledImage
+	for _, manifest := range manifests {
+		r := resourceOf(manifest)
+		if r.Kind == "" || r.Name == "" {
+			continue
+		}
+
+		check := readinessCheck{manifest: manifest, name: strings.ToLower(r.Kind) + "/" + r.Name, namespace: r.Namespace}
+		args, found := podsOf(check)
+		if !found {
+			continue
+		}
+
+		images, err := c.runningImages(ctx, check.namespace, args)
+		if err != nil {
+			logrus.Debugln("Unable to list the pods of", check.name, err)
+			continue
+		}
+		if len(images) == 0 {
+			logrus.Debugln("No running pods for", check.name+", not collecting its image digests")
+			continue
+		}
+
+		seen := map[PulledImage]bool{}
+		for _, image := range images {
+			p := PulledImage{Workload: check.name, Image: image.image, Digest: digestOf(image.imageID)}
+			if built[p.Image] && !seen[p] {
+				seen[p] = true
+				pulled = append(pulled, p)
+			}
+		}
+	}
+
+	return pulled
+}
+
+// runningImage is the image of a running container, as written in the
+// pod spec, and the image ID reported by the container runtime.
+type runningImage struct {
+	image   string
+	imageID string
+}
+
+// runningImages lists the images of the running containers of some pods.
+func (c *CLI) runningImages(ctx context.Context, namespace string, args []string) ([]runningImage, error) {
+	buf, err := c.runOut(ctx, nil, namespace, "get", nil, append(args, "-o", "yaml")...)
+	if err != nil {
+		return nil, err
+	}
+
+	type container struct {
+		Name  string `yaml:"name"`
+		Image string `yaml:"image"`
+	}
+	type containerStatus struct {
+		Name    string `yaml:"name"`
+		ImageID string `yaml:"imageID"`
+		State   struct {
+			Running *struct{} `yaml:"running"`
+		} `yaml:"state"`
+	}
+	type pod struct {
+		Spec struct {
+			InitContainers []container `yaml:"initContainers"`
+			Containers     []container `yaml:"containers"`
+		} `yaml:"spec"`
+		Status struct {
+			InitContainerStatuses []containerStatus `yaml:"initContainerStatuses"`
+			ContainerStatuses     []containerStatus `yaml:"containerStatuses"`
+		} `yaml:"status"`
+	}
+	var pods struct {
+		Kind  string `yaml:"kind"`
+		Items []pod  `yaml:"items"`
+		pod   `yaml:",inline"`
+	}
+	if err := yaml.Unmarshal(buf, &pods); err != nil {
+		return nil, err
+	}
+	if pods.Kind == "Pod" {
+		pods.Items = []pod{pods.pod}
+	}
+
+	var images []runningImage
+	for _, p := range pods.Items {
+		specImages := map[string]string{}
+		for _, c := range append(p.Spec.InitContainers, p.Spec.Containers...) {
+			specImages[c.Name] = c.Image
+		}
+
+		for _, status := range append(p.Status.InitContainerStatuses, p.Status.ContainerStatuses...) {
+			if status.State.Running == nil || status.ImageID == "" {
+				continue
+			}
+			images = append(images, runningImage{image: specImages[status.Name], imageID: status.ImageID})
+		}
+	}
+
+	return images, nil
+}
+
+// digestOf extracts the digest of an image ID like
+// `docker-pullable://gcr.io/k8s-skaffold/web@sha256:...`. Image IDs without
+// a repository digest, like those of images loaded into a local cluster,
+// are returned without their scheme.
+func digestOf(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	if i := strings.Index(imageID, "://"); i >= 0 {
+		return imageID[i+3:]
+	}
+
+	return imageID
+}
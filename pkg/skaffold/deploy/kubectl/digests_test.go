@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestPulledImages(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kubectl --context kubecontext --namespace ns get pods -l app=web -o yaml", `apiVersion: v1
+kind: List
+items:
+- spec:
+    containers:
+    - {name: web, image: gcr.io/k8s-skaffold/web:v1}
+    - {name: proxy, image: envoyproxy/envoy}
+  status:
+    containerStatuses:
+    - name: web
+      imageID: docker-pullable://gcr.io/k8s-skaffold/web@sha256:abc
+      state: {running: {startedAt: "2018-09-01T00:00:00Z"}}
+    - name: proxy
+      imageID: docker-pullable://envoyproxy/envoy@sha256:def
+      state: {running: {startedAt: "2018-09-01T00:00:00Z"}}
+- spec:
+    containers:
+    - {name: web, image: gcr.io/k8s-skaffold/web:v1}
+  status:
+    containerStatuses:
+    - name: web
+      imageID: docker-pullable://gcr.io/k8s-skaffold/web@sha256:abc
+      state: {running: {startedAt: "2018-09-01T00:00:00Z"}}
+`, nil),
+		testutil.NewFakeCmdOut("kubectl --context kubecontext --namespace ns get pods -l app=api -o yaml", `apiVersion: v1
+kind: List
+items:
+- spec:
+    containers:
+    - {name: api, image: gcr.io/k8s-skaffold/api:v1}
+  status:
+    containerStatuses:
+    - name: api
+      imageID: ""
+      state: {waiting: {reason: ContainerCreating}}
+`, nil),
+	)
+
+	manifests := ManifestList{
+		[]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: ns\nspec:\n  selector:\n    matchLabels:\n      app: web\n"),
+		[]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\n  namespace: ns\nspec:\n  selector:\n    matchLabels:\n      app: api\n"),
+		[]byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: ns\n"),
+	}
+	builds := []build.Artifact{
+		{ImageName: "gcr.io/k8s-skaffold/web", Tag: "gcr.io/k8s-skaffold/web:v1"},
+		{ImageName: "gcr.io/k8s-skaffold/api", Tag: "gcr.io/k8s-skaffold/api:v1"},
+	}
+
+	cli := &CLI{KubeContext: "kubecontext"}
+	pulled := cli.PulledImages(context.Background(), manifests, builds)
+
+	testutil.CheckDeepEqual(t, []PulledImage{
+		{Workload: "deployment/web", Image: "gcr.io/k8s-skaffold/web:v1", Digest: "sha256:abc"},
+	}, pulled)
+}
+
+func TestDigestOf(t *testing.T) {
+	var tests = []struct {
+		imageID  string
+		expected string
+	}{
+		{imageID: "docker-pullable://gcr.io/k8s-skaffold/web@sha256:abc", expected: "sha256:abc"},
+		{imageID: "gcr.io/k8s-skaffold/web@sha256:abc", expected: "sha256:abc"},
+		{imageID: "docker://sha256:def", expected: "sha256:def"},
+		{imageID: "sha256:def", expected: "sha256:def"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.imageID, func(t *testing.T) {
+			testutil.CheckDeepEqual(t, test.expected, digestOf(test.imageID))
+		})
+	}
+}
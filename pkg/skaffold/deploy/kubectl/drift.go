@@ -0,0 +1,105 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// warnDrift warns about the resources about to be applied that were changed
+// in the cluster since they were last applied. The live resources are compared
+// to the manifests previously applied by this session or, on the first apply,
+// to their `kubectl.kubernetes.io/last-applied-configuration` annotation.
+// Only the live resources matching the drift selector are checked. This is
+// report-only: errors are logged.
+func (c *CLI) warnDrift(ctx context.Context, out io.Writer, manifests ManifestList) {
+	if c.DriftSelector == nil {
+		return
+	}
+
+	previous := map[Resource][]byte{}
+	for _, manifest := range c.previousApply {
+		previous[resourceOf(manifest)] = manifest
+	}
+
+	for _, manifest := range manifests {
+		r := resourceOf(manifest)
+
+		drifted, err := c.drifted(ctx, manifest, previous[r])
+		if err != nil {
+			logrus.Debugln("Unable to check drift of", r.Name, err)
+			continue
+		}
+
+		if drifted {
+			color.Yellow.Fprintln(out, "Warning:", strings.ToLower(r.Kind)+"/"+r.Name, "was changed in the cluster since it was last deployed. Those changes will be overwritten.")
+		}
+	}
+}
+
+// drifted checks if the live resource differs from the manifest that was
+// last applied: the given one, or the one recorded in the live resource.
+func (c *CLI) drifted(ctx context.Context, manifest []byte, applied []byte) (bool, error) {
+	single := ManifestList{manifest}
+	live, err := c.runOut(ctx, single.Reader(), "", "get", nil, "--ignore-not-found=true", "-o", "yaml", "-f", "-")
+	if err != nil {
+		return false, err
+	}
+	if len(bytes.TrimSpace(live)) == 0 {
+		// Deleted resources are not drift, they are simply created again.
+		return false, nil
+	}
+
+	var m struct {
+		Metadata struct {
+			Labels      map[string]string `yaml:"labels"`
+			Annotations map[string]string `yaml:"annotations"`
+		} `yaml:"metadata"`
+	}
+	if err := yaml.Unmarshal(live, &m); err != nil {
+		return false, err
+	}
+	if !c.DriftSelector.Matches(labels.Set(m.Metadata.Labels)) {
+		return false, nil
+	}
+
+	if applied == nil {
+		lastApplied := m.Metadata.Annotations[lastAppliedAnnotation]
+		if lastApplied == "" {
+			// Resources applied server-side don't record what was applied.
+			return false, nil
+		}
+		applied = []byte(lastApplied)
+	}
+	previous := ManifestList{applied}
+
+	// `kubectl diff` exits with 1 when there are differences.
+	diff, err := c.runOut(ctx, previous.Reader(), "", "diff", nil, "-f", "-")
+	if len(bytes.TrimSpace(diff)) > 0 {
+		return true, nil
+	}
+
+	return false, err
+}
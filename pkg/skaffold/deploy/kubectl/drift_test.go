@@ -0,0 +1,136 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const getLiveYAML = "kubectl --context kubecontext get --ignore-not-found=true -o yaml -f -"
+
+func TestApplyWarnsAboutDrift(t *testing.T) {
+	var tests = []struct {
+		description string
+		live        string
+		diffed      bool
+		diff        string
+		expected    string
+	}{
+		{
+			description: "drifted",
+			live:        "metadata:\n  labels:\n    skaffold-deployer: kustomize\n",
+			diffed:      true,
+			diff:        "-  port: 80\n+  port: 8080\n",
+			expected:    "Warning: service/leeroy-web was changed in the cluster since it was last deployed. Those changes will be overwritten.\n",
+		},
+		{
+			description: "not drifted",
+			live:        "metadata:\n  labels:\n    skaffold-deployer: kustomize\n",
+			diffed:      true,
+		},
+		{
+			description: "not deployed by skaffold",
+			live:        "metadata:\n  labels:\n    app: leeroy-web\n",
+		},
+		{
+			description: "deleted",
+		},
+	}
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmds := []*testutil.FakeCmd{
+				testutil.NewFakeCmdOut(getLiveYAML, "", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+				testutil.NewFakeCmdOut(getLiveYAML, test.live, nil),
+			}
+			if test.diffed {
+				var err error
+				if test.diff != "" {
+					err = fmt.Errorf("exit status 1")
+				}
+				cmds = append(cmds, testutil.NewFakeCmdOut("kubectl --context kubecontext diff -f -", test.diff, err))
+			}
+			cmds = append(cmds, testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil))
+			util.DefaultExecCommand = testutil.NewFakeCmds(cmds...)
+
+			cli := &CLI{KubeContext: "kubecontext", DriftSelector: labels.SelectorFromSet(map[string]string{"skaffold-deployer": "kustomize"})}
+			_, err := cli.Apply(context.Background(), &bytes.Buffer{}, ManifestList{[]byte(serviceYAML)})
+			testutil.CheckError(t, false, err)
+
+			var out bytes.Buffer
+			_, err = cli.Apply(context.Background(), &out, ManifestList{[]byte(serviceYAML + "spec: {}\n")})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, out.String())
+		})
+	}
+}
+
+func TestFirstApplyWarnsAboutDrift(t *testing.T) {
+	var tests = []struct {
+		description string
+		live        string
+		diffed      bool
+		expected    string
+	}{
+		{
+			description: "changed since last applied",
+			live: `metadata:
+  labels:
+    skaffold-deployer: kustomize
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: '{"apiVersion":"v1","kind":"Service","metadata":{"name":"leeroy-web"}}'
+`,
+			diffed:   true,
+			expected: "Warning: service/leeroy-web was changed in the cluster since it was last deployed. Those changes will be overwritten.\n",
+		},
+		{
+			description: "applied server-side",
+			live:        "metadata:\n  labels:\n    skaffold-deployer: kustomize\n",
+		},
+	}
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmds := []*testutil.FakeCmd{
+				testutil.NewFakeCmdOut(getLiveYAML, test.live, nil),
+			}
+			if test.diffed {
+				cmds = append(cmds, testutil.NewFakeCmdOut("kubectl --context kubecontext diff -f -", "-  port: 80\n+  port: 8080\n", fmt.Errorf("exit status 1")))
+			}
+			cmds = append(cmds, testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil))
+			util.DefaultExecCommand = testutil.NewFakeCmds(cmds...)
+
+			var out bytes.Buffer
+			cli := &CLI{KubeContext: "kubecontext", DriftSelector: labels.SelectorFromSet(map[string]string{"skaffold-deployer": "kustomize"})}
+			_, err := cli.Apply(context.Background(), &out, ManifestList{[]byte(serviceYAML)})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, out.String())
+		})
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// serverDryRun sends the manifests to the API server with `--dry-run=server`
+// so that admission webhooks and quotas can reject them before anything
+// is persisted. The output is captured so that the rejection is part of the error.
+func (c *CLI) serverDryRun(ctx context.Context, manifests ManifestList) error {
+	manifests, err := manifests.dryRunnable()
+	if err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		return nil
+	}
+
+	args := []string{"--dry-run=server"}
+	if c.Validation != "" {
+		args = append(args, "--validate="+c.Validation)
+	}
+	args = append(args, "-f", "-")
+
+	if _, err := c.runOut(ctx, manifests.Reader(), "", "apply", c.Flags.Apply, args...); err != nil {
+		return errors.Wrap(err, "server-side dry-run rejected the manifests, nothing was applied")
+	}
+
+	return nil
+}
+
+// dryRunnable removes the resources that can't be checked before anything
+// is applied: custom resources of a CustomResourceDefinition and resources of
+// a Namespace that are part of the same manifests. A dry-run doesn't create the
+// definition or the namespace, so the server would reject them.
+func (l *ManifestList) dryRunnable() (ManifestList, error) {
+	definedKinds, err := l.customResourceKinds()
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := map[string]bool{}
+	created := l.Select(schema.GroupVersionKind{Kind: "Namespace"})
+	for _, r := range created.Resources() {
+		namespaces[r.Name] = true
+	}
+
+	return l.Filter(func(r Resource) bool {
+		if definedKinds[groupKind(r)] || namespaces[r.Namespace] {
+			logrus.Debugf("Not dry-running %s/%s: it depends on a resource created by the same apply", r.Kind, r.Name)
+			return false
+		}
+		return true
+	}), nil
+}
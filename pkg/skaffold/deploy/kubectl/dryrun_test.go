@@ -0,0 +1,145 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const dryRunApply = "kubectl --context kubecontext apply --dry-run=server -f -"
+
+func TestServerDryRun(t *testing.T) {
+	var tests = []struct {
+		description string
+		command     util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "accepted",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(dryRunApply, "", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+			),
+		},
+		{
+			description: "rejected by admission",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(dryRunApply, "", fmt.Errorf("admission webhook denied the request")),
+			),
+			shouldErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			cli := &CLI{KubeContext: "kubecontext", ServerDryRun: true}
+			_, err := cli.Apply(context.Background(), ioutil.Discard, ManifestList{[]byte(podYAML)})
+
+			testutil.CheckError(t, test.shouldErr, err)
+			if err != nil && !strings.Contains(err.Error(), "admission webhook denied the request") {
+				t.Errorf("expected the admission error to be reported, got %v", err)
+			}
+		})
+	}
+}
+
+func TestServerDryRunRejectedIsNotRecorded(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut(dryRunApply, "", fmt.Errorf("denied")),
+		testutil.NewFakeCmdOut(dryRunApply, "", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+	)
+
+	cli := &CLI{KubeContext: "kubecontext", ServerDryRun: true}
+	manifests := ManifestList{[]byte(podYAML)}
+
+	_, err := cli.Apply(context.Background(), ioutil.Discard, manifests)
+	testutil.CheckError(t, true, err)
+
+	updated, err := cli.Apply(context.Background(), ioutil.Discard, manifests)
+	testutil.CheckErrorAndDeepEqual(t, false, err, manifests, updated)
+}
+
+// recordingDryRun records the manifests sent to the server-side dry-run.
+type recordingDryRun struct {
+	checked []string
+}
+
+func (r *recordingDryRun) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	if command := strings.Join(cmd.Args, " "); command != dryRunApply {
+		return nil, fmt.Errorf("unexpected command: %s", command)
+	}
+	in, _ := ioutil.ReadAll(cmd.Stdin)
+	r.checked = append(r.checked, string(in))
+	return []byte{}, nil
+}
+
+func (r *recordingDryRun) RunCmd(cmd *exec.Cmd) error {
+	return fmt.Errorf("unexpected command: %s", strings.Join(cmd.Args, " "))
+}
+
+func TestServerDryRunCreatedBySameApply(t *testing.T) {
+	namespaceYAML := "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: team\n"
+	inNamespace := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: team\n"
+
+	var tests = []struct {
+		description string
+		manifests   ManifestList
+		expected    ManifestList
+	}{
+		{
+			description: "custom resources of a new definition",
+			manifests:   ManifestList{[]byte(crdYAML), []byte(crYAML), []byte(podYAML)},
+			expected:    ManifestList{[]byte(crdYAML), []byte(podYAML)},
+		},
+		{
+			description: "resources of a new namespace",
+			manifests:   ManifestList{[]byte(namespaceYAML), []byte(inNamespace)},
+			expected:    ManifestList{[]byte(namespaceYAML)},
+		},
+		{
+			description: "only custom resources of existing definitions",
+			manifests:   ManifestList{[]byte(crYAML)},
+			expected:    ManifestList{[]byte(crYAML)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			recorder := &recordingDryRun{}
+			util.DefaultExecCommand = recorder
+
+			cli := &CLI{KubeContext: "kubecontext", ServerDryRun: true}
+			err := cli.serverDryRun(context.Background(), test.manifests)
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, []string{test.expected.String()}, recorder.checked)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	yaml "gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Resource identifies a kubernetes resource described by a manifest.
+type Resource struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+}
+
+// GroupVersionKind returns the group, version and kind of the resource.
+func (r Resource) GroupVersionKind() schema.GroupVersionKind {
+	return schema.FromAPIVersionAndKind(r.APIVersion, r.Kind)
+}
+
+// Filter returns the manifests for which the predicate returns true.
+// Manifests are returned unchanged, byte for byte.
+func (l *ManifestList) Filter(predicate func(Resource) bool) ManifestList {
+	var filtered ManifestList
+
+	for _, manifest := range *l {
+		if predicate(resourceOf(manifest)) {
+			filtered = append(filtered, manifest)
+		}
+	}
+
+	return filtered
+}
+
+// Select returns the manifests of the given group, version and kind. An empty
+// version selects every version of the group and kind.
+// Manifests are returned unchanged, byte for byte.
+func (l *ManifestList) Select(gvk schema.GroupVersionKind) ManifestList {
+	return l.Filter(func(r Resource) bool {
+		actual := r.GroupVersionKind()
+		if gvk.Version == "" {
+			return actual.GroupKind() == gvk.GroupKind()
+		}
+		return actual == gvk
+	})
+}
+
+// SelectByLabels returns the manifests whose labels match the selector.
+// Manifests are returned unchanged, byte for byte.
+func (l *ManifestList) SelectByLabels(selector labels.Selector) ManifestList {
+	var selected ManifestList
+
+	for _, manifest := range *l {
+		var m struct {
+			Metadata struct {
+				Labels map[string]string `yaml:"labels"`
+			} `yaml:"metadata"`
+		}
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			continue
+		}
+
+		if selector.Matches(labels.Set(m.Metadata.Labels)) {
+			selected = append(selected, manifest)
+		}
+	}
+
+	return selected
+}
+
+// Resources returns the identity of every resource in the list.
+func (l *ManifestList) Resources() []Resource {
+	var resources []Resource
+
+	for _, manifest := range *l {
+		resources = append(resources, resourceOf(manifest))
+	}
+
+	return resources
+}
+
+// resourceOf decodes only the identity of a resource. Manifests
+// that can't be decoded yield a zero Resource.
+func resourceOf(manifest []byte) Resource {
+	var m struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Namespace string `yaml:"namespace"`
+			Name      string `yaml:"name"`
+		} `yaml:"metadata"`
+	}
+
+	if err := yaml.Unmarshal(manifest, &m); err != nil {
+		return Resource{}
+	}
+
+	return Resource{
+		APIVersion: m.APIVersion,
+		Kind:       m.Kind,
+		Namespace:  m.Metadata.Namespace,
+		Name:       m.Metadata.Name,
+	}
+}
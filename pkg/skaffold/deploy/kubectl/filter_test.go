@@ -0,0 +1,167 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const podYAML = `apiVersion: v1
+kind: Pod
+metadata:
+  # comments and formatting are kept
+  name: leeroy-web
+  namespace:   ns
+spec:
+  containers:
+  - {name: leeroy-web, image: leeroy-web}
+`
+
+const serviceYAML = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: leeroy-web
+`
+
+func TestFilter(t *testing.T) {
+	var tests = []struct {
+		description string
+		predicate   func(Resource) bool
+		expected    ManifestList
+	}{
+		{
+			description: "by kind",
+			predicate:   func(r Resource) bool { return r.Kind == "Pod" },
+			expected:    ManifestList{[]byte(podYAML)},
+		},
+		{
+			description: "by identity",
+			predicate: func(r Resource) bool {
+				return r == Resource{APIVersion: "v1", Kind: "Pod", Namespace: "ns", Name: "leeroy-web"}
+			},
+			expected: ManifestList{[]byte(podYAML)},
+		},
+		{
+			description: "all",
+			predicate:   func(Resource) bool { return true },
+			expected:    ManifestList{[]byte(podYAML), []byte(serviceYAML), []byte("INVALID")},
+		},
+		{
+			description: "invalid manifest is a zero resource",
+			predicate:   func(r Resource) bool { return r == Resource{} },
+			expected:    ManifestList{[]byte("INVALID")},
+		},
+		{
+			description: "none",
+			predicate:   func(Resource) bool { return false },
+		},
+	}
+
+	manifests := ManifestList{[]byte(podYAML), []byte(serviceYAML), []byte("INVALID")}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			filtered := manifests.Filter(test.predicate)
+
+			testutil.CheckDeepEqual(t, test.expected, filtered)
+		})
+	}
+}
+
+func TestSelectByLabels(t *testing.T) {
+	api := []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: api
+  labels:
+    app: api
+`)
+	web := []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  labels:
+    app: web
+`)
+	manifests := ManifestList{api, web, []byte(serviceYAML)}
+
+	selector, err := labels.Parse("app=api")
+	testutil.CheckError(t, false, err)
+
+	testutil.CheckDeepEqual(t, ManifestList{api}, manifests.SelectByLabels(selector))
+}
+
+func TestSelect(t *testing.T) {
+	appsV1 := []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`)
+	extensions := []byte(`apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: legacy
+`)
+	appsV1beta2 := []byte(`apiVersion: apps/v1beta2
+kind: Deployment
+metadata:
+  name: beta
+`)
+	manifests := ManifestList{appsV1, []byte(podYAML), extensions, appsV1beta2, []byte(serviceYAML), []byte("INVALID")}
+
+	var tests = []struct {
+		description string
+		gvk         schema.GroupVersionKind
+		expected    ManifestList
+	}{
+		{
+			description: "group and version",
+			gvk:         schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			expected:    ManifestList{appsV1},
+		},
+		{
+			description: "other group",
+			gvk:         schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Deployment"},
+			expected:    ManifestList{extensions},
+		},
+		{
+			description: "every version of the group",
+			gvk:         schema.GroupVersionKind{Group: "apps", Kind: "Deployment"},
+			expected:    ManifestList{appsV1, appsV1beta2},
+		},
+		{
+			description: "core group",
+			gvk:         schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+			expected:    ManifestList{[]byte(serviceYAML)},
+		},
+		{
+			description: "none",
+			gvk:         schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			testutil.CheckDeepEqual(t, test.expected, manifests.Select(test.gvk))
+		})
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// historyKeyFormat is a timestamp that is a valid ConfigMap key and
+// whose lexical order is the chronological order.
+const historyKeyFormat = "20060102T150405.000Z"
+
+// for testing
+var now = time.Now
+
+// HistoryEntry describes a deploy recorded in the history ConfigMap.
+type HistoryEntry struct {
+	Hash      string            `yaml:"hash"`
+	Images    map[string]string `yaml:"images,omitempty"`
+	Resources []string          `yaml:"resources,omitempty"`
+}
+
+// NewHistoryEntry describes the deploy of the given manifests and images.
+func NewHistoryEntry(manifests ManifestList, images map[string]string) HistoryEntry {
+	sum := sha256.Sum256([]byte(manifests.String()))
+
+	var resources []string
+	for _, r := range manifests.Resources() {
+		if r.Kind != "" && r.Name != "" {
+			resources = append(resources, strings.ToLower(r.Kind)+"/"+r.Name)
+		}
+	}
+
+	return HistoryEntry{
+		Hash:      hex.EncodeToString(sum[:]),
+		Images:    images,
+		Resources: resources,
+	}
+}
+
+// RecordHistory adds an entry, keyed by timestamp, to the history ConfigMap
+// of the namespace. Only the last limit entries are kept.
+func (c *CLI) RecordHistory(ctx context.Context, entry HistoryEntry, limit int) error {
+	live, err := c.runOut(ctx, nil, c.Namespace, "get", nil, "configmap", c.HistoryConfigMap, "--ignore-not-found=true", "-o", "yaml")
+	if err != nil {
+		return errors.Wrap(err, "getting history")
+	}
+
+	var configMap struct {
+		Data map[string]string `yaml:"data"`
+	}
+	if err := yaml.Unmarshal(live, &configMap); err != nil {
+		return errors.Wrap(err, "reading history")
+	}
+
+	value, err := yaml.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "marshalling history entry")
+	}
+
+	data := configMap.Data
+	if data == nil {
+		data = map[string]string{}
+	}
+	data[now().UTC().Format(historyKeyFormat)] = string(value)
+
+	var keys []string
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		for _, key := range keys[:len(keys)-limit] {
+			delete(data, key)
+		}
+	}
+
+	manifest, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name": c.HistoryConfigMap,
+		},
+		"data": data,
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshalling history")
+	}
+
+	if _, err := c.runOut(ctx, bytes.NewReader(manifest), c.Namespace, "apply", nil, "-f", "-"); err != nil {
+		return errors.Wrap(err, "recording history")
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+// historyRecorder fakes the history ConfigMap and records the applied one.
+type historyRecorder struct {
+	live     string
+	commands []string
+	applied  string
+}
+
+func (r *historyRecorder) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	r.commands = append(r.commands, strings.Join(cmd.Args, " "))
+	if cmd.Stdin == nil {
+		return []byte(r.live), nil
+	}
+
+	in, err := ioutil.ReadAll(cmd.Stdin)
+	r.applied = string(in)
+	return nil, err
+}
+
+func (r *historyRecorder) RunCmd(cmd *exec.Cmd) error {
+	return nil
+}
+
+func TestRecordHistory(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	recorder := &historyRecorder{live: `apiVersion: v1
+kind: ConfigMap
+data:
+  20180901T100000.000Z: "hash: first\n"
+  20180902T100000.000Z: "hash: second\n"
+`}
+	util.DefaultExecCommand = recorder
+
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time { return time.Date(2018, 9, 3, 10, 0, 0, 0, time.UTC) }
+
+	cli := &CLI{KubeContext: "kubecontext", Namespace: "ns", HistoryConfigMap: "skaffold-history"}
+	entry := HistoryEntry{Hash: "third", Images: map[string]string{"leeroy-web": "leeroy-web:v1"}, Resources: []string{"pod/leeroy-web"}}
+	err := cli.RecordHistory(context.Background(), entry, 2)
+
+	testutil.CheckError(t, false, err)
+	testutil.CheckDeepEqual(t, []string{
+		"kubectl --context kubecontext --namespace ns get configmap skaffold-history --ignore-not-found=true -o yaml",
+		"kubectl --context kubecontext --namespace ns apply -f -",
+	}, recorder.commands)
+	testutil.CheckDeepEqual(t, `apiVersion: v1
+data:
+  20180902T100000.000Z: |
+    hash: second
+  20180903T100000.000Z: |
+    hash: third
+    images:
+      leeroy-web: leeroy-web:v1
+    resources:
+    - pod/leeroy-web
+kind: ConfigMap
+metadata:
+  name: skaffold-history
+`, recorder.applied)
+}
+
+func TestNewHistoryEntry(t *testing.T) {
+	entry := NewHistoryEntry(ManifestList{[]byte(podYAML), []byte(serviceYAML)}, nil)
+
+	testutil.CheckDeepEqual(t, []string{"pod/leeroy-web", "service/leeroy-web"}, entry.Resources)
+	testutil.CheckDeepEqual(t, 64, len(entry.Hash))
+}
+
+func TestLeftoversKeepHistory(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmdOut("kubectl --context kubecontext get configmaps -l app=web -o yaml", `apiVersion: v1
+kind: List
+items:
+- {apiVersion: v1, kind: ConfigMap, metadata: {namespace: default, name: skaffold-history, labels: {app: web}}}
+- {apiVersion: v1, kind: ConfigMap, metadata: {namespace: default, name: old, labels: {app: web}}}
+`, nil)
+	fakeDeletableResources("kubecontext", "configmaps")
+
+	cli := &CLI{KubeContext: "kubecontext", HistoryConfigMap: "skaffold-history"}
+	leftovers, err := cli.Leftovers(context.Background(), "app=web", nil)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"configmap/old"}, leftovers)
+}
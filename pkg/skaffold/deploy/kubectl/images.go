@@ -17,8 +17,12 @@ limitations under the License.
 package kubectl
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
@@ -27,35 +31,200 @@ import (
 // for testing
 var warner Warner = &logrusWarner{}
 
+const (
+	// ImageMatchStrict only replaces the images of the manifests that don't
+	// pin a tag. Pinned tags are left untouched.
+	ImageMatchStrict = "strict"
+	// ImageMatchRepository matches images by repository, ignoring the tag
+	// pinned by the manifests, which is overwritten by the built tag.
+	ImageMatchRepository = "repository"
+)
+
+// ReplaceOptions configure how ReplaceImages replaces the images.
+type ReplaceOptions struct {
+	// Lock pins digests that take precedence over the built tags.
+	// Images absent from the lock use the built tags.
+	Lock *ImageLock
+
+	// Mirrors match images by their canonical name, and write every
+	// image reference in the form they are configured for.
+	Mirrors *RegistryMirrors
+
+	// Match is either strict, the default, or repository. Images pinned
+	// by digest are never overwritten.
+	Match string
+}
+
 // ReplaceImages replaces image names in a list of manifests.
-func (l *ManifestList) ReplaceImages(builds []build.Artifact) (ManifestList, error) {
-	replacer := newImageReplacer(builds)
+// Images are matched by image string, never by resource name, so the
+// names transformed by kustomize's namePrefix or nameSuffix don't matter.
+func (l *ManifestList) ReplaceImages(builds []build.Artifact, opts ReplaceOptions) (ManifestList, error) {
+	switch opts.Match {
+	case "", ImageMatchStrict, ImageMatchRepository:
+	default:
+		return nil, errors.Errorf("invalid image match %q, must be %s or %s", opts.Match, ImageMatchStrict, ImageMatchRepository)
+	}
+
+	replacer := newImageReplacer(builds, opts.Mirrors)
+	replacer.ignoreTags = opts.Match == ImageMatchRepository
+	if opts.Lock != nil {
+		for imageName, tag := range opts.Lock.tags() {
+			replacer.tagsByImageName[opts.Mirrors.Canonical(imageName)] = tag
+		}
+	}
 
 	updated, err := l.Visit(replacer)
 	if err != nil {
 		return nil, errors.Wrap(err, "replacing images")
 	}
 
+	if opts.Lock != nil && opts.Lock.Strict {
+		if err := replacer.checkLocked(opts.Lock); err != nil {
+			return nil, err
+		}
+	}
+
 	replacer.Check()
 	logrus.Debugln("manifests with tagged images", updated.String())
 
 	return updated, nil
 }
 
+// GetImages returns the sorted list of images used by the containers,
+// init containers and ephemeral containers of every pod spec, whatever the kind of workload.
+// Manifests that can't be decoded are ignored.
+func (l *ManifestList) GetImages() []string {
+	seen := map[string]bool{}
+
+	for _, manifest := range *l {
+		m := make(map[interface{}]interface{})
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			continue
+		}
+
+		collectImages(m, seen)
+	}
+
+	var images []string
+	for image := range seen {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	return images
+}
+
+// UnqualifiedImages returns the sorted list of images that don't name
+// their registry host explicitly and rely on the default registry.
+// Images are parsed as ReplaceImages does.
+func (l *ManifestList) UnqualifiedImages() []string {
+	var unqualified []string
+
+	for _, image := range l.GetImages() {
+		parsed, err := docker.ParseReference(image)
+		if err != nil {
+			warner.Warnf("Couldn't parse image: %s", image)
+			continue
+		}
+
+		if parsed.Registry == "" {
+			unqualified = append(unqualified, image)
+		}
+	}
+
+	return unqualified
+}
+
+func collectImages(value interface{}, images map[string]bool) {
+	switch t := value.(type) {
+	case []interface{}:
+		for _, v := range t {
+			collectImages(v, images)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range t {
+			switch k {
+			case "containers", "initContainers", "ephemeralContainers":
+				containers, _ := v.([]interface{})
+				for _, c := range containers {
+					container, _ := c.(map[interface{}]interface{})
+					if image, ok := container["image"].(string); ok && image != "" {
+						images[image] = true
+					}
+				}
+			default:
+				collectImages(v, images)
+			}
+		}
+	}
+}
+
+// MapImageNames renames the built artifacts after the logical image names used
+// by the manifests. imageNames maps logical image names to artifact image names.
+// Artifacts that are not mapped keep their name.
+func MapImageNames(builds []build.Artifact, imageNames map[string]string) ([]build.Artifact, error) {
+	if len(imageNames) == 0 {
+		return builds, nil
+	}
+
+	tagsByImageName := make(map[string]string)
+	for _, b := range builds {
+		tagsByImageName[b.ImageName] = b.Tag
+	}
+
+	var logicalNames []string
+	mapped := make(map[string]bool)
+	for logicalName, imageName := range imageNames {
+		if _, present := tagsByImageName[imageName]; !present {
+			return nil, errors.Errorf("image %s is mapped to %s, which is not a built artifact", logicalName, imageName)
+		}
+		logicalNames = append(logicalNames, logicalName)
+		mapped[imageName] = true
+	}
+	sort.Strings(logicalNames)
+
+	var renamed []build.Artifact
+	for _, b := range builds {
+		if !mapped[b.ImageName] {
+			renamed = append(renamed, b)
+		}
+	}
+	for _, logicalName := range logicalNames {
+		renamed = append(renamed, build.Artifact{
+			ImageName: logicalName,
+			Tag:       tagsByImageName[imageNames[logicalName]],
+		})
+	}
+
+	return renamed, nil
+}
+
 type imageReplacer struct {
 	tagsByImageName map[string]string
 	found           map[string]bool
+	mirrors         *RegistryMirrors
+	ignoreTags      bool
 }
 
-func newImageReplacer(builds []build.Artifact) *imageReplacer {
+func newImageReplacer(builds []build.Artifact, mirrors *RegistryMirrors) *imageReplacer {
+	// Two builds of the same image, possibly through a mirror, are ambiguous.
+	// The first one wins, whatever the order of the others.
 	tagsByImageName := make(map[string]string)
 	for _, build := range builds {
-		tagsByImageName[build.ImageName] = build.Tag
+		imageName := mirrors.Canonical(build.ImageName)
+		if tag, found := tagsByImageName[imageName]; found {
+			if tag != build.Tag {
+				warner.Warnf("Image %s was built more than once, using %s and ignoring %s", imageName, tag, build.Tag)
+			}
+			continue
+		}
+		tagsByImageName[imageName] = build.Tag
 	}
 
 	return &imageReplacer{
 		tagsByImageName: tagsByImageName,
 		found:           make(map[string]bool),
+		mirrors:         mirrors,
 	}
 }
 
@@ -66,30 +235,62 @@ func (r *imageReplacer) Matches(key string) bool {
 func (r *imageReplacer) NewValue(key string, old interface{}) (bool, interface{}) {
 	image := old.(string)
 
-	parsed, err := docker.ParseReference(image)
+	parsed, err := docker.ParseReference(r.mirrors.Canonical(image))
 	if err != nil {
 		warner.Warnf("Couldn't parse image: %s", image)
 		return false, nil
 	}
 
 	if tag, present := r.tagsByImageName[parsed.BaseName]; present {
-		if parsed.FullyQualified {
-			if tag == image {
+		if parsed.FullyQualified && !r.overwrites(image, parsed) {
+			if r.mirrors.Canonical(tag) == r.mirrors.Canonical(image) {
 				r.found[parsed.BaseName] = true
 			}
 		} else {
 			r.found[parsed.BaseName] = true
-			return true, tag
+			return true, r.mirrors.Written(tag)
 		}
 	}
 
+	if written := r.mirrors.Written(image); written != image {
+		return true, written
+	}
+
 	return false, nil
 }
 
+// overwrites checks if the tag pinned by an image is ignored.
+func (r *imageReplacer) overwrites(image string, parsed *docker.ImageReference) bool {
+	return r.ignoreTags && parsed.Tag != "" && !strings.Contains(image, "@")
+}
+
 func (r *imageReplacer) Check() {
+	var imageNames []string
 	for imageName := range r.tagsByImageName {
+		imageNames = append(imageNames, imageName)
+	}
+	sort.Strings(imageNames)
+
+	for _, imageName := range imageNames {
 		if !r.found[imageName] {
 			warner.Warnf("image [%s] is not used by the deployment", imageName)
 		}
 	}
 }
+
+// checkLocked fails if a pinned image is not used by the manifests.
+func (r *imageReplacer) checkLocked(lock *ImageLock) error {
+	var missing []string
+	for imageName := range lock.Digests {
+		if !r.found[r.mirrors.Canonical(imageName)] {
+			missing = append(missing, imageName)
+		}
+	}
+	sort.Strings(missing)
+
+	if len(missing) > 0 {
+		return errors.Errorf("images pinned in the lockfile are not used by the deployment: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
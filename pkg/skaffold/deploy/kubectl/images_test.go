@@ -100,7 +100,7 @@ spec:
 	fakeWarner := &fakeWarner{}
 	warner = fakeWarner
 
-	resultManifest, err := manifests.ReplaceImages(builds)
+	resultManifest, err := manifests.ReplaceImages(builds, ReplaceOptions{})
 
 	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
 	testutil.CheckErrorAndDeepEqual(t, false, err, []string{
@@ -110,11 +110,31 @@ spec:
 	}, fakeWarner.warnings)
 }
 
+func TestReplaceImagesBuiltTwice(t *testing.T) {
+	manifests := ManifestList{[]byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\nspec:\n  containers:\n  - image: skaffold/web\n")}
+	builds := []build.Artifact{
+		{ImageName: "skaffold/web", Tag: "skaffold/web:FIRST"},
+		{ImageName: "skaffold/web", Tag: "skaffold/web:IGNORED"},
+		{ImageName: "skaffold/web", Tag: "skaffold/web:FIRST"},
+	}
+
+	defer func(w Warner) { warner = w }(warner)
+	fakeWarner := &fakeWarner{}
+	warner = fakeWarner
+
+	resultManifest, err := manifests.ReplaceImages(builds, ReplaceOptions{})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\nspec:\n  containers:\n  - image: skaffold/web:FIRST", resultManifest.String())
+	testutil.CheckDeepEqual(t, []string{
+		"Image skaffold/web was built more than once, using skaffold/web:FIRST and ignoring skaffold/web:IGNORED",
+	}, fakeWarner.warnings)
+}
+
 func TestReplaceEmptyManifest(t *testing.T) {
 	manifests := ManifestList{[]byte(""), []byte("  ")}
 	expected := ManifestList{}
 
-	resultManifest, err := manifests.ReplaceImages(nil)
+	resultManifest, err := manifests.ReplaceImages(nil, ReplaceOptions{})
 
 	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
 }
@@ -122,7 +142,338 @@ func TestReplaceEmptyManifest(t *testing.T) {
 func TestReplaceInvalidManifest(t *testing.T) {
 	manifests := ManifestList{[]byte("INVALID")}
 
-	_, err := manifests.ReplaceImages(nil)
+	_, err := manifests.ReplaceImages(nil, ReplaceOptions{})
+
+	testutil.CheckError(t, true, err)
+}
+
+func TestReplaceImagesMatch(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: getting-started
+spec:
+  containers:
+  - image: myrepo/api:v1
+    name: pinned
+  - image: myrepo/api
+    name: not-tagged
+  - image: myrepo/api@sha256:81daf011d63b68cfa514ddab7741a1adddd59d3264118dfb0fd9266328bb8883
+    name: digest
+  - image: myrepo/other:v1
+    name: not-built
+`)}
+
+	builds := []build.Artifact{{ImageName: "myrepo/api", Tag: "myrepo/api:abcdef"}}
+
+	var tests = []struct {
+		description string
+		match       string
+		expected    ManifestList
+		warnings    []string
+	}{
+		{
+			description: "strict keeps pinned tags",
+			match:       ImageMatchStrict,
+			expected: ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: getting-started
+spec:
+  containers:
+  - image: myrepo/api:v1
+    name: pinned
+  - image: myrepo/api:abcdef
+    name: not-tagged
+  - image: myrepo/api@sha256:81daf011d63b68cfa514ddab7741a1adddd59d3264118dfb0fd9266328bb8883
+    name: digest
+  - image: myrepo/other:v1
+    name: not-built
+`)},
+		},
+		{
+			description: "repository overwrites pinned tags",
+			match:       ImageMatchRepository,
+			expected: ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: getting-started
+spec:
+  containers:
+  - image: myrepo/api:abcdef
+    name: pinned
+  - image: myrepo/api:abcdef
+    name: not-tagged
+  - image: myrepo/api@sha256:81daf011d63b68cfa514ddab7741a1adddd59d3264118dfb0fd9266328bb8883
+    name: digest
+  - image: myrepo/other:v1
+    name: not-built
+`)},
+		},
+	}
+
+	defer func(w Warner) { warner = w }(warner)
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			fakeWarner := &fakeWarner{}
+			warner = fakeWarner
+
+			resultManifest, err := manifests.ReplaceImages(builds, ReplaceOptions{Match: test.match})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected.String(), resultManifest.String())
+			testutil.CheckDeepEqual(t, test.warnings, fakeWarner.warnings)
+		})
+	}
+}
+
+func TestReplaceImagesInvalidMatch(t *testing.T) {
+	manifests := ManifestList{[]byte(podYAML)}
+
+	_, err := manifests.ReplaceImages(nil, ReplaceOptions{Match: "tag"})
+
+	testutil.CheckError(t, true, err)
+}
+
+func TestReplaceImagesIsReproducible(t *testing.T) {
+	manifests := ManifestList{[]byte(`kind: Pod
+metadata:
+  name: getting-started
+spec:
+  containers:
+  - name: web
+    image: gcr.io/k8s-skaffold/web
+  - name: app
+    image: gcr.io/k8s-skaffold/app
+`), []byte(`kind: ConfigMap
+metadata: {name: untouched}
+`)}
+
+	builds := []build.Artifact{{
+		ImageName: "gcr.io/k8s-skaffold/web",
+		Tag:       "gcr.io/k8s-skaffold/web:TAG",
+	}, {
+		ImageName: "gcr.io/k8s-skaffold/app",
+		Tag:       "gcr.io/k8s-skaffold/app:TAG",
+	}}
+
+	expected := ManifestList{[]byte(`kind: Pod
+metadata:
+  name: getting-started
+spec:
+  containers:
+  - name: web
+    image: gcr.io/k8s-skaffold/web:TAG
+  - name: app
+    image: gcr.io/k8s-skaffold/app:TAG
+`), []byte(`kind: ConfigMap
+metadata: {name: untouched}
+`)}
+
+	first, err := manifests.ReplaceImages(builds, ReplaceOptions{})
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected, first)
 
+	second, err := manifests.ReplaceImages([]build.Artifact{builds[1], builds[0]}, ReplaceOptions{})
+	testutil.CheckErrorAndDeepEqual(t, false, err, first, second)
+}
+
+func TestMapImageNames(t *testing.T) {
+	builds := []build.Artifact{
+		{ImageName: "gcr.io/dev/web", Tag: "gcr.io/dev/web:v1"},
+		{ImageName: "gcr.io/dev/app", Tag: "gcr.io/dev/app:v1"},
+	}
+
+	renamed, err := MapImageNames(builds, map[string]string{"web": "gcr.io/dev/web"})
+	testutil.CheckErrorAndDeepEqual(t, false, err, []build.Artifact{
+		{ImageName: "gcr.io/dev/app", Tag: "gcr.io/dev/app:v1"},
+		{ImageName: "web", Tag: "gcr.io/dev/web:v1"},
+	}, renamed)
+
+	_, err = MapImageNames(builds, map[string]string{"db": "gcr.io/dev/db"})
 	testutil.CheckError(t, true, err)
 }
+
+func TestGetImages(t *testing.T) {
+	var tests = []struct {
+		description string
+		manifest    string
+		expected    []string
+	}{
+		{
+			description: "pod",
+			manifest: `apiVersion: v1
+kind: Pod
+spec:
+  initContainers:
+  - image: init
+  containers:
+  - image: web
+  - image: sidecar
+  ephemeralContainers:
+  - image: debug
+`,
+			expected: []string{"debug", "init", "sidecar", "web"},
+		},
+		{
+			description: "deployment",
+			manifest: `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - image: web
+`,
+			expected: []string{"web"},
+		},
+		{
+			description: "statefulset",
+			manifest: `apiVersion: apps/v1
+kind: StatefulSet
+spec:
+  template:
+    spec:
+      containers:
+      - image: db
+`,
+			expected: []string{"db"},
+		},
+		{
+			description: "daemonset",
+			manifest: `apiVersion: apps/v1
+kind: DaemonSet
+spec:
+  template:
+    spec:
+      containers:
+      - image: agent
+`,
+			expected: []string{"agent"},
+		},
+		{
+			description: "replicaset",
+			manifest: `apiVersion: apps/v1
+kind: ReplicaSet
+spec:
+  template:
+    spec:
+      containers:
+      - image: web
+`,
+			expected: []string{"web"},
+		},
+		{
+			description: "job",
+			manifest: `apiVersion: batch/v1
+kind: Job
+spec:
+  template:
+    spec:
+      containers:
+      - image: migrate
+`,
+			expected: []string{"migrate"},
+		},
+		{
+			description: "cronjob",
+			manifest: `apiVersion: batch/v1beta1
+kind: CronJob
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - image: backup
+`,
+			expected: []string{"backup"},
+		},
+		{
+			description: "no pod spec",
+			manifest: `apiVersion: v1
+kind: Service
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			manifests := ManifestList{[]byte(test.manifest)}
+
+			testutil.CheckDeepEqual(t, test.expected, manifests.GetImages())
+		})
+	}
+}
+
+func TestGetImagesDeduplicates(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: web
+`), []byte(`apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: app
+  - image: web
+`), []byte("INVALID")}
+
+	testutil.CheckDeepEqual(t, []string{"app", "web"}, manifests.GetImages())
+}
+
+func TestReplaceImagesWithNamePrefix(t *testing.T) {
+	// Rendered by a kustomization with `namePrefix: dev-` and `nameSuffix: -v2`
+	manifests := ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: dev-web-v2
+spec:
+  template:
+    spec:
+      containers:
+      - image: gcr.io/k8s-skaffold/web
+        name: web
+`)}
+
+	expected := ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: dev-web-v2
+spec:
+  template:
+    spec:
+      containers:
+      - image: gcr.io/k8s-skaffold/web:v1
+        name: web
+`)}
+
+	defer func(w Warner) { warner = w }(warner)
+	fakeWarner := &fakeWarner{}
+	warner = fakeWarner
+
+	resultManifest, err := manifests.ReplaceImages([]build.Artifact{{ImageName: "gcr.io/k8s-skaffold/web", Tag: "gcr.io/k8s-skaffold/web:v1"}}, ReplaceOptions{})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
+	testutil.CheckDeepEqual(t, []string(nil), fakeWarner.warnings)
+}
+
+func TestUnqualifiedImages(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: getting-started
+spec:
+  containers:
+  - image: nginx
+    name: library
+  - image: skaffold/web:v1
+    name: hub-user
+  - image: gcr.io/k8s-skaffold/app:v1
+    name: qualified
+  - image: localhost:5000/app
+    name: local-registry
+`)}
+
+	testutil.CheckDeepEqual(t, []string{"nginx", "skaffold/web:v1"}, manifests.UnqualifiedImages())
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// configContent is the content of a ConfigMap or a Secret.
+type configContent struct {
+	Immutable  bool              `yaml:"immutable"`
+	Data       map[string]string `yaml:"data"`
+	BinaryData map[string]string `yaml:"binaryData"`
+	StringData map[string]string `yaml:"stringData"`
+}
+
+// data returns the content as the API server stores it: the stringData
+// of a Secret is base64 encoded into its data.
+func (c *configContent) data() (map[string]string, map[string]string) {
+	data := map[string]string{}
+	for k, v := range c.Data {
+		data[k] = v
+	}
+	for k, v := range c.StringData {
+		data[k] = base64.StdEncoding.EncodeToString([]byte(v))
+	}
+
+	binaryData := map[string]string{}
+	for k, v := range c.BinaryData {
+		binaryData[k] = v
+	}
+
+	return data, binaryData
+}
+
+// deleteChangedImmutable deletes the live ConfigMaps and Secrets that are
+// immutable and whose content differs from the manifests, since the apply
+// can't update them. The apply that follows recreates them.
+func (c *CLI) deleteChangedImmutable(ctx context.Context, out io.Writer, manifests ManifestList) error {
+	for _, manifest := range manifests {
+		r := resourceOf(manifest)
+		if r.Kind != "ConfigMap" && r.Kind != "Secret" {
+			continue
+		}
+
+		var desired configContent
+		if err := yaml.Unmarshal(manifest, &desired); err != nil {
+			return errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		name := strings.ToLower(r.Kind) + "/" + r.Name
+		buf, err := c.runOut(ctx, nil, r.Namespace, "get", nil, name, "--ignore-not-found=true", "-o", "yaml")
+		if err != nil {
+			return errors.Wrapf(err, "getting %s", name)
+		}
+
+		var live configContent
+		if err := yaml.Unmarshal(buf, &live); err != nil {
+			return errors.Wrapf(err, "reading live %s", name)
+		}
+		if !live.Immutable {
+			continue
+		}
+
+		desiredData, desiredBinaryData := desired.data()
+		liveData, liveBinaryData := live.data()
+		if reflect.DeepEqual(desiredData, liveData) && reflect.DeepEqual(desiredBinaryData, liveBinaryData) {
+			continue
+		}
+
+		color.Default.Fprintln(out, "Recreating", name+": it is immutable and its content changed")
+		if err := c.run(ctx, nil, out, r.Namespace, "delete", c.Flags.Delete, name, "--ignore-not-found=true"); err != nil {
+			return errors.Wrapf(err, "deleting %s", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const immutableConfigYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: ns
+immutable: true
+data:
+  mode: fast
+`
+
+const immutableSecretYAML = `apiVersion: v1
+kind: Secret
+metadata:
+  name: token
+  namespace: ns
+immutable: true
+stringData:
+  token: secret
+`
+
+func TestRecreateImmutable(t *testing.T) {
+	getConfig := "kubectl --context kubecontext --namespace ns get configmap/settings --ignore-not-found=true -o yaml"
+	getSecret := "kubectl --context kubecontext --namespace ns get secret/token --ignore-not-found=true -o yaml"
+	apply := "kubectl --context kubecontext apply -f -"
+
+	var tests = []struct {
+		description string
+		command     util.Command
+		expectedOut string
+	}{
+		{
+			description: "changed immutable config",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getConfig, "immutable: true\ndata:\n  mode: slow\n", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext --namespace ns delete configmap/settings --ignore-not-found=true", nil),
+				testutil.NewFakeCmdOut(getSecret, "immutable: true\ndata:\n  token: c2VjcmV0\n", nil),
+				testutil.NewFakeCmd(apply, nil),
+			),
+			expectedOut: "Recreating configmap/settings: it is immutable and its content changed\n",
+		},
+		{
+			description: "unchanged immutable resources",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getConfig, "immutable: true\ndata:\n  mode: fast\n", nil),
+				testutil.NewFakeCmdOut(getSecret, "immutable: true\ndata:\n  token: c2VjcmV0\n", nil),
+				testutil.NewFakeCmd(apply, nil),
+			),
+		},
+		{
+			description: "changed mutable config and new secret",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getConfig, "data:\n  mode: slow\n", nil),
+				testutil.NewFakeCmdOut(getSecret, "", nil),
+				testutil.NewFakeCmd(apply, nil),
+			),
+		},
+		{
+			description: "changed immutable secret",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getConfig, "immutable: true\ndata:\n  mode: fast\n", nil),
+				testutil.NewFakeCmdOut(getSecret, "immutable: true\ndata:\n  token: b2xk\n", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext --namespace ns delete secret/token --ignore-not-found=true", nil),
+				testutil.NewFakeCmd(apply, nil),
+			),
+			expectedOut: "Recreating secret/token: it is immutable and its content changed\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			var out bytes.Buffer
+			cli := &CLI{KubeContext: "kubecontext", RecreateImmutable: true}
+			_, err := cli.Apply(context.Background(), &out, ManifestList{[]byte(immutableConfigYAML), []byte(immutableSecretYAML), []byte(podYAML)})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expectedOut, out.String())
+		})
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"sort"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/constants"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SetLabels sets labels on every resource, merged with the labels it already
+// has. Resources annotated with `skaffold.dev/skip-labels: "true"` are not labelled.
+// The order of keys is preserved and manifests that already carry the same
+// labels are returned byte for byte.
+func (l *ManifestList) SetLabels(labels map[string]string) (ManifestList, error) {
+	var updated ManifestList
+
+	for _, manifest := range *l {
+		var m yaml.MapSlice
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		if len(m) == 0 || !setLabels(&m, labels) {
+			updated = append(updated, manifest)
+			continue
+		}
+
+		updatedManifest, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling yaml")
+		}
+
+		updated = append(updated, updatedManifest)
+	}
+
+	return updated, nil
+}
+
+func setLabels(m *yaml.MapSlice, labels map[string]string) bool {
+	value, _ := mapSliceValue(*m, "metadata")
+	metadata, _ := value.(yaml.MapSlice)
+
+	value, _ = mapSliceValue(metadata, "annotations")
+	annotations, _ := value.(yaml.MapSlice)
+	if skip, _ := mapSliceValue(annotations, constants.SkipLabelsAnnotation); skip == "true" {
+		return false
+	}
+
+	value, _ = mapSliceValue(metadata, "labels")
+	current, _ := value.(yaml.MapSlice)
+
+	// New labels are added in a stable order.
+	var keys []string
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changed := false
+	for _, key := range keys {
+		if v, _ := mapSliceValue(current, key); v == labels[key] {
+			continue
+		}
+		setMapSliceValue(&current, key, labels[key])
+		changed = true
+	}
+
+	if changed {
+		setMapSliceValue(&metadata, "labels", current)
+		setMapSliceValue(m, "metadata", metadata)
+	}
+	return changed
+}
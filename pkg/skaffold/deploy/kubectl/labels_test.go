@@ -0,0 +1,73 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestSetLabels(t *testing.T) {
+	labelled := `apiVersion: v1
+kind: Pod
+metadata:
+  labels:
+    app: web
+    skaffold-deployer: kustomize
+  name: web
+`
+	skipped := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  annotations:
+    skaffold.dev/skip-labels: "true"
+  name: managed
+`
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  labels:
+    app: web
+  name: web
+`), []byte(labelled), []byte(skipped), []byte(`kind: Service
+apiVersion: v1
+metadata:
+  name: web
+spec:
+  selector:
+    app: web
+    tier: frontend
+`)}
+
+	// The order of keys is kept and new labels are appended.
+	expected := ManifestList{[]byte(labelled), []byte(labelled), []byte(skipped), []byte(`kind: Service
+apiVersion: v1
+metadata:
+  name: web
+  labels:
+    skaffold-deployer: kustomize
+spec:
+  selector:
+    app: web
+    tier: frontend
+`)}
+
+	updated, err := manifests.SetLabels(map[string]string{"skaffold-deployer": "kustomize"})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), updated.String())
+}
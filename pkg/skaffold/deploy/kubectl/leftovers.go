@@ -0,0 +1,258 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DeleteLeftovers deletes the resources of the namespace that match the
+// selector but are not part of the given manifests, ie. resources left
+// by previous deploys of an older render.
+func (c *CLI) DeleteLeftovers(ctx context.Context, out io.Writer, selector string, manifests ManifestList) error {
+	if err := c.validatePrune(); err != nil {
+		return err
+	}
+
+	leftovers, err := c.labelledLeftovers(ctx, selector, manifests)
+	if err != nil {
+		return err
+	}
+
+	if c.PruneFieldManager != "" && len(leftovers) > 0 {
+		color.Default.Fprintln(out, "Considering resources left by previous deploys:", strings.Join(leftovers, ", "))
+		owned, err := c.managedBy(ctx, leftovers, c.PruneFieldManager)
+		if err != nil {
+			return err
+		}
+		isOwned := map[string]bool{}
+		for _, name := range owned {
+			isOwned[name] = true
+		}
+		for _, name := range leftovers {
+			if !isOwned[name] {
+				color.Default.Fprintln(out, "Not pruning", name+": not managed by field manager", c.PruneFieldManager)
+			}
+		}
+		leftovers = owned
+	}
+
+	if len(leftovers) == 0 {
+		color.Default.Fprintln(out, "No resources left by previous deploys")
+		return nil
+	}
+
+	if c.DryRun {
+		color.Default.Fprintln(out, "Would delete resources left by previous deploys:", strings.Join(leftovers, ", "))
+		return nil
+	}
+
+	color.Default.Fprintln(out, "Deleting resources left by previous deploys:", strings.Join(leftovers, ", "))
+	args, err := c.deleteArgs()
+	if err != nil {
+		return err
+	}
+	args = append(args, leftovers...)
+	if err := c.run(ctx, nil, out, c.Namespace, "delete", c.Flags.Delete, args...); err != nil {
+		return errors.Wrap(err, "kubectl delete")
+	}
+
+	return nil
+}
+
+// Leftovers lists, as `kind.group/name`, the resources of the namespace that
+// match the selector but are not part of the given manifests. With a
+// PruneFieldManager, only the resources managed by that field manager are listed.
+func (c *CLI) Leftovers(ctx context.Context, selector string, manifests ManifestList) ([]string, error) {
+	leftovers, err := c.labelledLeftovers(ctx, selector, manifests)
+	if err != nil || c.PruneFieldManager == "" || len(leftovers) == 0 {
+		return leftovers, err
+	}
+
+	return c.managedBy(ctx, leftovers, c.PruneFieldManager)
+}
+
+// labelledLeftovers lists the resources of the namespace that match the
+// selector but are not part of the given manifests, whoever manages them.
+// Resources annotated with NoPruneAnnotation are never listed.
+func (c *CLI) labelledLeftovers(ctx context.Context, selector string, manifests ManifestList) ([]string, error) {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing selector %s", selector)
+	}
+
+	resources, err := c.deletableResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := c.runOut(ctx, nil, c.Namespace, "get", nil, strings.Join(resources, ","), "-l", selector, "-o", "yaml")
+	if err != nil {
+		return nil, errors.Wrap(err, "listing labelled resources")
+	}
+
+	var list struct {
+		Items []struct {
+			APIVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+			Metadata   struct {
+				Namespace   string            `yaml:"namespace"`
+				Name        string            `yaml:"name"`
+				Labels      map[string]string `yaml:"labels"`
+				Annotations map[string]string `yaml:"annotations"`
+			} `yaml:"metadata"`
+		} `yaml:"items"`
+	}
+	if err := yaml.Unmarshal(buf, &list); err != nil {
+		return nil, errors.Wrap(err, "reading labelled resources")
+	}
+
+	// Manifests without a namespace are applied to the namespace of the deployer.
+	rendered := map[Resource]bool{}
+	for _, r := range manifests.Resources() {
+		namespace := r.Namespace
+		if namespace == "" {
+			namespace = c.Namespace
+		}
+		rendered[leftoverRef(r.Kind, namespace, r.Name)] = true
+	}
+
+	if c.HistoryConfigMap != "" {
+		rendered[leftoverRef("ConfigMap", c.Namespace, c.HistoryConfigMap)] = true
+	}
+
+	var leftovers []string
+	for _, item := range list.Items {
+		// The selector is checked again, in case the server ignored part of it.
+		if !parsed.Matches(labels.Set(item.Metadata.Labels)) {
+			continue
+		}
+
+		if rendered[leftoverRef(item.Kind, item.Metadata.Namespace, item.Metadata.Name)] {
+			continue
+		}
+		// Without a namespace, the deployer applies to the current namespace, which is the one listed.
+		if c.Namespace == "" && rendered[leftoverRef(item.Kind, "", item.Metadata.Name)] {
+			continue
+		}
+
+		name := objectName(item.APIVersion, item.Kind, item.Metadata.Name)
+		if item.Metadata.Annotations[NoPruneAnnotation] == "true" {
+			logrus.Infof("Not pruning %s: annotated with %s", name, NoPruneAnnotation)
+			continue
+		}
+
+		leftovers = append(leftovers, name)
+	}
+
+	return leftovers, nil
+}
+
+// leftoverRef identifies a resource of a namespace, whatever its apiVersion.
+func leftoverRef(kind, namespace, name string) Resource {
+	return Resource{Kind: strings.ToLower(kind), Namespace: namespace, Name: name}
+}
+
+// objectName formats a resource like `kubectl get -o name`: `kind.group/name`.
+func objectName(apiVersion, kind, name string) string {
+	gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
+	if gvk.Group == "" {
+		return strings.ToLower(kind) + "/" + name
+	}
+
+	return strings.ToLower(kind) + "." + gvk.Group + "/" + name
+}
+
+// validatePrune checks that the resources are applied with the field manager that prune looks for.
+func (c *CLI) validatePrune() error {
+	if c.PruneFieldManager != "" && !c.ServerSideApply {
+		return errors.New("pruning by field manager requires server-side apply")
+	}
+
+	return nil
+}
+
+// managedBy only keeps the resources, named `kind.group/name`, that have
+// the given field manager in their managed fields.
+func (c *CLI) managedBy(ctx context.Context, names []string, manager string) ([]string, error) {
+	args := append([]string{"--show-managed-fields", "-o", "yaml"}, names...)
+	buf, err := c.runOut(ctx, nil, c.Namespace, "get", nil, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting managed fields")
+	}
+
+	type object struct {
+		Kind     string `yaml:"kind"`
+		Metadata struct {
+			Name          string `yaml:"name"`
+			ManagedFields []struct {
+				Manager string `yaml:"manager"`
+			} `yaml:"managedFields"`
+		} `yaml:"metadata"`
+	}
+	var list struct {
+		object `yaml:",inline"`
+		Items  []object `yaml:"items"`
+	}
+	if err := yaml.Unmarshal(buf, &list); err != nil {
+		return nil, errors.Wrap(err, "reading managed fields")
+	}
+
+	// A single resource is not returned as a List.
+	objects := list.Items
+	if list.Kind != "List" {
+		objects = []object{list.object}
+	}
+
+	managed := map[string]bool{}
+	for _, o := range objects {
+		for _, f := range o.Metadata.ManagedFields {
+			if f.Manager == manager {
+				managed[strings.ToLower(o.Kind)+"/"+o.Metadata.Name] = true
+			}
+		}
+	}
+
+	var owned []string
+	for _, name := range names {
+		if managed[kindName(name)] {
+			owned = append(owned, name)
+		}
+	}
+
+	return owned, nil
+}
+
+// kindName turns the `kind.group/name` printed by `kubectl get -o name` into `kind/name`.
+func kindName(name string) string {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 {
+		return name
+	}
+
+	kind := strings.SplitN(parts[0], ".", 2)[0]
+	return kind + "/" + parts[1]
+}
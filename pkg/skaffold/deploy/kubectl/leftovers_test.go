@@ -0,0 +1,240 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const getLabelled = "kubectl --context kubecontext --namespace ns get pods,services,configmaps,deployments.apps -l skaffold-deployer=kustomize -o yaml"
+
+// labelledList is the output of `kubectl get -o yaml` for resources of the ns namespace
+// labelled by the kustomize deployer, given as `kind.group/name`.
+func labelledList(names ...string) string {
+	list := "apiVersion: v1\nkind: List\nitems:\n"
+	for _, name := range names {
+		parts := strings.SplitN(name, "/", 2)
+		kindGroup := strings.SplitN(parts[0], ".", 2)
+		apiVersion := "v1"
+		if len(kindGroup) == 2 {
+			apiVersion = kindGroup[1] + "/v1"
+		}
+		list += "- apiVersion: " + apiVersion + "\n  kind: " + strings.Title(kindGroup[0]) + "\n  metadata:\n    namespace: ns\n    name: " + parts[1] + "\n    labels:\n      skaffold-deployer: kustomize\n"
+	}
+	return list
+}
+
+// fakeDeletableResources caches the resources that the cluster of a kube context can delete.
+func fakeDeletableResources(kubeContext string, resources ...string) {
+	var rows [][]string
+	for _, r := range resources {
+		rows = append(rows, []string{r})
+	}
+
+	servedAPIResources.Lock()
+	defer servedAPIResources.Unlock()
+	servedAPIResources.byQuery[kubeContext+" --namespaced=true --verbs=delete,list -o name"] = rows
+}
+
+func TestDeleteLeftovers(t *testing.T) {
+	var tests = []struct {
+		description string
+		command     util.Command
+		expected    string
+	}{
+		{
+			description: "leftovers",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getLabelled, labelledList("pod/leeroy-web", "service/leeroy-web", "deployment.apps/old"), nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext --namespace ns delete --ignore-not-found=true service/leeroy-web deployment.apps/old", nil),
+			),
+			expected: "Deleting resources left by previous deploys: service/leeroy-web, deployment.apps/old\n",
+		},
+		{
+			description: "no leftovers",
+			command:     testutil.NewFakeCmdOut(getLabelled, labelledList("pod/leeroy-web"), nil),
+			expected:    "No resources left by previous deploys\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			fakeDeletableResources("kubecontext", "pods", "services", "configmaps", "deployments.apps")
+
+			var out bytes.Buffer
+			cli := &CLI{KubeContext: "kubecontext", Namespace: "ns"}
+			err := cli.DeleteLeftovers(context.Background(), &out, "skaffold-deployer=kustomize", ManifestList{[]byte(podYAML)})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, out.String())
+		})
+	}
+}
+
+func TestDeleteLeftoversManagedBy(t *testing.T) {
+	getManaged := "kubectl --context kubecontext --namespace ns get --show-managed-fields -o yaml service/leeroy-web deployment.apps/old"
+
+	var tests = []struct {
+		description string
+		command     util.Command
+		expected    string
+	}{
+		{
+			description: "only prune managed resources",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getLabelled, labelledList("pod/leeroy-web", "service/leeroy-web", "deployment.apps/old"), nil),
+				testutil.NewFakeCmdOut(getManaged, `apiVersion: v1
+kind: List
+items:
+- kind: Service
+  metadata:
+    name: leeroy-web
+    managedFields:
+    - manager: helm
+- kind: Deployment
+  metadata:
+    name: old
+    managedFields:
+    - manager: kube-controller-manager
+    - manager: skaffold
+`, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext --namespace ns delete --ignore-not-found=true deployment.apps/old", nil),
+			),
+			expected: "Considering resources left by previous deploys: service/leeroy-web, deployment.apps/old\n" +
+				"Not pruning service/leeroy-web: not managed by field manager skaffold\n" +
+				"Deleting resources left by previous deploys: deployment.apps/old\n",
+		},
+		{
+			description: "nothing managed",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getLabelled, labelledList("pod/leeroy-web", "service/leeroy-web", "deployment.apps/old"), nil),
+				testutil.NewFakeCmdOut(getManaged, "apiVersion: v1\nkind: List\nitems: []\n", nil),
+			),
+			expected: "Considering resources left by previous deploys: service/leeroy-web, deployment.apps/old\n" +
+				"Not pruning service/leeroy-web: not managed by field manager skaffold\n" +
+				"Not pruning deployment.apps/old: not managed by field manager skaffold\n" +
+				"No resources left by previous deploys\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			fakeDeletableResources("kubecontext", "pods", "services", "configmaps", "deployments.apps")
+
+			var out bytes.Buffer
+			cli := &CLI{KubeContext: "kubecontext", Namespace: "ns", ServerSideApply: true, PruneFieldManager: "skaffold"}
+			err := cli.DeleteLeftovers(context.Background(), &out, "skaffold-deployer=kustomize", ManifestList{[]byte(podYAML)})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, out.String())
+		})
+	}
+}
+
+func TestLeftoversManagedBySingleResource(t *testing.T) {
+	fakeDeletableResources("kubecontext", "pods", "services", "configmaps", "deployments.apps")
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut(getLabelled, labelledList("service/leeroy-web"), nil),
+		testutil.NewFakeCmdOut("kubectl --context kubecontext --namespace ns get --show-managed-fields -o yaml service/leeroy-web", `kind: Service
+metadata:
+  name: leeroy-web
+  managedFields:
+  - manager: skaffold
+`, nil),
+	)
+
+	cli := &CLI{KubeContext: "kubecontext", Namespace: "ns", ServerSideApply: true, PruneFieldManager: "skaffold"}
+	leftovers, err := cli.Leftovers(context.Background(), "skaffold-deployer=kustomize", nil)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"service/leeroy-web"}, leftovers)
+}
+
+func TestDeleteLeftoversRequiresServerSideApply(t *testing.T) {
+	cli := &CLI{KubeContext: "kubecontext", Namespace: "ns", PruneFieldManager: "skaffold"}
+	err := cli.DeleteLeftovers(context.Background(), &bytes.Buffer{}, "skaffold-deployer=kustomize", nil)
+
+	testutil.CheckError(t, true, err)
+}
+
+func TestLeftoversScopedToNamespaceAndSelector(t *testing.T) {
+	fakeDeletableResources("kubecontext", "pods", "services", "configmaps", "deployments.apps")
+
+	getScoped := "kubectl --context kubecontext --namespace ns get pods,services,configmaps,deployments.apps -l skaffold-deployer=kustomize,skaffold-kustomization=abc -o yaml"
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmdOut(getScoped, `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    namespace: ns
+    name: leeroy-web
+    labels: {skaffold-deployer: kustomize, skaffold-kustomization: abc}
+- apiVersion: v1
+  kind: Service
+  metadata:
+    namespace: ns
+    name: leeroy-app
+    labels: {skaffold-deployer: kustomize, skaffold-kustomization: abc}
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    namespace: ns
+    name: other-project
+    labels: {skaffold-deployer: kustomize, skaffold-kustomization: def}
+`, nil)
+
+	// leeroy-app is rendered, but in another namespace.
+	otherNamespace := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: leeroy-app\n  namespace: other\n")
+
+	cli := &CLI{KubeContext: "kubecontext", Namespace: "ns"}
+	leftovers, err := cli.Leftovers(context.Background(), "skaffold-deployer=kustomize,skaffold-kustomization=abc", ManifestList{[]byte(podYAML), otherNamespace})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"service/leeroy-app"}, leftovers)
+}
+
+func TestLeftoversNoPrune(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmdOut(getLabelled, labelledList("service/leeroy-web")+`- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    namespace: ns
+    name: keep
+    labels: {skaffold-deployer: kustomize}
+    annotations: {skaffold.dev/no-prune: "true"}
+`, nil)
+
+	fakeDeletableResources("kubecontext", "pods", "services", "configmaps", "deployments.apps")
+
+	cli := &CLI{KubeContext: "kubecontext", Namespace: "ns"}
+	leftovers, err := cli.Leftovers(context.Background(), "skaffold-deployer=kustomize", nil)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"service/leeroy-web"}, leftovers)
+}
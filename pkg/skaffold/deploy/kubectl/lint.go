@@ -0,0 +1,148 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Lint rules.
+const (
+	LintResourceLimits = "resource-limits"
+	LintLatestTag      = "latest-tag"
+	LintLivenessProbe  = "liveness-probe"
+	LintPrivileged     = "privileged"
+)
+
+var lintRules = []string{LintResourceLimits, LintLatestTag, LintLivenessProbe, LintPrivileged}
+
+// Lint looks for common anti-patterns in the containers of every manifest
+// and returns a warning for each one it finds. This is policy guidance,
+// not validation: manifests that can't be decoded are ignored.
+func (l *ManifestList) Lint(disabled []string) ([]string, error) {
+	enabled := map[string]bool{}
+	for _, rule := range lintRules {
+		enabled[rule] = true
+	}
+	for _, rule := range disabled {
+		if !enabled[rule] {
+			return nil, fmt.Errorf("unknown lint rule %q: should be one of %s", rule, strings.Join(lintRules, ", "))
+		}
+		enabled[rule] = false
+	}
+
+	var warnings []string
+	for _, manifest := range *l {
+		m := make(map[interface{}]interface{})
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			continue
+		}
+
+		r := resourceOf(manifest)
+		linter := &linter{
+			enabled:  enabled,
+			resource: strings.ToLower(r.Kind) + "/" + r.Name,
+		}
+		linter.visit(m)
+		sort.Strings(linter.warnings)
+		warnings = append(warnings, linter.warnings...)
+	}
+
+	return warnings, nil
+}
+
+type linter struct {
+	enabled  map[string]bool
+	resource string
+	warnings []string
+}
+
+func (l *linter) visit(value interface{}) {
+	switch t := value.(type) {
+	case []interface{}:
+		for _, v := range t {
+			l.visit(v)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range t {
+			switch k {
+			case "containers":
+				l.lintContainers(v, true)
+			case "initContainers":
+				l.lintContainers(v, false)
+			default:
+				l.visit(v)
+			}
+		}
+	}
+}
+
+func (l *linter) lintContainers(containers interface{}, longRunning bool) {
+	list, ok := containers.([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, c := range list {
+		container, ok := c.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		name, _ := container["name"].(string)
+		image, _ := container["image"].(string)
+
+		if l.enabled[LintResourceLimits] {
+			resources, _ := container["resources"].(map[interface{}]interface{})
+			if limits, _ := resources["limits"].(map[interface{}]interface{}); len(limits) == 0 {
+				l.warn(name, LintResourceLimits, "has no resource limits")
+			}
+		}
+		if l.enabled[LintLatestTag] && image != "" && usesLatestTag(image) {
+			l.warn(name, LintLatestTag, fmt.Sprintf("uses the latest tag of %s", image))
+		}
+		if l.enabled[LintLivenessProbe] && longRunning && container["livenessProbe"] == nil {
+			l.warn(name, LintLivenessProbe, "has no liveness probe")
+		}
+		if l.enabled[LintPrivileged] {
+			securityContext, _ := container["securityContext"].(map[interface{}]interface{})
+			if privileged, _ := securityContext["privileged"].(bool); privileged {
+				l.warn(name, LintPrivileged, "is privileged")
+			}
+		}
+	}
+}
+
+func (l *linter) warn(container, rule, message string) {
+	l.warnings = append(l.warnings, fmt.Sprintf("%s: container %s %s (%s)", l.resource, container, message, rule))
+}
+
+// usesLatestTag returns true for images that are neither pinned by digest
+// nor tagged with anything but `latest`.
+func usesLatestTag(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+
+	name := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(name, ":")
+	return i == -1 || name[i+1:] == "latest"
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestLint(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: gcr.io/project/web
+        securityContext:
+          privileged: true
+      - name: proxy
+        image: proxy:1.2@sha256:abcd
+        livenessProbe: {httpGet: {port: 80}}
+        resources: {limits: {cpu: 100m}}
+      initContainers:
+      - name: init
+        image: localhost:5000/init:latest
+        resources: {limits: {cpu: 100m}}
+`), []byte(serviceYAML), []byte("INVALID")}
+
+	var tests = []struct {
+		description string
+		disabled    []string
+		expected    []string
+		shouldErr   bool
+	}{
+		{
+			description: "all rules",
+			expected: []string{
+				"deployment/web: container init uses the latest tag of localhost:5000/init:latest (latest-tag)",
+				"deployment/web: container web has no liveness probe (liveness-probe)",
+				"deployment/web: container web has no resource limits (resource-limits)",
+				"deployment/web: container web is privileged (privileged)",
+				"deployment/web: container web uses the latest tag of gcr.io/project/web (latest-tag)",
+			},
+		},
+		{
+			description: "disabled rules",
+			disabled:    []string{LintLatestTag, LintLivenessProbe, LintResourceLimits},
+			expected: []string{
+				"deployment/web: container web is privileged (privileged)",
+			},
+		},
+		{
+			description: "unknown rule",
+			disabled:    []string{"unknown"},
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			warnings, err := manifests.Lint(test.disabled)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, warnings)
+		})
+	}
+}
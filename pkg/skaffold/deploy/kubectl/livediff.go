@@ -0,0 +1,201 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// LiveChanges sorts manifests by what an apply would do to the live resources.
+type LiveChanges struct {
+	// Added are the resources that don't exist yet.
+	Added ManifestList
+	// Changed are the resources that an apply would modify.
+	Changed ManifestList
+	// Unchanged are the resources that are up to date.
+	Unchanged ManifestList
+	// Skipped are the resources that an apply leaves alone: those annotated
+	// to be skipped and the create-only resources that already exist.
+	Skipped ManifestList
+}
+
+// LiveDiff compares the manifests to the live resources in the cluster,
+// with a single `kubectl get` and a single `kubectl diff`.
+// Nothing is changed in the cluster.
+func (c *CLI) LiveDiff(ctx context.Context, manifests ManifestList) (*LiveChanges, error) {
+	changes := &LiveChanges{}
+
+	var toApply ManifestList
+	for _, manifest := range manifests {
+		if skipped(manifest) {
+			changes.Skipped = append(changes.Skipped, manifest)
+			continue
+		}
+		toApply = append(toApply, manifest)
+	}
+	if len(toApply) == 0 {
+		return changes, nil
+	}
+
+	toApply, err := c.setDefaultNamespace(ctx, toApply)
+	if err != nil {
+		return nil, errors.Wrap(err, "setting default namespace")
+	}
+
+	createOnly := toApply.createOnlyResources()
+	toApply, _, err = toApply.stripSkaffoldAnnotations()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading skaffold annotations")
+	}
+
+	live, err := c.runOut(ctx, toApply.Reader(), "", "get", nil, "--ignore-not-found=true", "-o", "yaml", "-f", "-")
+	if err != nil {
+		return nil, errors.Wrap(err, "getting live resources")
+	}
+	liveResources, err := liveResources(live)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading live resources")
+	}
+
+	var existing ManifestList
+	for _, manifest := range toApply {
+		r := resourceOf(manifest)
+		switch {
+		case !containsResource(liveResources, r):
+			changes.Added = append(changes.Added, manifest)
+		case createOnly[r]:
+			changes.Skipped = append(changes.Skipped, manifest)
+		default:
+			existing = append(existing, manifest)
+		}
+	}
+	if len(existing) == 0 {
+		return changes, nil
+	}
+
+	// `kubectl diff` exits with 1 when there are differences.
+	diff, err := c.runOut(ctx, existing.Reader(), "", "diff", nil, "-f", "-")
+	hasDiff := len(bytes.TrimSpace(diff)) > 0
+	if err != nil && !hasDiff {
+		return nil, errors.Wrap(err, "diffing live resources")
+	}
+
+	diffed := diffedObjects(diff)
+	for _, manifest := range existing {
+		// Without the names of the compared objects, like with some
+		// external diff programs, every existing resource may have changed.
+		if hasDiff && (len(diffed) == 0 || diffedResource(diffed, resourceOf(manifest))) {
+			changes.Changed = append(changes.Changed, manifest)
+		} else {
+			changes.Unchanged = append(changes.Unchanged, manifest)
+		}
+	}
+
+	return changes, nil
+}
+
+// liveResources reads the resources printed by `kubectl get -o yaml`.
+func liveResources(buf []byte) ([]Resource, error) {
+	type object struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Namespace string `yaml:"namespace"`
+			Name      string `yaml:"name"`
+		} `yaml:"metadata"`
+	}
+	var list struct {
+		object `yaml:",inline"`
+		Items  []object `yaml:"items"`
+	}
+	if err := yaml.Unmarshal(buf, &list); err != nil {
+		return nil, err
+	}
+
+	// A single resource is not returned as a List.
+	objects := list.Items
+	if list.Kind != "List" && list.Kind != "" {
+		objects = []object{list.object}
+	}
+
+	var resources []Resource
+	for _, o := range objects {
+		resources = append(resources, Resource{APIVersion: o.APIVersion, Kind: o.Kind, Namespace: o.Metadata.Namespace, Name: o.Metadata.Name})
+	}
+	return resources, nil
+}
+
+// containsResource checks if a resource is in a list of live resources.
+// A resource without a namespace is in the default namespace of the kube
+// context, which only the live resources know.
+func containsResource(live []Resource, r Resource) bool {
+	for _, l := range live {
+		if groupKind(l) == groupKind(r) && l.Name == r.Name && (r.Namespace == "" || l.Namespace == r.Namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffedObjects lists the names of the files compared by `kubectl diff`.
+func diffedObjects(diff []byte) []string {
+	var names []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) > 1 && fields[0] == "diff":
+			names = append(names, path.Base(fields[len(fields)-1]))
+		case len(fields) > 1 && fields[0] == "+++":
+			names = append(names, path.Base(fields[1]))
+		}
+	}
+
+	return names
+}
+
+// diffedResource checks if `kubectl diff` compared a resource. kubectl names
+// the files `[group.]version.Kind.namespace.name`. Namespaces can't contain
+// dots, so a resource without a namespace matches any of them.
+func diffedResource(diffed []string, r Resource) bool {
+	gvk := r.GroupVersionKind()
+	prefix := gvk.Version + "." + gvk.Kind + "."
+	if gvk.Group != "" {
+		prefix = gvk.Group + "." + prefix
+	}
+	suffix := "." + r.Name
+
+	for _, name := range diffed {
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) || len(name) < len(prefix)+len(suffix) {
+			continue
+		}
+
+		namespace := name[len(prefix) : len(name)-len(suffix)]
+		if namespace == r.Namespace || (r.Namespace == "" && !strings.Contains(namespace, ".")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const liveDiff = "kubectl --context kubecontext diff -f -"
+
+func TestLiveDiff(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut(getLiveYAML, `apiVersion: v1
+kind: List
+items:
+- {apiVersion: v1, kind: Service, metadata: {namespace: default, name: leeroy-web}}
+- {apiVersion: v1, kind: ConfigMap, metadata: {namespace: default, name: config}}
+- {apiVersion: apps/v1, kind: Deployment, metadata: {namespace: default, name: web}}
+- {apiVersion: v1, kind: Secret, metadata: {namespace: default, name: creds}}
+`, nil),
+		testutil.NewFakeCmdOut(liveDiff, `diff -u -N /tmp/LIVE-1/v1.Service.default.leeroy-web /tmp/MERGED-2/v1.Service.default.leeroy-web
+--- /tmp/LIVE-1/v1.Service.default.leeroy-web	2018-10-14 10:00:00
++++ /tmp/MERGED-2/v1.Service.default.leeroy-web	2018-10-14 10:00:00
+-  port: 80
++  port: 8080
+diff -u -N /tmp/LIVE-1/v1.ConfigMap.default.my.config /tmp/MERGED-2/v1.ConfigMap.default.my.config
+`, fmt.Errorf("exit status 1")),
+	)
+
+	configMap := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n")
+	deployment := []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n")
+	skipped := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: manual\n  annotations:\n    skaffold.dev/skip: \"true\"\n")
+	createOnly := []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\n  annotations:\n    skaffold.dev/create-only: \"true\"\n")
+	manifests := ManifestList{[]byte(podYAML), []byte(serviceYAML), configMap, deployment, skipped, createOnly}
+
+	cli := &CLI{KubeContext: "kubecontext"}
+	changes, err := cli.LiveDiff(context.Background(), manifests)
+
+	testutil.CheckError(t, false, err)
+	testutil.CheckDeepEqual(t, []Resource{{APIVersion: "v1", Kind: "Pod", Namespace: "ns", Name: "leeroy-web"}}, changes.Added.Resources())
+	testutil.CheckDeepEqual(t, []Resource{{APIVersion: "v1", Kind: "Service", Name: "leeroy-web"}}, changes.Changed.Resources())
+	testutil.CheckDeepEqual(t, []Resource{{APIVersion: "v1", Kind: "ConfigMap", Name: "config"}, {APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}}, changes.Unchanged.Resources())
+	testutil.CheckDeepEqual(t, []Resource{{APIVersion: "v1", Kind: "ConfigMap", Name: "manual"}, {APIVersion: "v1", Kind: "Secret", Name: "creds"}}, changes.Skipped.Resources())
+}
+
+func TestLiveDiffSingleLiveResource(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut(getLiveYAML, "apiVersion: v1\nkind: Pod\nmetadata: {namespace: ns, name: leeroy-web}\n", nil),
+		testutil.NewFakeCmdOut(liveDiff, "", nil),
+	)
+
+	cli := &CLI{KubeContext: "kubecontext"}
+	changes, err := cli.LiveDiff(context.Background(), ManifestList{[]byte(podYAML)})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, ManifestList{[]byte(podYAML)}, changes.Unchanged)
+}
+
+func TestLiveDiffWithoutObjectNames(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut(getLiveYAML, `apiVersion: v1
+kind: List
+items:
+- {apiVersion: v1, kind: Pod, metadata: {namespace: ns, name: leeroy-web}}
+- {apiVersion: v1, kind: Service, metadata: {namespace: default, name: leeroy-web}}
+`, nil),
+		testutil.NewFakeCmdOut(liveDiff, "-  port: 80\n+  port: 8080\n", fmt.Errorf("exit status 1")),
+	)
+
+	cli := &CLI{KubeContext: "kubecontext"}
+	changes, err := cli.LiveDiff(context.Background(), ManifestList{[]byte(podYAML), []byte(serviceYAML)})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, ManifestList{[]byte(podYAML), []byte(serviceYAML)}, changes.Changed)
+}
+
+func TestLiveDiffError(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut(getLiveYAML, "apiVersion: v1\nkind: Pod\nmetadata: {namespace: ns, name: leeroy-web}\n", nil),
+		testutil.NewFakeCmdOut(liveDiff, "", fmt.Errorf("exit status 2")),
+	)
+
+	cli := &CLI{KubeContext: "kubecontext"}
+	_, err := cli.LiveDiff(context.Background(), ManifestList{[]byte(podYAML)})
+
+	testutil.CheckError(t, true, err)
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// for testing
+var (
+	lockRetryInterval = 2 * time.Second
+	lockNow           = time.Now
+)
+
+// lease is a Lease, that tells who holds the lock and until when.
+type lease struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name              string `yaml:"name"`
+		Namespace         string `yaml:"namespace,omitempty"`
+		ResourceVersion   string `yaml:"resourceVersion,omitempty"`
+		CreationTimestamp string `yaml:"creationTimestamp,omitempty"`
+	} `yaml:"metadata"`
+	Spec struct {
+		HolderIdentity       string `yaml:"holderIdentity"`
+		LeaseDurationSeconds int    `yaml:"leaseDurationSeconds,omitempty"`
+		AcquireTime          string `yaml:"acquireTime,omitempty"`
+		RenewTime            string `yaml:"renewTime,omitempty"`
+	} `yaml:"spec"`
+}
+
+// expired tells if the holder stopped renewing the lease for longer than its
+// duration. Leases without a duration or a renew time, created by older
+// versions, expire after the given duration since they were created.
+func (l *lease) expired(now time.Time, duration time.Duration) bool {
+	if l.Spec.LeaseDurationSeconds > 0 {
+		duration = time.Duration(l.Spec.LeaseDurationSeconds) * time.Second
+	}
+
+	renewed := l.Spec.RenewTime
+	if renewed == "" {
+		renewed = l.Metadata.CreationTimestamp
+	}
+	renewTime, err := time.Parse(time.RFC3339Nano, renewed)
+	if err != nil {
+		return false
+	}
+
+	return renewTime.Add(duration).Before(now)
+}
+
+// leaseManifest is a Lease held by holder. With a resourceVersion, the Lease
+// only replaces the version that was read.
+func leaseManifest(name, holder string, duration time.Duration, resourceVersion string) (io.Reader, error) {
+	now := lockNow().UTC().Format(microTime)
+
+	l := lease{APIVersion: "coordination.k8s.io/v1", Kind: "Lease"}
+	l.Metadata.Name = name
+	l.Metadata.ResourceVersion = resourceVersion
+	l.Spec.HolderIdentity = holder
+	l.Spec.LeaseDurationSeconds = int(duration.Seconds())
+	l.Spec.AcquireTime = now
+	l.Spec.RenewTime = now
+
+	buf, err := yaml.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(buf), nil
+}
+
+// microTime is the format of the times of a Lease.
+const microTime = "2006-01-02T15:04:05.000000Z07:00"
+
+// AcquireLock creates a Lease used as a lock by concurrent deploys to the same namespace.
+// The holder has to renew it with RenewLock before the duration expires. Otherwise,
+// the lock is taken over by the next deploy, which avoids a crashed deploy from
+// holding the lock forever. If the lock is held by someone else, it retries until
+// the timeout expires. A zero timeout fails right away.
+func (c *CLI) AcquireLock(ctx context.Context, name string, holder string, timeout time.Duration, duration time.Duration) error {
+	deadline := lockNow().Add(timeout)
+
+	for {
+		manifest, err := leaseManifest(name, holder, duration, "")
+		if err != nil {
+			return err
+		}
+		_, err = c.runOut(ctx, manifest, c.Namespace, "create", nil, "-f", "-")
+		if err == nil {
+			logrus.Debugf("Acquired deploy lock %s as %s", name, holder)
+			return nil
+		}
+
+		if !strings.Contains(err.Error(), "AlreadyExists") {
+			return errors.Wrap(err, "creating lease")
+		}
+
+		current, err := c.getLease(ctx, name)
+		if err == nil && current.expired(lockNow(), duration) {
+			logrus.Infof("Deploy lock %s held by %s has expired, taking it over", name, current.Spec.HolderIdentity)
+			// Replacing the version that was read fails if someone else took it over first.
+			takeOver, err := leaseManifest(name, holder, duration, current.Metadata.ResourceVersion)
+			if err != nil {
+				return err
+			}
+			if _, err := c.runOut(ctx, takeOver, c.Namespace, "replace", nil, "-f", "-"); err == nil {
+				logrus.Debugf("Acquired deploy lock %s as %s", name, holder)
+				return nil
+			}
+		}
+
+		holderIdentity := unknown
+		if current != nil {
+			holderIdentity = current.Spec.HolderIdentity
+		}
+
+		if lockNow().Add(lockRetryInterval).After(deadline) {
+			return fmt.Errorf("deploy lock %s is held by %s", name, holderIdentity)
+		}
+
+		logrus.Infof("Deploy lock %s is held by %s, waiting...", name, holderIdentity)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// RenewLock extends the Lease acquired by AcquireLock, as long as it is still held by holder.
+func (c *CLI) RenewLock(ctx context.Context, name string, holder string) error {
+	holderJSON, err := json.Marshal(holder)
+	if err != nil {
+		return err
+	}
+
+	patch := fmt.Sprintf(`[{"op":"test","path":"/spec/holderIdentity","value":%s},{"op":"replace","path":"/spec/renewTime","value":"%s"}]`, holderJSON, lockNow().UTC().Format(microTime))
+	if _, err := c.runOut(ctx, nil, c.Namespace, "patch", nil, "lease", name, "--type=json", "-p", patch); err != nil {
+		return errors.Wrap(err, "renewing lease")
+	}
+
+	return nil
+}
+
+// ReleaseLock deletes the Lease created by AcquireLock, unless it expired
+// and was taken over by someone else. The deletion is conditioned on the
+// version that was read, so that a concurrent take over is never deleted.
+func (c *CLI) ReleaseLock(ctx context.Context, name string, holder string) error {
+	current, err := c.getLease(ctx, name)
+	if err != nil {
+		if strings.Contains(err.Error(), "NotFound") {
+			return nil
+		}
+		return errors.Wrap(err, "getting lease")
+	}
+
+	if current.Spec.HolderIdentity != holder {
+		logrus.Warnf("Deploy lock %s was taken over by %s, not releasing it", name, current.Spec.HolderIdentity)
+		return nil
+	}
+
+	namespace := current.Metadata.Namespace
+	if namespace == "" {
+		namespace = c.Namespace
+	}
+	preconditions := fmt.Sprintf(`{"kind":"DeleteOptions","apiVersion":"v1","preconditions":{"resourceVersion":%q}}`, current.Metadata.ResourceVersion)
+	path := fmt.Sprintf("/apis/coordination.k8s.io/v1/namespaces/%s/leases/%s", namespace, name)
+
+	if _, err := c.runOut(ctx, strings.NewReader(preconditions), "", "delete", nil, "--raw", path, "-f", "-"); err != nil {
+		return errors.Wrap(err, "deleting lease")
+	}
+
+	return nil
+}
+
+func (c *CLI) getLease(ctx context.Context, name string) (*lease, error) {
+	buf, err := c.runOut(ctx, nil, c.Namespace, "get", nil, "lease", name, "-o", "yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var l lease
+	if err := yaml.Unmarshal(buf, &l); err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	createLease  = "kubectl --context kubecontext --namespace ns create -f -"
+	getLease     = "kubectl --context kubecontext --namespace ns get lease lock -o yaml"
+	replaceLease = "kubectl --context kubecontext --namespace ns replace -f -"
+)
+
+// heldLease is a Lease held by other-host-42, last renewed at the given time.
+func heldLease(renewTime string) string {
+	return `apiVersion: coordination.k8s.io/v1
+kind: Lease
+metadata:
+  name: lock
+  resourceVersion: "42"
+spec:
+  holderIdentity: other-host-42
+  leaseDurationSeconds: 60
+  renewTime: "` + renewTime + `"
+`
+}
+
+func TestAcquireLock(t *testing.T) {
+	alreadyExists := fmt.Errorf(`Error from server (AlreadyExists): leases.coordination.k8s.io "lock" already exists`)
+
+	var tests = []struct {
+		description string
+		timeout     time.Duration
+		command     util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "acquire",
+			command:     testutil.NewFakeCmdOut(createLease, "", nil),
+		},
+		{
+			description: "fail fast",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(createLease, "", alreadyExists),
+				testutil.NewFakeCmdOut(getLease, heldLease("2018-09-01T00:00:30.000000Z"), nil),
+			),
+			shouldErr: true,
+		},
+		{
+			description: "take over an expired lease",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(createLease, "", alreadyExists),
+				testutil.NewFakeCmdOut(getLease, heldLease("2018-08-31T23:58:00.000000Z"), nil),
+				testutil.NewFakeCmdOut(replaceLease, "", nil),
+			),
+		},
+		{
+			description: "someone else took over the expired lease first",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(createLease, "", alreadyExists),
+				testutil.NewFakeCmdOut(getLease, heldLease("2018-08-31T23:58:00.000000Z"), nil),
+				testutil.NewFakeCmdOut(replaceLease, "", fmt.Errorf("Error from server (Conflict): the object has been modified")),
+			),
+			shouldErr: true,
+		},
+		{
+			description: "wait",
+			timeout:     time.Second,
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(createLease, "", alreadyExists),
+				testutil.NewFakeCmdOut(getLease, heldLease("2018-09-01T00:00:30.000000Z"), nil),
+				testutil.NewFakeCmdOut(createLease, "", nil),
+			),
+		},
+		{
+			description: "other error",
+			command:     testutil.NewFakeCmdOut(createLease, "", fmt.Errorf("forbidden")),
+			shouldErr:   true,
+		},
+	}
+
+	defer func(d time.Duration) { lockRetryInterval = d }(lockRetryInterval)
+	lockRetryInterval = time.Millisecond
+	defer func(now func() time.Time) { lockNow = now }(lockNow)
+	lockNow = fixedNow
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			cli := &CLI{KubeContext: "kubecontext", Namespace: "ns"}
+			err := cli.AcquireLock(context.Background(), "lock", "host-1", test.timeout, time.Minute)
+
+			testutil.CheckError(t, test.shouldErr, err)
+		})
+	}
+}
+
+func TestReleaseLock(t *testing.T) {
+	deleteLease := "kubectl --context kubecontext delete --raw /apis/coordination.k8s.io/v1/namespaces/ns/leases/lock -f -"
+
+	var tests = []struct {
+		description string
+		command     util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "release",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getLease, "metadata:\n  name: lock\n  namespace: ns\n  resourceVersion: \"43\"\nspec:\n  holderIdentity: host-1\n", nil),
+				testutil.NewFakeCmdOut(deleteLease, "", nil),
+			),
+		},
+		{
+			description: "already released",
+			command:     testutil.NewFakeCmdOut(getLease, "", fmt.Errorf(`Error from server (NotFound): leases.coordination.k8s.io "lock" not found`)),
+		},
+		{
+			description: "don't release a lease that was taken over",
+			command:     testutil.NewFakeCmdOut(getLease, heldLease("2018-09-01T00:00:30.000000Z"), nil),
+		},
+		{
+			description: "lease taken over while releasing",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getLease, "metadata:\n  name: lock\n  resourceVersion: \"43\"\nspec:\n  holderIdentity: host-1\n", nil),
+				testutil.NewFakeCmdOut(deleteLease, "", fmt.Errorf("Error from server (Conflict): the object has been modified")),
+			),
+			shouldErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			cli := &CLI{KubeContext: "kubecontext", Namespace: "ns"}
+			err := cli.ReleaseLock(context.Background(), "lock", "host-1")
+
+			testutil.CheckError(t, test.shouldErr, err)
+		})
+	}
+}
+
+func TestRenewLock(t *testing.T) {
+	defer func(now func() time.Time) { lockNow = now }(lockNow)
+	lockNow = fixedNow
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmdOut(`kubectl --context kubecontext --namespace ns patch lease lock --type=json -p [{"op":"test","path":"/spec/holderIdentity","value":"host-1"},{"op":"replace","path":"/spec/renewTime","value":"2018-09-01T00:01:00.000000Z"}]`, "", nil)
+
+	cli := &CLI{KubeContext: "kubecontext", Namespace: "ns"}
+	err := cli.RenewLock(context.Background(), "lock", "host-1")
+
+	testutil.CheckError(t, false, err)
+}
+
+func TestLeaseExpired(t *testing.T) {
+	var tests = []struct {
+		description string
+		lease       string
+		expected    bool
+	}{
+		{
+			description: "renewed",
+			lease:       heldLease("2018-09-01T00:00:30.000000Z"),
+		},
+		{
+			description: "not renewed for longer than its duration",
+			lease:       heldLease("2018-08-31T23:58:00.000000Z"),
+			expected:    true,
+		},
+		{
+			description: "created by an older version",
+			lease:       "metadata:\n  creationTimestamp: 2018-08-31T23:58:00Z\nspec:\n  holderIdentity: other-host-42\n",
+			expected:    true,
+		},
+		{
+			description: "unknown renew time",
+			lease:       "spec:\n  holderIdentity: other-host-42\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var l lease
+			err := yaml.Unmarshal([]byte(test.lease), &l)
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, l.expired(fixedNow(), time.Minute))
+		})
+	}
+}
+
+func fixedNow() time.Time {
+	return time.Date(2018, 9, 1, 0, 1, 0, 0, time.UTC)
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ImageLock pins image names to digests.
+type ImageLock struct {
+	// Digests maps image names to digests, eg. `sha256:...`.
+	Digests map[string]string
+
+	// Strict fails the render if a pinned image is not used by the manifests.
+	Strict bool
+}
+
+// ReadImageLock reads a lockfile that maps image names to digests:
+//   gcr.io/k8s-skaffold/leeroy-web: sha256:...
+func ReadImageLock(path string) (*ImageLock, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading image lockfile")
+	}
+
+	digests := make(map[string]string)
+	if err := yaml.Unmarshal(buf, &digests); err != nil {
+		return nil, errors.Wrap(err, "parsing image lockfile")
+	}
+
+	for imageName, digest := range digests {
+		if !strings.Contains(digest, ":") {
+			return nil, errors.Errorf("invalid digest %q for image %s", digest, imageName)
+		}
+	}
+
+	return &ImageLock{
+		Digests: digests,
+	}, nil
+}
+
+// tags returns the pinned references, by image name.
+func (l *ImageLock) tags() map[string]string {
+	tags := make(map[string]string)
+	for imageName, digest := range l.Digests {
+		tags[imageName] = imageName + "@" + digest
+	}
+	return tags
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const digest = "sha256:81daf011d63b68cfa514ddab7741a1adddd59d3264118dfb0fd9266328bb8883"
+
+func TestReadImageLock(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+	tmpDir.Write("images.lock", "gcr.io/k8s-skaffold/web: "+digest)
+	tmpDir.Write("invalid.lock", "gcr.io/k8s-skaffold/web: v1")
+
+	lock, err := ReadImageLock(tmpDir.Path("images.lock"))
+	testutil.CheckErrorAndDeepEqual(t, false, err, map[string]string{"gcr.io/k8s-skaffold/web": digest}, lock.Digests)
+
+	_, err = ReadImageLock(tmpDir.Path("invalid.lock"))
+	testutil.CheckError(t, true, err)
+
+	_, err = ReadImageLock(tmpDir.Path("missing.lock"))
+	testutil.CheckError(t, true, err)
+}
+
+func TestReplaceLockedImages(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: getting-started
+spec:
+  containers:
+  - image: gcr.io/k8s-skaffold/web
+    name: locked
+  - image: gcr.io/k8s-skaffold/app
+    name: built
+`)}
+
+	builds := []build.Artifact{
+		{ImageName: "gcr.io/k8s-skaffold/web", Tag: "gcr.io/k8s-skaffold/web:built"},
+		{ImageName: "gcr.io/k8s-skaffold/app", Tag: "gcr.io/k8s-skaffold/app:built"},
+	}
+
+	expected := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: getting-started
+spec:
+  containers:
+  - image: gcr.io/k8s-skaffold/web@` + digest + `
+    name: locked
+  - image: gcr.io/k8s-skaffold/app:built
+    name: built
+`)}
+
+	defer func(w Warner) { warner = w }(warner)
+	warner = &fakeWarner{}
+
+	lock := &ImageLock{Digests: map[string]string{"gcr.io/k8s-skaffold/web": digest}, Strict: true}
+	resultManifest, err := manifests.ReplaceImages(builds, ReplaceOptions{Lock: lock})
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
+
+	lock.Digests["gcr.io/k8s-skaffold/unused"] = digest
+	_, err = manifests.ReplaceImages(builds, ReplaceOptions{Lock: lock})
+	testutil.CheckError(t, true, err)
+
+	lock.Strict = false
+	_, err = manifests.ReplaceImages(builds, ReplaceOptions{Lock: lock})
+	testutil.CheckError(t, false, err)
+}
@@ -36,12 +36,50 @@ func (l *ManifestList) String() string {
 	return str
 }
 
+// utf8BOM is the byte order mark that some Windows editors write.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
 // Append appends the yaml manifests defined in the given buffer.
+// Documents are split on `---` markers only: at the start of a line and
+// followed by a blank or the end of the line. Lines that merely start with
+// `---`, in a multi-line string for example, are part of the document.
+// A leading UTF-8 BOM is removed and CRLF line endings are turned into LF.
+// Other bytes are kept as is.
 func (l *ManifestList) Append(buf []byte) {
-	parts := bytes.Split(buf, []byte("\n---"))
-	for _, part := range parts {
-		*l = append(*l, part)
+	buf = normalizeEncoding(buf)
+	separator := []byte("\n---")
+
+	start := 0
+	for offset := 0; ; {
+		i := bytes.Index(buf[offset:], separator)
+		if i < 0 {
+			break
+		}
+		end := offset + i
+		offset = end + len(separator)
+
+		if offset < len(buf) && !isBlank(buf[offset]) {
+			continue
+		}
+
+		*l = append(*l, buf[start:end])
+		start = offset
 	}
+
+	*l = append(*l, buf[start:])
+}
+
+func normalizeEncoding(buf []byte) []byte {
+	buf = bytes.TrimPrefix(buf, utf8BOM)
+	if bytes.Contains(buf, []byte("\r\n")) {
+		buf = bytes.Replace(buf, []byte("\r\n"), []byte("\n"), -1)
+	}
+
+	return buf
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
 }
 
 // Diff computes the list of manifests that have changed.
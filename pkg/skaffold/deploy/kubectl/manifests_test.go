@@ -0,0 +1,136 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const anchorsYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: anchors
+  labels: &labels
+    app: web
+data:
+  labels: *labels`
+
+const blockScalarYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: scripts
+data:
+  script: |
+    echo start
+    ---
+    --- not a separator
+    echo end`
+
+const quotedYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: quoted
+data:
+  note: "first line
+---second line"`
+
+func TestAppend(t *testing.T) {
+	var tests = []struct {
+		description string
+		yaml        string
+		expected    ManifestList
+	}{
+		{
+			description: "single document",
+			yaml:        anchorsYAML,
+			expected:    ManifestList{[]byte(anchorsYAML)},
+		},
+		{
+			description: "separators",
+			yaml:        anchorsYAML + "\n---\n" + blockScalarYAML + "\n--- # comment\n" + quotedYAML,
+			expected: ManifestList{
+				[]byte(anchorsYAML),
+				[]byte("\n" + blockScalarYAML),
+				[]byte(" # comment\n" + quotedYAML),
+			},
+		},
+		{
+			description: "separator at the end",
+			yaml:        quotedYAML + "\n---",
+			expected:    ManifestList{[]byte(quotedYAML), []byte("")},
+		},
+		{
+			description: "separator with windows line ending",
+			yaml:        quotedYAML + "\n---\r\n" + anchorsYAML,
+			expected:    ManifestList{[]byte(quotedYAML), []byte("\n" + anchorsYAML)},
+		},
+		{
+			description: "windows line endings",
+			yaml:        strings.Replace(anchorsYAML+"\n---\n"+blockScalarYAML, "\n", "\r\n", -1),
+			expected:    ManifestList{[]byte(anchorsYAML), []byte("\n" + blockScalarYAML)},
+		},
+		{
+			description: "byte order mark",
+			yaml:        "\xef\xbb\xbf" + anchorsYAML + "\n---\n" + quotedYAML,
+			expected:    ManifestList{[]byte(anchorsYAML), []byte("\n" + quotedYAML)},
+		},
+		{
+			description: "byte order mark and windows line endings",
+			yaml:        "\xef\xbb\xbf" + strings.Replace(quotedYAML, "\n", "\r\n", -1),
+			expected:    ManifestList{[]byte(quotedYAML)},
+		},
+		{
+			description: "lone carriage returns are kept",
+			yaml:        "data: \"a\rb\"",
+			expected:    ManifestList{[]byte("data: \"a\rb\"")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var manifests ManifestList
+			manifests.Append([]byte(test.yaml))
+
+			testutil.CheckDeepEqual(t, test.expected, manifests)
+		})
+	}
+}
+
+func TestAppendRoundTrip(t *testing.T) {
+	var manifests ManifestList
+	manifests.Append([]byte(anchorsYAML + "\n---\n" + blockScalarYAML + "\n---\n" + quotedYAML))
+
+	testutil.CheckDeepEqual(t, anchorsYAML+"\n---\n"+blockScalarYAML+"\n---\n"+quotedYAML, manifests.String())
+	testutil.CheckDeepEqual(t, []Resource{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "anchors"},
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "scripts"},
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "quoted"},
+	}, manifests.Resources())
+}
+
+func TestAppendWindowsManifestApplies(t *testing.T) {
+	var manifests ManifestList
+	manifests.Append([]byte("\xef\xbb\xbfapiVersion: v1\r\nkind: Pod\r\nmetadata:\r\n  name: leeroy-web\r\n---\r\napiVersion: v1\r\nkind: Service\r\nmetadata:\r\n  name: leeroy-web\r\n"))
+
+	testutil.CheckDeepEqual(t, []Resource{
+		{APIVersion: "v1", Kind: "Pod", Name: "leeroy-web"},
+		{APIVersion: "v1", Kind: "Service", Name: "leeroy-web"},
+	}, manifests.Resources())
+}
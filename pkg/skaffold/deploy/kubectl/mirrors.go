@@ -0,0 +1,156 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+)
+
+// RegistryMirrors rewrites image references between their canonical
+// registry and a mirror of that registry.
+type RegistryMirrors struct {
+	prefixes       []string
+	mirrors        map[string]string
+	mirrorPrefixes []string
+	canonicals     map[string]string
+	writeCanonical bool
+}
+
+// NewRegistryMirrors parses the rewrites, from canonical prefixes to mirror
+// prefixes, and the form that is written to the manifests: `mirror` or `canonical`.
+func NewRegistryMirrors(rewrites map[string]string, write string) (*RegistryMirrors, error) {
+	m := &RegistryMirrors{
+		mirrors:    make(map[string]string),
+		canonicals: make(map[string]string),
+	}
+
+	switch write {
+	case "", "mirror":
+	case "canonical":
+		m.writeCanonical = true
+	default:
+		return nil, errors.Errorf("invalid registry mirrors write mode %q, must be mirror or canonical", write)
+	}
+
+	for canonical, mirror := range rewrites {
+		canonical = strings.TrimSuffix(canonical, "/")
+		mirror = strings.TrimSuffix(mirror, "/")
+		if canonical == "" || mirror == "" {
+			return nil, errors.Errorf("invalid registry mirror %q: %q", canonical, mirror)
+		}
+
+		m.prefixes = append(m.prefixes, canonical)
+		m.mirrors[canonical] = mirror
+		m.mirrorPrefixes = append(m.mirrorPrefixes, mirror)
+		m.canonicals[mirror] = canonical
+	}
+
+	sortLongestFirst(m.prefixes)
+	sortLongestFirst(m.mirrorPrefixes)
+
+	return m, nil
+}
+
+// sortLongestFirst sorts prefixes so that the most specific rewrite wins.
+func sortLongestFirst(prefixes []string) {
+	sort.Slice(prefixes, func(i, j int) bool {
+		if len(prefixes[i]) != len(prefixes[j]) {
+			return len(prefixes[i]) > len(prefixes[j])
+		}
+		return prefixes[i] < prefixes[j]
+	})
+}
+
+// Canonical returns the name of an image on its canonical registry.
+func (m *RegistryMirrors) Canonical(image string) string {
+	if m == nil {
+		return image
+	}
+
+	for _, mirror := range m.mirrorPrefixes {
+		if rest, ok := trimRegistryPrefix(image, mirror); ok {
+			return m.canonicals[mirror] + rest
+		}
+	}
+
+	return image
+}
+
+// Mirrored returns the name of an image on its mirror. Images of registries
+// that are not mirrored are returned as is.
+func (m *RegistryMirrors) Mirrored(image string) string {
+	if m == nil {
+		return image
+	}
+
+	image = m.Canonical(image)
+	for _, canonical := range m.prefixes {
+		if rest, ok := trimRegistryPrefix(image, canonical); ok {
+			return m.mirrors[canonical] + rest
+		}
+	}
+
+	return image
+}
+
+// Written returns the name of an image as it should be written to the manifests.
+func (m *RegistryMirrors) Written(image string) string {
+	if m == nil {
+		return image
+	}
+
+	if m.writeCanonical {
+		return m.Canonical(image)
+	}
+	return m.Mirrored(image)
+}
+
+// WrittenBuilds returns the artifacts with their tags as written to the manifests.
+func (m *RegistryMirrors) WrittenBuilds(builds []build.Artifact) []build.Artifact {
+	if m == nil {
+		return builds
+	}
+
+	var written []build.Artifact
+	for _, b := range builds {
+		b.Tag = m.Written(b.Tag)
+		written = append(written, b)
+	}
+
+	return written
+}
+
+// trimRegistryPrefix removes a prefix from an image name, only
+// on a path boundary: `docker.io` is a prefix of `docker.io/nginx`
+// but not of `docker.iox/nginx`.
+func trimRegistryPrefix(image, prefix string) (string, bool) {
+	if !strings.HasPrefix(image, prefix) {
+		return "", false
+	}
+
+	rest := image[len(prefix):]
+	if rest != "" && rest[0] != '/' {
+		return "", false
+	}
+
+	return rest, true
+}
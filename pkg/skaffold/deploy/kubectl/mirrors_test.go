@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestRegistryMirrors(t *testing.T) {
+	mirrors, err := NewRegistryMirrors(map[string]string{
+		"docker.io":         "mirror.internal/docker.io",
+		"docker.io/library": "mirror.internal/hub",
+	}, "")
+	testutil.CheckError(t, false, err)
+
+	var tests = []struct {
+		description string
+		image       string
+		canonical   string
+		mirrored    string
+	}{
+		{
+			description: "canonical image",
+			image:       "docker.io/skaffold/web:v1",
+			canonical:   "docker.io/skaffold/web:v1",
+			mirrored:    "mirror.internal/docker.io/skaffold/web:v1",
+		},
+		{
+			description: "mirrored image",
+			image:       "mirror.internal/docker.io/skaffold/web:v1",
+			canonical:   "docker.io/skaffold/web:v1",
+			mirrored:    "mirror.internal/docker.io/skaffold/web:v1",
+		},
+		{
+			description: "most specific rewrite",
+			image:       "docker.io/library/nginx",
+			canonical:   "docker.io/library/nginx",
+			mirrored:    "mirror.internal/hub/nginx",
+		},
+		{
+			description: "prefix on a path boundary only",
+			image:       "docker.iox/web",
+			canonical:   "docker.iox/web",
+			mirrored:    "docker.iox/web",
+		},
+		{
+			description: "registry not mirrored",
+			image:       "gcr.io/k8s-skaffold/web",
+			canonical:   "gcr.io/k8s-skaffold/web",
+			mirrored:    "gcr.io/k8s-skaffold/web",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			testutil.CheckDeepEqual(t, test.canonical, mirrors.Canonical(test.image))
+			testutil.CheckDeepEqual(t, test.mirrored, mirrors.Mirrored(test.image))
+		})
+	}
+}
+
+func TestNewRegistryMirrorsInvalid(t *testing.T) {
+	_, err := NewRegistryMirrors(map[string]string{"docker.io": "mirror.internal"}, "both")
+	testutil.CheckError(t, true, err)
+
+	_, err = NewRegistryMirrors(map[string]string{"docker.io": ""}, "mirror")
+	testutil.CheckError(t, true, err)
+}
+
+func TestReplaceMirroredImages(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: getting-started
+spec:
+  containers:
+  - image: docker.io/skaffold/web
+    name: canonical
+  - image: mirror.internal/docker.io/skaffold/app
+    name: mirrored
+  - image: docker.io/library/redis
+    name: not-built
+`)}
+
+	builds := []build.Artifact{
+		{ImageName: "docker.io/skaffold/web", Tag: "docker.io/skaffold/web:built"},
+		{ImageName: "docker.io/skaffold/app", Tag: "docker.io/skaffold/app:built"},
+	}
+
+	var tests = []struct {
+		description string
+		write       string
+		expected    ManifestList
+	}{
+		{
+			description: "write mirrored names",
+			write:       "mirror",
+			expected: ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: getting-started
+spec:
+  containers:
+  - image: mirror.internal/docker.io/skaffold/web:built
+    name: canonical
+  - image: mirror.internal/docker.io/skaffold/app:built
+    name: mirrored
+  - image: mirror.internal/docker.io/library/redis
+    name: not-built
+`)},
+		},
+		{
+			description: "write canonical names",
+			write:       "canonical",
+			expected: ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: getting-started
+spec:
+  containers:
+  - image: docker.io/skaffold/web:built
+    name: canonical
+  - image: docker.io/skaffold/app:built
+    name: mirrored
+  - image: docker.io/library/redis
+    name: not-built
+`)},
+		},
+	}
+
+	defer func(w Warner) { warner = w }(warner)
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			fakeWarner := &fakeWarner{}
+			warner = fakeWarner
+
+			mirrors, err := NewRegistryMirrors(map[string]string{"docker.io": "mirror.internal/docker.io"}, test.write)
+			testutil.CheckError(t, false, err)
+
+			resultManifest, err := manifests.ReplaceImages(builds, ReplaceOptions{Mirrors: mirrors})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected.String(), resultManifest.String())
+			testutil.CheckDeepEqual(t, []string(nil), fakeWarner.warnings)
+		})
+	}
+}
+
+func TestWrittenBuilds(t *testing.T) {
+	mirrors, err := NewRegistryMirrors(map[string]string{"docker.io": "mirror.internal/docker.io"}, "mirror")
+	testutil.CheckError(t, false, err)
+
+	builds := mirrors.WrittenBuilds([]build.Artifact{{ImageName: "docker.io/skaffold/web", Tag: "docker.io/skaffold/web:built"}})
+
+	testutil.CheckDeepEqual(t, []build.Artifact{{ImageName: "docker.io/skaffold/web", Tag: "mirror.internal/docker.io/skaffold/web:built"}}, builds)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SetDefaultNamespace sets the namespace of every namespaced resource
+// that doesn't already declare one. Resources whose kind is in clusterScoped
+// are left untouched, as are the manifests that already declare a namespace
+// which are returned byte for byte.
+func (l *ManifestList) SetDefaultNamespace(namespace string, clusterScoped map[string]bool) (ManifestList, error) {
+	var updated ManifestList
+
+	for _, manifest := range *l {
+		var m yaml.MapSlice
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		if len(m) == 0 || !setDefaultNamespace(&m, namespace, clusterScoped) {
+			updated = append(updated, manifest)
+			continue
+		}
+
+		updatedManifest, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling yaml")
+		}
+
+		updated = append(updated, updatedManifest)
+	}
+
+	return updated, nil
+}
+
+func setDefaultNamespace(m *yaml.MapSlice, namespace string, clusterScoped map[string]bool) bool {
+	if kind, ok := mapSliceValue(*m, "kind"); ok && clusterScoped[fmt.Sprint(kind)] {
+		return false
+	}
+
+	value, _ := mapSliceValue(*m, "metadata")
+	metadata, _ := value.(yaml.MapSlice)
+
+	if ns, ok := mapSliceValue(metadata, "namespace"); ok && ns != nil && ns != "" {
+		return false
+	}
+
+	setMapSliceValue(&metadata, "namespace", namespace)
+	setMapSliceValue(m, "metadata", metadata)
+	return true
+}
+
+// withoutNamespaces removes the Namespace objects, which cleanup preserves.
+func (l *ManifestList) withoutNamespaces(out io.Writer) ManifestList {
+	var preserved []string
+	others := l.Filter(func(r Resource) bool {
+		if r.Kind == "Namespace" {
+			preserved = append(preserved, r.Name)
+			return false
+		}
+		return true
+	})
+
+	if len(preserved) > 0 {
+		color.Default.Fprintln(out, "Preserving namespaces", strings.Join(preserved, ", ")+": they are not deleted on cleanup")
+	}
+
+	return others
+}
+
+// EnsureNamespace creates a namespace, unless it already exists.
+func (c *CLI) EnsureNamespace(ctx context.Context, out io.Writer, namespace string) error {
+	existing, err := c.runOut(ctx, nil, "", "get", nil, "namespace/"+namespace, "--ignore-not-found=true", "-o", "name")
+	if err != nil {
+		return errors.Wrapf(err, "getting namespace %s", namespace)
+	}
+	if len(bytes.TrimSpace(existing)) > 0 {
+		return nil
+	}
+
+	color.Default.Fprintln(out, "Creating namespace", namespace)
+	if err := c.run(ctx, nil, out, "", "create", nil, "namespace", namespace); err != nil {
+		return errors.Wrapf(err, "creating namespace %s", namespace)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestSetDefaultNamespace(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: undeclared
+spec:
+  containers:
+  - image: example
+    name: example
+`), []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: declared
+  namespace: other
+`), []byte(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: cluster-scoped
+`), []byte(`apiVersion: node.k8s.io/v1beta1
+kind: RuntimeClass
+metadata:
+  name: cluster-scoped
+`), []byte(`metadata:
+  name: ordered
+  labels:
+    app: ordered
+kind: ConfigMap
+apiVersion: v1
+`), []byte(`apiVersion: v1
+kind: ConfigMap
+`)}
+
+	expected := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: undeclared
+  namespace: default-ns
+spec:
+  containers:
+  - image: example
+    name: example
+`), []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: declared
+  namespace: other
+`), []byte(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: cluster-scoped
+`), []byte(`apiVersion: node.k8s.io/v1beta1
+kind: RuntimeClass
+metadata:
+  name: cluster-scoped
+`), []byte(`metadata:
+  name: ordered
+  labels:
+    app: ordered
+  namespace: default-ns
+kind: ConfigMap
+apiVersion: v1
+`), []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  namespace: default-ns
+`)}
+
+	resultManifest, err := manifests.SetDefaultNamespace("default-ns", map[string]bool{"Namespace": true, "RuntimeClass": true})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
+}
+
+func TestSetDefaultNamespaceInvalidManifest(t *testing.T) {
+	manifests := ManifestList{[]byte("INVALID")}
+
+	_, err := manifests.SetDefaultNamespace("default-ns", nil)
+
+	testutil.CheckError(t, true, err)
+}
+
+func TestDeletePreservesNamespaces(t *testing.T) {
+	namespace := []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: shared\n")
+
+	var tests = []struct {
+		description      string
+		deleteNamespaces bool
+		expectedOut      string
+	}{
+		{
+			description: "preserve namespaces",
+			expectedOut: "Preserving namespaces shared: they are not deleted on cleanup\nWould delete pod/leeroy-web in namespace ns\n",
+		},
+		{
+			description:      "delete namespaces",
+			deleteNamespaces: true,
+			expectedOut:      "Would delete namespace/shared\nWould delete pod/leeroy-web in namespace ns\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var out bytes.Buffer
+			cli := &CLI{KubeContext: "kubecontext", DryRun: true, DeleteNamespaces: test.deleteNamespaces}
+			err := cli.Delete(context.Background(), &out, ManifestList{namespace, []byte(podYAML)})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expectedOut, out.String())
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/pkg/errors"
+)
+
+var (
+	annotationTooLong = regexp.MustCompile(`^The (\S+) "([^"]*)" is invalid: metadata\.annotations: Too long`)
+	requestTooLarge   = regexp.MustCompile(`Request entity too large: limit is (\d+)`)
+)
+
+// defaultRequestLimit is the default size limit of a request to the API server.
+const defaultRequestLimit = 3 * 1024 * 1024
+
+// oversizedResources finds the manifests that failed to apply because of the
+// size of the last-applied annotation that a client-side apply adds. Either the
+// annotation is too long, and kubectl names the resource, or the request is
+// too large, and the resources that take more than half of the limit are the ones
+// that the annotation doubled. It also tells if every error is about the size.
+func oversizedResources(output []byte, manifests ManifestList) (ManifestList, bool) {
+	names := map[Resource]bool{}
+	limit := 0
+	onlySize := true
+
+	for _, line := range applyErrors(output) {
+		if match := annotationTooLong.FindStringSubmatch(line); match != nil {
+			names[Resource{Kind: match[1], Name: match[2]}] = true
+		} else if match := requestTooLarge.FindStringSubmatch(line); match != nil {
+			limit, _ = strconv.Atoi(match[1])
+			if limit == 0 {
+				limit = defaultRequestLimit
+			}
+		} else {
+			onlySize = false
+		}
+	}
+
+	oversized := manifests.Filter(func(r Resource) bool {
+		return names[Resource{Kind: r.Kind, Name: r.Name}]
+	})
+	if limit > 0 {
+		for _, manifest := range manifests {
+			r := resourceOf(manifest)
+			if !names[Resource{Kind: r.Kind, Name: r.Name}] && 2*len(manifest) > limit {
+				oversized = append(oversized, manifest)
+			}
+		}
+	}
+
+	return oversized, onlySize
+}
+
+// applyOversizedServerSide applies server-side, without the last-applied annotation,
+// the resources that a client-side apply failed to apply because of their size.
+// It returns the original error if other resources failed too.
+func (c *CLI) applyOversizedServerSide(ctx context.Context, out io.Writer, output []byte, manifests ManifestList, validation string, applyErr error) error {
+	oversized, onlySize := oversizedResources(output, manifests)
+	if len(oversized) == 0 {
+		return applyErr
+	}
+
+	var names []string
+	for _, r := range oversized.Resources() {
+		names = append(names, strings.ToLower(r.Kind)+"/"+r.Name)
+	}
+	color.Default.Fprintln(out, "Applying", strings.Join(names, ", "), "server-side: the last-applied annotation of a client-side apply makes them too large")
+
+	if err := c.runApply(ctx, out, oversized, validation, applyMode{serverSide: true}); err != nil {
+		return errors.Wrap(err, "applying too large resources server-side")
+	}
+
+	if !onlySize {
+		return applyErr
+	}
+
+	return nil
+}
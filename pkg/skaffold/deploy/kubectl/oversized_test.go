@@ -0,0 +1,123 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+var bigConfigYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: big
+data:
+  blob: ` + strings.Repeat("x", 300)
+
+// scriptedApply fakes a first `kubectl apply` that prints its output and
+// fails, and records the commands and the manifests that follow.
+type scriptedApply struct {
+	output   string
+	commands []string
+	applied  []string
+}
+
+func (f *scriptedApply) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	f.commands = append(f.commands, strings.Join(cmd.Args, " "))
+	return nil, nil
+}
+
+func (f *scriptedApply) RunCmd(cmd *exec.Cmd) error {
+	f.commands = append(f.commands, strings.Join(cmd.Args, " "))
+	in, _ := ioutil.ReadAll(cmd.Stdin)
+	f.applied = append(f.applied, string(in))
+
+	if len(f.applied) == 1 {
+		fmt.Fprint(cmd.Stdout, f.output)
+		return fmt.Errorf("exit status 1")
+	}
+	return nil
+}
+
+func TestApplyOversizedServerSide(t *testing.T) {
+	var tests = []struct {
+		description string
+		output      string
+		fallback    bool
+		expectedOut string
+		shouldErr   bool
+	}{
+		{
+			description: "annotation too long",
+			output:      "pod/leeroy-web created\nThe ConfigMap \"big\" is invalid: metadata.annotations: Too long: must have at most 262144 bytes\n",
+			fallback:    true,
+			expectedOut: "pod/leeroy-web created\nThe ConfigMap \"big\" is invalid: metadata.annotations: Too long: must have at most 262144 bytes\n" +
+				"Applying configmap/big server-side: the last-applied annotation of a client-side apply makes them too large\n",
+		},
+		{
+			description: "request too large",
+			output:      "Error from server (RequestEntityTooLarge): error when creating \"STDIN\": Request entity too large: limit is 400\n",
+			fallback:    true,
+			expectedOut: "Error from server (RequestEntityTooLarge): error when creating \"STDIN\": Request entity too large: limit is 400\n" +
+				"Applying configmap/big server-side: the last-applied annotation of a client-side apply makes them too large\n",
+		},
+		{
+			description: "other errors still fail",
+			output:      "The ConfigMap \"big\" is invalid: metadata.annotations: Too long: must have at most 262144 bytes\nError from server (Forbidden): error when creating \"STDIN\": pods \"leeroy-web\" is forbidden\n",
+			fallback:    true,
+			expectedOut: "The ConfigMap \"big\" is invalid: metadata.annotations: Too long: must have at most 262144 bytes\nError from server (Forbidden): error when creating \"STDIN\": pods \"leeroy-web\" is forbidden\n" +
+				"Applying configmap/big server-side: the last-applied annotation of a client-side apply makes them too large\n",
+			shouldErr: true,
+		},
+		{
+			description: "no fallback",
+			output:      "The ConfigMap \"big\" is invalid: metadata.annotations: Too long: must have at most 262144 bytes\n",
+			expectedOut: "The ConfigMap \"big\" is invalid: metadata.annotations: Too long: must have at most 262144 bytes\n",
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			fake := &scriptedApply{output: test.output}
+			util.DefaultExecCommand = fake
+
+			var out bytes.Buffer
+			cli := &CLI{KubeContext: "kubecontext", ServerSideFallback: test.fallback}
+			_, err := cli.Apply(context.Background(), &out, ManifestList{[]byte(podYAML), []byte(bigConfigYAML)})
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expectedOut, out.String())
+			if test.fallback {
+				testutil.CheckDeepEqual(t, []string{
+					"kubectl --context kubecontext apply -f -",
+					"kubectl --context kubecontext get --ignore-not-found=true -o yaml -f -",
+					"kubectl --context kubecontext apply --server-side -f -",
+				}, fake.commands)
+				testutil.CheckDeepEqual(t, bigConfigYAML, fake.applied[1])
+			}
+		})
+	}
+}
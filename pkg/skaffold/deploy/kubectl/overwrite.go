@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// applyWithoutOverwrite applies the resources of the protected kinds with
+// `--overwrite=false` so that kubectl refuses to clobber changes made to
+// them out-of-band. Other resources are applied normally.
+func (c *CLI) applyWithoutOverwrite(ctx context.Context, out io.Writer, manifests ManifestList, validation string, mode applyMode) error {
+	protected := manifests.Filter(c.isProtected)
+	if len(protected) > 0 {
+		if err := c.applyWith(ctx, out, protected, validation, mode, "--overwrite=false"); err != nil {
+			var names []string
+			for _, r := range protected.Resources() {
+				names = append(names, strings.ToLower(r.Kind)+"/"+r.Name)
+			}
+			return errors.Wrapf(err, "refusing to overwrite %s: they might have been changed outside of skaffold", strings.Join(names, ", "))
+		}
+	}
+
+	others := manifests.Filter(func(r Resource) bool { return !c.isProtected(r) })
+	if len(others) == 0 {
+		return nil
+	}
+
+	return c.applyWith(ctx, out, others, validation, mode)
+}
+
+func (c *CLI) isProtected(r Resource) bool {
+	for _, kind := range c.NoOverwriteKinds {
+		if strings.EqualFold(kind, r.Kind) {
+			return true
+		}
+	}
+	return false
+}
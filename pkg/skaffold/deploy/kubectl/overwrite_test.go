@@ -0,0 +1,73 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestApplyWithoutOverwrite(t *testing.T) {
+	var tests = []struct {
+		description string
+		manifests   ManifestList
+		command     util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "no protected resource",
+			manifests:   ManifestList{[]byte(podYAML)},
+			command:     testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+		},
+		{
+			description: "protected resources",
+			manifests:   ManifestList{[]byte(serviceYAML), []byte(podYAML)},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd("kubectl --context kubecontext apply --overwrite=false -f -", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+			),
+		},
+		{
+			description: "only protected resources",
+			manifests:   ManifestList{[]byte(serviceYAML)},
+			command:     testutil.NewFakeCmd("kubectl --context kubecontext apply --overwrite=false -f -", nil),
+		},
+		{
+			description: "conflict",
+			manifests:   ManifestList{[]byte(serviceYAML), []byte(podYAML)},
+			command:     testutil.NewFakeCmd("kubectl --context kubecontext apply --overwrite=false -f -", fmt.Errorf("conflict")),
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			cli := &CLI{KubeContext: "kubecontext", NoOverwriteKinds: []string{"service"}}
+			_, err := cli.Apply(context.Background(), ioutil.Discard, test.manifests)
+
+			testutil.CheckError(t, test.shouldErr, err)
+		})
+	}
+}
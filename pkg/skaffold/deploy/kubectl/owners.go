@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+)
+
+// SetOwnerReference adds an owner reference to every namespaced resource
+// that is not already owned. Resources whose kind is in clusterScoped are
+// left untouched since they can't be owned by a namespaced parent.
+func (l *ManifestList) SetOwnerReference(owner v1alpha3.OwnerReference, clusterScoped map[string]bool) (ManifestList, error) {
+	var updated ManifestList
+
+	for _, manifest := range *l {
+		var m yaml.MapSlice
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		if len(m) == 0 || !setOwnerReference(&m, owner, clusterScoped) {
+			updated = append(updated, manifest)
+			continue
+		}
+
+		updatedManifest, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling yaml")
+		}
+
+		updated = append(updated, updatedManifest)
+	}
+
+	return updated, nil
+}
+
+func setOwnerReference(m *yaml.MapSlice, owner v1alpha3.OwnerReference, clusterScoped map[string]bool) bool {
+	if kind, ok := mapSliceValue(*m, "kind"); ok && clusterScoped[fmt.Sprint(kind)] {
+		return false
+	}
+
+	value, _ := mapSliceValue(*m, "metadata")
+	metadata, _ := value.(yaml.MapSlice)
+
+	if refs, ok := mapSliceValue(metadata, "ownerReferences"); ok {
+		if refs, ok := refs.([]interface{}); ok && len(refs) > 0 {
+			return false
+		}
+	}
+
+	setMapSliceValue(&metadata, "ownerReferences", []interface{}{
+		yaml.MapSlice{
+			{Key: "apiVersion", Value: owner.APIVersion},
+			{Key: "kind", Value: owner.Kind},
+			{Key: "name", Value: owner.Name},
+			{Key: "uid", Value: owner.UID},
+		},
+	})
+	setMapSliceValue(m, "metadata", metadata)
+	return true
+}
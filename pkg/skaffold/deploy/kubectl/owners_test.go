@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestSetOwnerReference(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: orphan
+`), []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: owned
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: ReplicaSet
+    name: other
+    uid: "42"
+`), []byte(`kind: Service
+apiVersion: v1
+metadata:
+  name: ordered
+  labels:
+    app: ordered
+spec:
+  type: ClusterIP
+`), []byte(`apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cluster-scoped
+`)}
+
+	expected := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: orphan
+  ownerReferences:
+  - apiVersion: v1
+    kind: ConfigMap
+    name: parent
+    uid: "1234"
+`), []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: owned
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: ReplicaSet
+    name: other
+    uid: "42"
+`), []byte(`kind: Service
+apiVersion: v1
+metadata:
+  name: ordered
+  labels:
+    app: ordered
+  ownerReferences:
+  - apiVersion: v1
+    kind: ConfigMap
+    name: parent
+    uid: "1234"
+spec:
+  type: ClusterIP
+`), []byte(`apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cluster-scoped
+`)}
+
+	resultManifest, err := manifests.SetOwnerReference(v1alpha3.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Name:       "parent",
+		UID:        "1234",
+	}, map[string]bool{"ClusterRole": true})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/pkg/errors"
+)
+
+// waitInParallel runs the readiness checks concurrently, at most Parallelism
+// at a time, so that the wait lasts as long as the slowest resource rather than
+// the sum of all of them. Each check is bounded by the resource timeout, or by
+// Timeout, and Timeout also bounds the whole wait.
+func (c *CLI) waitInParallel(ctx context.Context, out io.Writer, checks []readinessCheck, builds []build.Artifact, cfg v1alpha3.ReadinessConfig) error {
+	var timeoutArgs []string
+	if cfg.ResourceTimeout != "" {
+		if _, err := time.ParseDuration(cfg.ResourceTimeout); err != nil {
+			return errors.Wrapf(err, "parsing resource timeout %q", cfg.ResourceTimeout)
+		}
+		timeoutArgs = []string{fmt.Sprintf("--timeout=%s", cfg.ResourceTimeout)}
+	}
+
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return errors.Wrapf(err, "parsing readiness timeout %q", cfg.Timeout)
+		}
+		if timeoutArgs == nil {
+			timeoutArgs = []string{fmt.Sprintf("--timeout=%s", cfg.Timeout)}
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var waited []readinessCheck
+	for _, check := range checks {
+		if check.skipped != "" {
+			color.Default.Fprintln(out, "Not waiting for", check.name+":", check.skipped)
+			continue
+		}
+		waited = append(waited, check)
+	}
+	if len(waited) == 0 {
+		return nil
+	}
+
+	color.Default.Fprintf(out, "Waiting for %d resources to be ready, %d at a time...\n", len(waited), cfg.Parallelism)
+
+	var lock sync.Mutex
+	failures := make([]string, len(waited))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < cfg.Parallelism && i < len(waited); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indices {
+				check := waited[i]
+				if ctx.Err() != nil {
+					failures[i] = fmt.Sprintf("%s: not checked, readiness timeout of %s exceeded", check.name, cfg.Timeout)
+					continue
+				}
+
+				w := &prefixWriter{out: out, lock: &lock, prefix: "[" + check.name + "] "}
+				err := c.runCheck(ctx, w, check, append(check.args, timeoutArgs...), builds, cfg)
+				w.Flush()
+				if err != nil {
+					fmt.Fprintln(w, "not ready:", err)
+					failures[i] = fmt.Sprintf("%s: %v", check.name, err)
+				} else {
+					fmt.Fprintln(w, "ready")
+				}
+			}
+		}()
+	}
+
+	for i := range waited {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	var notReady []string
+	for _, failure := range failures {
+		if failure != "" {
+			notReady = append(notReady, failure)
+		}
+	}
+
+	return notReadyError(notReady, len(waited))
+}
+
+// prefixWriter writes the output of a concurrent check line by line, each
+// prefixed by the resource, so that the output of the checks doesn't mix.
+type prefixWriter struct {
+	out    io.Writer
+	lock   *sync.Mutex
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+
+		if err := w.writeLine(w.buf.Next(i + 1)); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush writes the last line if it doesn't end with a new line.
+func (w *prefixWriter) Flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	line := append(w.buf.Bytes(), '\n')
+	w.buf.Reset()
+	return w.writeLine(line)
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	_, err := fmt.Fprintf(w.out, "%s%s", w.prefix, line)
+	return err
+}
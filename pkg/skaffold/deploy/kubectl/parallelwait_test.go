@@ -0,0 +1,126 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+// concurrentChecks fakes readiness checks that take a moment, and records
+// how many of them run at the same time.
+type concurrentChecks struct {
+	lock       sync.Mutex
+	outputs    map[string]string
+	errors     map[string]error
+	running    int
+	maxRunning int
+	commands   []string
+}
+
+func (f *concurrentChecks) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	return nil, nil
+}
+
+func (f *concurrentChecks) RunCmd(cmd *exec.Cmd) error {
+	command := strings.Join(cmd.Args, " ")
+
+	f.lock.Lock()
+	f.commands = append(f.commands, command)
+	f.running++
+	if f.running > f.maxRunning {
+		f.maxRunning = f.running
+	}
+	f.lock.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+	fmt.Fprint(cmd.Stdout, f.outputs[command])
+
+	f.lock.Lock()
+	f.running--
+	f.lock.Unlock()
+
+	return f.errors[command]
+}
+
+func TestWaitInParallel(t *testing.T) {
+	rollout := "kubectl --context kubecontext --namespace ns rollout status deployment/web --timeout=1m"
+	job := "kubectl --context kubecontext wait --for=condition=complete job/migrate --timeout=1m"
+	pod := "kubectl --context kubecontext wait --for=condition=Ready pod/debug --timeout=1m"
+
+	fake := &concurrentChecks{
+		outputs: map[string]string{
+			rollout: "Waiting for deployment \"web\" rollout to finish\ndeployment \"web\" successfully rolled out",
+			pod:     "error: timed out waiting for the condition\n",
+		},
+		errors: map[string]error{pod: fmt.Errorf("timeout")},
+	}
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = fake
+
+	var out bytes.Buffer
+	cli := &CLI{KubeContext: "kubecontext"}
+	err := cli.WaitForReadiness(context.Background(), &out, waitManifests, nil, v1alpha3.ReadinessConfig{Timeout: "1m", Parallelism: 2})
+
+	testutil.CheckError(t, true, err)
+	testutil.CheckDeepEqual(t, "1 of 3 resources are not ready:\n - pod/debug: timeout", err.Error())
+	testutil.CheckDeepEqual(t, 2, fake.maxRunning)
+
+	sort.Strings(fake.commands)
+	testutil.CheckDeepEqual(t, []string{rollout, pod, job}, fake.commands)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	testutil.CheckDeepEqual(t, []string{
+		"Not waiting for configmap/config: no readiness check for kind ConfigMap",
+		"Waiting for 3 resources to be ready, 2 at a time...",
+	}, lines[:2])
+
+	checkLines := lines[2:]
+	sort.Strings(checkLines)
+	testutil.CheckDeepEqual(t, []string{
+		"[deployment/web] Waiting for deployment \"web\" rollout to finish",
+		"[deployment/web] deployment \"web\" successfully rolled out",
+		"[deployment/web] ready",
+		"[job/migrate] ready",
+		"[pod/debug] error: timed out waiting for the condition",
+		"[pod/debug] not ready: timeout",
+	}, checkLines)
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &prefixWriter{out: &out, lock: &sync.Mutex{}, prefix: "[job/migrate] "}
+
+	fmt.Fprint(w, "first line\nsecond")
+	testutil.CheckDeepEqual(t, "[job/migrate] first line\n", out.String())
+
+	fmt.Fprint(w, " line\nlast")
+	w.Flush()
+	testutil.CheckDeepEqual(t, "[job/migrate] first line\n[job/migrate] second line\n[job/migrate] last\n", out.String())
+}
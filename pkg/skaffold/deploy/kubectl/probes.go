@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// OverrideProbes rewrites the delays of the readiness and liveness probes
+// of the containers that run one of the given images, or of every container
+// if sidecars are included. Containers without probes are left untouched.
+func (l *ManifestList) OverrideProbes(cfg v1alpha3.DevProbes, images map[string]bool) (ManifestList, error) {
+	var updated ManifestList
+
+	for _, manifest := range *l {
+		var m yaml.MapSlice
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		probes := &probesOverrider{cfg: cfg, images: images}
+		if !probes.visit(m) {
+			updated = append(updated, manifest)
+			continue
+		}
+
+		updatedManifest, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling yaml")
+		}
+
+		updated = append(updated, updatedManifest)
+	}
+
+	return updated, nil
+}
+
+type probesOverrider struct {
+	cfg    v1alpha3.DevProbes
+	images map[string]bool
+}
+
+func (p *probesOverrider) visit(value interface{}) bool {
+	changed := false
+
+	switch t := value.(type) {
+	case []interface{}:
+		for _, v := range t {
+			changed = p.visit(v) || changed
+		}
+	case yaml.MapSlice:
+		for _, item := range t {
+			if item.Key == "containers" || item.Key == "initContainers" {
+				changed = p.overrideContainers(item.Value) || changed
+			} else {
+				changed = p.visit(item.Value) || changed
+			}
+		}
+	}
+
+	return changed
+}
+
+func (p *probesOverrider) overrideContainers(containers interface{}) bool {
+	list, ok := containers.([]interface{})
+	if !ok {
+		return false
+	}
+
+	changed := false
+	for i, c := range list {
+		container, ok := c.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+
+		value, _ := mapSliceValue(container, "image")
+		image, _ := value.(string)
+		if !p.cfg.IncludeSidecars && !p.images[image] {
+			continue
+		}
+
+		for _, key := range []string{"readinessProbe", "livenessProbe"} {
+			value, _ := mapSliceValue(container, key)
+			probe, ok := value.(yaml.MapSlice)
+			if !ok {
+				continue
+			}
+
+			if p.cfg.InitialDelaySeconds != nil {
+				setMapSliceValue(&probe, "initialDelaySeconds", *p.cfg.InitialDelaySeconds)
+			}
+			if p.cfg.PeriodSeconds != nil {
+				setMapSliceValue(&probe, "periodSeconds", *p.cfg.PeriodSeconds)
+			}
+			setMapSliceValue(&container, key, probe)
+			changed = true
+		}
+		list[i] = container
+	}
+
+	return changed
+}
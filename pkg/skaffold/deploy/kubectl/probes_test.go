@@ -0,0 +1,85 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestOverrideProbes(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - image: web:abcdef
+    livenessProbe:
+      initialDelaySeconds: 60
+      periodSeconds: 30
+    name: web
+    readinessProbe:
+      initialDelaySeconds: 60
+  - image: proxy:1.0
+    name: sidecar
+    readinessProbe:
+      initialDelaySeconds: 60
+`), []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: no-probes
+spec:
+  containers:
+  - image: web:abcdef
+    name: web
+`)}
+
+	zero, two := 0, 2
+	cfg := v1alpha3.DevProbes{InitialDelaySeconds: &zero, PeriodSeconds: &two}
+	images := map[string]bool{"web:abcdef": true}
+
+	expected := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - image: web:abcdef
+    livenessProbe:
+      initialDelaySeconds: 0
+      periodSeconds: 2
+    name: web
+    readinessProbe:
+      initialDelaySeconds: 0
+      periodSeconds: 2
+  - image: proxy:1.0
+    name: sidecar
+    readinessProbe:
+      initialDelaySeconds: 60
+`), manifests[1]}
+
+	resultManifest, err := manifests.OverrideProbes(cfg, images)
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
+
+	cfg.IncludeSidecars = true
+	resultManifest, err = manifests.OverrideProbes(cfg, images)
+	testutil.CheckErrorAndDeepEqual(t, false, err, false, strings.Contains(resultManifest.String(), "initialDelaySeconds: 60"))
+}
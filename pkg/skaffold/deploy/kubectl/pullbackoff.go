@@ -0,0 +1,226 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const defaultImagePullRetryDelay = 10 * time.Second
+
+// for testing
+var pullBackOffPollInterval = 5 * time.Second
+
+// pullBackOffReasons are the reasons of a container waiting for its image.
+var pullBackOffReasons = map[string]bool{
+	"ImagePullBackOff": true,
+	"ErrImagePull":     true,
+}
+
+// pullBackOff is a container that can't pull its image.
+type pullBackOff struct {
+	image  string
+	reason string
+}
+
+// runCheck runs a readiness check. When it fails because a pod can't pull
+// an image that skaffold just pushed, the check is retried: the image is
+// often not visible in the registry yet. Images that skaffold didn't build,
+// or that still can't be pulled once the retries are exhausted, fail the check.
+func (c *CLI) runCheck(ctx context.Context, out io.Writer, check readinessCheck, args []string, builds []build.Artifact, cfg v1alpha3.ReadinessConfig) error {
+	if err := c.waitForClaims(ctx, out, check, cfg); err != nil {
+		return err
+	}
+
+	if cfg.ImagePullRetries <= 0 {
+		return c.run(ctx, nil, out, check.namespace, check.command, nil, args...)
+	}
+
+	delay := defaultImagePullRetryDelay
+	if cfg.ImagePullRetryDelay != "" {
+		parsed, parseErr := time.ParseDuration(cfg.ImagePullRetryDelay)
+		if parseErr != nil {
+			return fmt.Errorf("parsing image pull retry delay %q: %v", cfg.ImagePullRetryDelay, parseErr)
+		}
+		delay = parsed
+	}
+
+	built := func(image string) bool {
+		for _, b := range builds {
+			if docker.SameImage(b.Tag, image) {
+				return true
+			}
+		}
+		return false
+	}
+
+	backOff, found, err := c.runWatchingPulls(ctx, out, check, args)
+	for retries := cfg.ImagePullRetries; err != nil; retries-- {
+		if !found {
+			return err
+		}
+		if !built(backOff.image) {
+			return fmt.Errorf("image %s can't be pulled (%s): %v", backOff.image, backOff.reason, err)
+		}
+		if retries == 0 {
+			return fmt.Errorf("image %s still can't be pulled (%s) after %d retries: %v", backOff.image, backOff.reason, cfg.ImagePullRetries, err)
+		}
+
+		color.Default.Fprintf(out, "Image %s of %s can't be pulled yet (%s), retrying in %s (%d retries left)\n", backOff.image, check.name, backOff.reason, delay, retries-1)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		backOff, found, err = c.runWatchingPulls(ctx, out, check, args)
+	}
+
+	return nil
+}
+
+// runWatchingPulls runs a readiness check while polling the pods of the resource,
+// so that a pod that can't pull its image stops the check right away, rather than
+// once the check times out. It returns the container that can't pull its image, if any.
+func (c *CLI) runWatchingPulls(ctx context.Context, out io.Writer, check readinessCheck, args []string) (pullBackOff, bool, error) {
+	checkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.run(checkCtx, nil, out, check.namespace, check.command, nil, args...)
+	}()
+
+	ticker := time.NewTicker(pullBackOffPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			if err == nil {
+				return pullBackOff{}, false, nil
+			}
+			backOff, found := c.findPullBackOff(ctx, check)
+			return backOff, found, err
+		case <-ticker.C:
+			backOff, found := c.findPullBackOff(ctx, check)
+			if !found {
+				continue
+			}
+			cancel()
+			<-done
+			return backOff, true, errors.New("stopped waiting early")
+		}
+	}
+}
+
+// findPullBackOff looks for a pod of the resource that can't pull an image.
+func (c *CLI) findPullBackOff(ctx context.Context, check readinessCheck) (pullBackOff, bool) {
+	args, found := podsOf(check)
+	if !found {
+		return pullBackOff{}, false
+	}
+
+	buf, err := c.runOut(ctx, nil, check.namespace, "get", nil, append(args, "-o", "yaml")...)
+	if err != nil {
+		logrus.Debugln("Unable to list the pods of", check.name, err)
+		return pullBackOff{}, false
+	}
+
+	type containerStatus struct {
+		Image string `yaml:"image"`
+		State struct {
+			Waiting *struct {
+				Reason string `yaml:"reason"`
+			} `yaml:"waiting"`
+		} `yaml:"state"`
+	}
+	type podStatus struct {
+		Status struct {
+			InitContainerStatuses []containerStatus `yaml:"initContainerStatuses"`
+			ContainerStatuses     []containerStatus `yaml:"containerStatuses"`
+		} `yaml:"status"`
+	}
+	var pods struct {
+		Kind      string      `yaml:"kind"`
+		Items     []podStatus `yaml:"items"`
+		podStatus `yaml:",inline"`
+	}
+	if err := yaml.Unmarshal(buf, &pods); err != nil {
+		logrus.Debugln("Unable to read the pods of", check.name, err)
+		return pullBackOff{}, false
+	}
+	if pods.Kind == "Pod" {
+		pods.Items = []podStatus{pods.podStatus}
+	}
+
+	for _, pod := range pods.Items {
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			if status.State.Waiting != nil && pullBackOffReasons[status.State.Waiting.Reason] {
+				return pullBackOff{image: status.Image, reason: status.State.Waiting.Reason}, true
+			}
+		}
+	}
+
+	return pullBackOff{}, false
+}
+
+// podsOf returns the `kubectl get` arguments that list the pods of a resource.
+func podsOf(check readinessCheck) ([]string, bool) {
+	r := resourceOf(check.manifest)
+
+	switch r.Kind {
+	case "Pod":
+		return []string{check.name}, true
+	case "Job":
+		return []string{"pods", "-l", "job-name=" + r.Name}, true
+	case "Deployment", "StatefulSet", "DaemonSet":
+		var m struct {
+			Spec struct {
+				Selector struct {
+					MatchLabels map[string]string `yaml:"matchLabels"`
+				} `yaml:"selector"`
+			} `yaml:"spec"`
+		}
+		if err := yaml.Unmarshal(check.manifest, &m); err != nil || len(m.Spec.Selector.MatchLabels) == 0 {
+			return nil, false
+		}
+
+		var selector []string
+		for k, v := range m.Spec.Selector.MatchLabels {
+			selector = append(selector, k+"="+v)
+		}
+		sort.Strings(selector)
+
+		return []string{"pods", "-l", strings.Join(selector, ",")}, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const (
+	rolloutWeb = "kubectl --context kubecontext --namespace ns rollout status deployment/web"
+	getWebPods = "kubectl --context kubecontext --namespace ns get pods -l app=web,tier=front -o yaml"
+)
+
+func podsPullingImage(image string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: List
+items:
+- kind: Pod
+  status:
+    containerStatuses:
+    - image: %s
+      state:
+        waiting:
+          reason: ImagePullBackOff
+`, image)
+}
+
+func TestWaitRetriesImagePullBackOff(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: ns
+spec:
+  selector:
+    matchLabels:
+      tier: front
+      app: web
+`)}
+	builds := []build.Artifact{{ImageName: "gcr.io/k8s-skaffold/web", Tag: "gcr.io/k8s-skaffold/web:v1"}}
+	cfg := v1alpha3.ReadinessConfig{ImagePullRetries: 2, ImagePullRetryDelay: "1ms"}
+
+	var tests = []struct {
+		description string
+		command     util.Command
+		expectedOut string
+		shouldErr   bool
+	}{
+		{
+			description: "image becomes available",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd(rolloutWeb, fmt.Errorf("timeout")),
+				testutil.NewFakeCmdOut(getWebPods, podsPullingImage("gcr.io/k8s-skaffold/web:v1"), nil),
+				testutil.NewFakeCmd(rolloutWeb, nil),
+			),
+			expectedOut: "Waiting for deployment/web to be ready...\n" +
+				"Image gcr.io/k8s-skaffold/web:v1 of deployment/web can't be pulled yet (ImagePullBackOff), retrying in 1ms (1 retries left)\n",
+		},
+		{
+			description: "image never available",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd(rolloutWeb, fmt.Errorf("timeout")),
+				testutil.NewFakeCmdOut(getWebPods, podsPullingImage("gcr.io/k8s-skaffold/web:v1"), nil),
+				testutil.NewFakeCmd(rolloutWeb, fmt.Errorf("timeout")),
+				testutil.NewFakeCmdOut(getWebPods, podsPullingImage("gcr.io/k8s-skaffold/web:v1"), nil),
+				testutil.NewFakeCmd(rolloutWeb, fmt.Errorf("timeout")),
+				testutil.NewFakeCmdOut(getWebPods, podsPullingImage("gcr.io/k8s-skaffold/web:v1"), nil),
+			),
+			expectedOut: "Waiting for deployment/web to be ready...\n" +
+				"Image gcr.io/k8s-skaffold/web:v1 of deployment/web can't be pulled yet (ImagePullBackOff), retrying in 1ms (1 retries left)\n" +
+				"Image gcr.io/k8s-skaffold/web:v1 of deployment/web can't be pulled yet (ImagePullBackOff), retrying in 1ms (0 retries left)\n",
+			shouldErr: true,
+		},
+		{
+			description: "image not built by skaffold",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd(rolloutWeb, fmt.Errorf("timeout")),
+				testutil.NewFakeCmdOut(getWebPods, podsPullingImage("redis:typo"), nil),
+			),
+			expectedOut: "Waiting for deployment/web to be ready...\n",
+			shouldErr:   true,
+		},
+		{
+			description: "other failure",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd(rolloutWeb, fmt.Errorf("timeout")),
+				testutil.NewFakeCmdOut(getWebPods, "apiVersion: v1\nkind: List\nitems: []\n", nil),
+			),
+			expectedOut: "Waiting for deployment/web to be ready...\n",
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			var out bytes.Buffer
+			cli := &CLI{KubeContext: "kubecontext"}
+			err := cli.WaitForReadiness(context.Background(), &out, manifests, builds, cfg)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expectedOut, out.String())
+		})
+	}
+}
+
+// slowRollout fakes a rollout that only returns when it's killed, while
+// the pods can't pull their image.
+type slowRollout struct {
+	sync.Mutex
+	image  string
+	events []string
+}
+
+func (f *slowRollout) RunCmd(cmd *exec.Cmd) error {
+	time.Sleep(100 * time.Millisecond)
+	f.record("rollout killed")
+	return errors.New("signal: killed")
+}
+
+func (f *slowRollout) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	f.record("pods listed")
+	return []byte(podsPullingImage(f.image)), nil
+}
+
+func (f *slowRollout) record(event string) {
+	f.Lock()
+	defer f.Unlock()
+	f.events = append(f.events, event)
+}
+
+func TestWaitDetectsImagePullBackOffWhileWaiting(t *testing.T) {
+	defer func(d time.Duration) { pullBackOffPollInterval = d }(pullBackOffPollInterval)
+	pullBackOffPollInterval = time.Millisecond
+
+	manifests := ManifestList{[]byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n  namespace: ns\n")}
+	cfg := v1alpha3.ReadinessConfig{ImagePullRetries: 1, ImagePullRetryDelay: "1ms"}
+
+	var tests = []struct {
+		description string
+		pulled      string
+		builds      []build.Artifact
+		expectedErr string
+	}{
+		{
+			description: "image not built by skaffold",
+			pulled:      "docker.io/library/redis:typo",
+			builds:      []build.Artifact{{ImageName: "skaffold/web", Tag: "skaffold/web:v1"}},
+			expectedErr: "image docker.io/library/redis:typo can't be pulled (ImagePullBackOff)",
+		},
+		{
+			description: "built image reported with its normalized name",
+			pulled:      "docker.io/skaffold/web:v1",
+			builds:      []build.Artifact{{ImageName: "skaffold/web", Tag: "skaffold/web:v1"}},
+			expectedErr: "image docker.io/skaffold/web:v1 still can't be pulled (ImagePullBackOff) after 1 retries",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			fake := &slowRollout{image: test.pulled}
+			util.DefaultExecCommand = fake
+
+			cli := &CLI{KubeContext: "kubecontext"}
+			err := cli.WaitForReadiness(context.Background(), ioutil.Discard, manifests, test.builds, cfg)
+
+			testutil.CheckError(t, true, err)
+			if !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("expected error %q, got %q", test.expectedErr, err)
+			}
+			// The pull backoff is found before the rollout times out.
+			testutil.CheckDeepEqual(t, "pods listed", fake.events[0])
+		})
+	}
+}
+
+func TestPodsOf(t *testing.T) {
+	args, found := podsOf(readinessCheck{name: "job/migrate", manifest: []byte("apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n")})
+	testutil.CheckDeepEqual(t, true, found)
+	testutil.CheckDeepEqual(t, []string{"pods", "-l", "job-name=migrate"}, args)
+
+	args, found = podsOf(readinessCheck{name: "pod/debug", manifest: []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: debug\n")})
+	testutil.CheckDeepEqual(t, true, found)
+	testutil.CheckDeepEqual(t, []string{"pod/debug"}, args)
+
+	_, found = podsOf(readinessCheck{name: "crontab/c", manifest: []byte("apiVersion: example.com/v1\nkind: CronTab\nmetadata:\n  name: c\n")})
+	testutil.CheckDeepEqual(t, false, found)
+}
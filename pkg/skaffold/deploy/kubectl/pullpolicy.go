@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SetImagePullPolicy sets the image pull policy of every container.
+// Manifests without containers are returned byte for byte.
+func (l *ManifestList) SetImagePullPolicy(policy string) (ManifestList, error) {
+	var updated ManifestList
+
+	for _, manifest := range *l {
+		var m yaml.MapSlice
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		if !setImagePullPolicy(m, policy) {
+			updated = append(updated, manifest)
+			continue
+		}
+
+		updatedManifest, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling yaml")
+		}
+
+		updated = append(updated, updatedManifest)
+	}
+
+	return updated, nil
+}
+
+func setImagePullPolicy(value interface{}, policy string) bool {
+	changed := false
+
+	switch t := value.(type) {
+	case []interface{}:
+		for _, v := range t {
+			changed = setImagePullPolicy(v, policy) || changed
+		}
+	case yaml.MapSlice:
+		for _, item := range t {
+			if item.Key == "containers" || item.Key == "initContainers" {
+				changed = setContainersPullPolicy(item.Value, policy) || changed
+			} else {
+				changed = setImagePullPolicy(item.Value, policy) || changed
+			}
+		}
+	}
+
+	return changed
+}
+
+func setContainersPullPolicy(containers interface{}, policy string) bool {
+	list, ok := containers.([]interface{})
+	if !ok {
+		return false
+	}
+
+	changed := false
+	for i, c := range list {
+		container, ok := c.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		if current, _ := mapSliceValue(container, "imagePullPolicy"); current == policy {
+			continue
+		}
+
+		setMapSliceValue(&container, "imagePullPolicy", policy)
+		list[i] = container
+		changed = true
+	}
+
+	return changed
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestSetImagePullPolicy(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - image: web
+        imagePullPolicy: Always
+        name: web
+      initContainers:
+      - image: init
+        name: init
+`), []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`)}
+
+	expected := ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - image: web
+        imagePullPolicy: IfNotPresent
+        name: web
+      initContainers:
+      - image: init
+        name: init
+        imagePullPolicy: IfNotPresent
+`), manifests[1]}
+
+	resultManifest, err := manifests.SetImagePullPolicy("IfNotPresent")
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"golang.org/x/time/rate"
+)
+
+// waitForApplyRateLimit blocks until the next `kubectl apply` can be sent
+// to the API server. The first time applies are throttled, the effective
+// rate is printed so that slow deploys can be explained.
+func (c *CLI) waitForApplyRateLimit(ctx context.Context, out io.Writer) error {
+	if c.ApplyRateLimit == nil || c.ApplyRateLimit.QPS <= 0 {
+		return nil
+	}
+
+	c.limiterOnce.Do(func() {
+		burst := c.ApplyRateLimit.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		c.limiter = rate.NewLimiter(rate.Limit(c.ApplyRateLimit.QPS), burst)
+	})
+
+	reservation := c.limiter.Reserve()
+	delay := reservation.Delay()
+	if delay == 0 {
+		return nil
+	}
+
+	if !c.throttled {
+		c.throttled = true
+		color.Default.Fprintf(out, "Throttling applies to %g per second, with a burst of %d\n", float64(c.limiter.Limit()), c.limiter.Burst())
+	}
+
+	select {
+	case <-ctx.Done():
+		reservation.Cancel()
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestApplyRateLimit(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+	)
+
+	var out bytes.Buffer
+	cli := &CLI{
+		KubeContext:    "kubecontext",
+		ApplyBatchSize: 1,
+		ApplyRateLimit: &v1alpha3.ApplyRateLimit{QPS: 100, Burst: 2},
+	}
+	_, err := cli.Apply(context.Background(), &out, ManifestList{[]byte(podYAML), []byte(serviceYAML), []byte(crYAML)})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Throttling applies to 100 per second, with a burst of 2\n", out.String())
+}
+
+func TestApplyRateLimitCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cli := &CLI{ApplyRateLimit: &v1alpha3.ApplyRateLimit{QPS: 0.001}}
+	testutil.CheckError(t, false, cli.waitForApplyRateLimit(ctx, &bytes.Buffer{}))
+	testutil.CheckError(t, true, cli.waitForApplyRateLimit(ctx, &bytes.Buffer{}))
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SetReplicas overrides the number of replicas of Deployments and StatefulSets.
+// Workloads targeted by a HorizontalPodAutoscaler of the same list are left
+// untouched, not to fight with the autoscaler. Targets are matched against
+// the rendered names, ie. after kustomize's namePrefix and nameSuffix.
+func (l *ManifestList) SetReplicas(replicas int) (ManifestList, error) {
+	autoscaled, err := l.autoscaledResources()
+	if err != nil {
+		return nil, err
+	}
+
+	var updated ManifestList
+
+	for _, manifest := range *l {
+		r := resourceOf(manifest)
+		if r.Kind != "Deployment" && r.Kind != "StatefulSet" {
+			updated = append(updated, manifest)
+			continue
+		}
+
+		if autoscaled[Resource{Kind: r.Kind, Namespace: r.Namespace, Name: r.Name}] {
+			logrus.Debugf("Not overriding replicas of %s/%s, it's scaled by a HorizontalPodAutoscaler", r.Kind, r.Name)
+			updated = append(updated, manifest)
+			continue
+		}
+
+		var m yaml.MapSlice
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		value, _ := mapSliceValue(m, "spec")
+		spec, _ := value.(yaml.MapSlice)
+		setMapSliceValue(&spec, "replicas", replicas)
+		setMapSliceValue(&m, "spec", spec)
+
+		updatedManifest, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling yaml")
+		}
+
+		updated = append(updated, updatedManifest)
+	}
+
+	return updated, nil
+}
+
+// autoscaledResources lists the resources targeted by HorizontalPodAutoscalers.
+func (l *ManifestList) autoscaledResources() (map[Resource]bool, error) {
+	autoscaled := map[Resource]bool{}
+
+	for _, manifest := range *l {
+		if resourceOf(manifest).Kind != "HorizontalPodAutoscaler" {
+			continue
+		}
+
+		var hpa struct {
+			Metadata struct {
+				Namespace string `yaml:"namespace"`
+			} `yaml:"metadata"`
+			Spec struct {
+				ScaleTargetRef struct {
+					Kind string `yaml:"kind"`
+					Name string `yaml:"name"`
+				} `yaml:"scaleTargetRef"`
+			} `yaml:"spec"`
+		}
+		if err := yaml.Unmarshal(manifest, &hpa); err != nil {
+			return nil, errors.Wrap(err, "reading HorizontalPodAutoscaler")
+		}
+
+		autoscaled[Resource{
+			Kind:      hpa.Spec.ScaleTargetRef.Kind,
+			Namespace: hpa.Metadata.Namespace,
+			Name:      hpa.Spec.ScaleTargetRef.Name,
+		}] = true
+	}
+
+	return autoscaled, nil
+}
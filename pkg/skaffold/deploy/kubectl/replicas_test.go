@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestSetReplicas(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+`), []byte(`apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+`), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: autoscaled
+spec:
+  replicas: 3
+`), []byte(`apiVersion: autoscaling/v1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: autoscaled
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: autoscaled
+`), []byte(`apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+`)}
+
+	expected := ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+`), []byte(`apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 1
+`), manifests[2], manifests[3], manifests[4]}
+
+	resultManifest, err := manifests.SetReplicas(1)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
+}
+
+func TestSetReplicasWithNamePrefix(t *testing.T) {
+	// Rendered by a kustomization with `namePrefix: dev-`, that also renames the target of the HPA
+	manifests := ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: dev-web
+spec:
+  replicas: 3
+`), []byte(`apiVersion: autoscaling/v1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: dev-web
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: dev-web
+`)}
+
+	resultManifest, err := manifests.SetReplicas(1)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, manifests.String(), resultManifest.String())
+}
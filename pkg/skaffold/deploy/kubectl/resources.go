@@ -0,0 +1,212 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// SetDefaultResources sets the default requests and limits of the containers
+// that run one of the given images, or of every container if sidecars are
+// included. Only the missing requests and limits are set. A default request
+// is not set if the container has a limit for that resource, since kubernetes
+// then defaults the request to the limit, and a default limit is not set if
+// it is lower than the request of the container.
+// Manifests that are not changed are returned byte for byte.
+func (l *ManifestList) SetDefaultResources(cfg v1alpha3.DefaultResources, images map[string]bool) (ManifestList, error) {
+	defaults := &resourcesDefaulter{cfg: cfg, images: images}
+	if err := defaults.validate(); err != nil {
+		return nil, err
+	}
+
+	var updated ManifestList
+
+	for _, manifest := range *l {
+		var m yaml.MapSlice
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		changed, err := defaults.visit(m)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			updated = append(updated, manifest)
+			continue
+		}
+
+		updatedManifest, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling yaml")
+		}
+
+		updated = append(updated, updatedManifest)
+	}
+
+	return updated, nil
+}
+
+type resourcesDefaulter struct {
+	cfg    v1alpha3.DefaultResources
+	images map[string]bool
+}
+
+// validate checks that the defaults are quantities and that no default
+// request is greater than the default limit of the same resource.
+func (d *resourcesDefaulter) validate() error {
+	for _, defaults := range []map[string]string{d.cfg.Requests, d.cfg.Limits} {
+		for name, value := range defaults {
+			if _, err := resource.ParseQuantity(value); err != nil {
+				return errors.Wrapf(err, "invalid default %s %q", name, value)
+			}
+		}
+	}
+
+	for name, request := range d.cfg.Requests {
+		limit, found := d.cfg.Limits[name]
+		if !found {
+			continue
+		}
+		if quantity := resource.MustParse(request); quantity.Cmp(resource.MustParse(limit)) > 0 {
+			return fmt.Errorf("default %s request %s is greater than its default limit %s", name, request, limit)
+		}
+	}
+
+	return nil
+}
+
+func (d *resourcesDefaulter) visit(value interface{}) (bool, error) {
+	changed := false
+
+	switch t := value.(type) {
+	case []interface{}:
+		for _, v := range t {
+			c, err := d.visit(v)
+			if err != nil {
+				return false, err
+			}
+			changed = c || changed
+		}
+	case yaml.MapSlice:
+		for _, item := range t {
+			var c bool
+			var err error
+			if item.Key == "containers" || item.Key == "initContainers" {
+				c, err = d.setContainersDefaults(item.Value)
+			} else {
+				c, err = d.visit(item.Value)
+			}
+			if err != nil {
+				return false, err
+			}
+			changed = c || changed
+		}
+	}
+
+	return changed, nil
+}
+
+func (d *resourcesDefaulter) setContainersDefaults(containers interface{}) (bool, error) {
+	list, ok := containers.([]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	changed := false
+	for i, c := range list {
+		container, ok := c.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+
+		value, _ := mapSliceValue(container, "image")
+		image, _ := value.(string)
+		if !d.cfg.IncludeSidecars && !d.images[image] {
+			continue
+		}
+
+		value, _ = mapSliceValue(container, "resources")
+		resources, _ := value.(yaml.MapSlice)
+		value, _ = mapSliceValue(resources, "requests")
+		requests, _ := value.(yaml.MapSlice)
+		value, _ = mapSliceValue(resources, "limits")
+		limits, _ := value.(yaml.MapSlice)
+
+		containerChanged := false
+		for _, name := range sortedKeys(d.cfg.Requests) {
+			if _, found := mapSliceValue(requests, name); found {
+				continue
+			}
+			if _, found := mapSliceValue(limits, name); found {
+				continue
+			}
+			setMapSliceValue(&requests, name, d.cfg.Requests[name])
+			containerChanged = true
+		}
+
+		for _, name := range sortedKeys(d.cfg.Limits) {
+			if _, found := mapSliceValue(limits, name); found {
+				continue
+			}
+			if request, found := mapSliceValue(requests, name); found {
+				quantity, err := resource.ParseQuantity(fmt.Sprint(request))
+				if err != nil {
+					containerName, _ := mapSliceValue(container, "name")
+					return false, errors.Wrapf(err, "invalid %s request of container %v", name, containerName)
+				}
+				if quantity.Cmp(resource.MustParse(d.cfg.Limits[name])) > 0 {
+					continue
+				}
+			}
+			setMapSliceValue(&limits, name, d.cfg.Limits[name])
+			containerChanged = true
+		}
+
+		if !containerChanged {
+			continue
+		}
+
+		if len(requests) > 0 {
+			setMapSliceValue(&resources, "requests", requests)
+		}
+		if len(limits) > 0 {
+			setMapSliceValue(&resources, "limits", limits)
+		}
+		setMapSliceValue(&container, "resources", resources)
+		list[i] = container
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// sortedKeys lists the keys of a map in a stable order.
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
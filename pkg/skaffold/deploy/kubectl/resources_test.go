@@ -0,0 +1,252 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestSetDefaultResources(t *testing.T) {
+	cfg := v1alpha3.DefaultResources{
+		Requests: map[string]string{"cpu": "100m", "memory": "128Mi"},
+		Limits:   map[string]string{"cpu": "500m", "memory": "512Mi"},
+	}
+	images := map[string]bool{"web:abcdef": true}
+
+	var tests = []struct {
+		description     string
+		includeSidecars bool
+		manifest        string
+		expected        string
+	}{
+		{
+			description: "no resources",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - image: web:abcdef
+    name: web
+`,
+			expected: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - image: web:abcdef
+    name: web
+    resources:
+      requests:
+        cpu: 100m
+        memory: 128Mi
+      limits:
+        cpu: 500m
+        memory: 512Mi
+`,
+		},
+		{
+			description: "requests only",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - image: web:abcdef
+    name: web
+    resources:
+      requests:
+        cpu: 250m
+        memory: 1Gi
+`,
+			expected: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - image: web:abcdef
+    name: web
+    resources:
+      requests:
+        cpu: 250m
+        memory: 1Gi
+      limits:
+        cpu: 500m
+`,
+		},
+		{
+			description: "partial requests",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - image: web:abcdef
+    name: web
+    resources:
+      requests:
+        cpu: 1
+`,
+			expected: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - image: web:abcdef
+    name: web
+    resources:
+      requests:
+        cpu: 1
+        memory: 128Mi
+      limits:
+        memory: 512Mi
+`,
+		},
+		{
+			description: "limits only",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - image: web:abcdef
+    name: web
+    resources:
+      limits:
+        memory: 64Mi
+`,
+			expected: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - image: web:abcdef
+    name: web
+    resources:
+      limits:
+        memory: 64Mi
+        cpu: 500m
+      requests:
+        cpu: 100m
+`,
+		},
+		{
+			description: "complete resources are kept byte for byte",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - {image: web:abcdef, name: web, resources: {requests: {cpu: 1, memory: 1Gi}, limits: {cpu: 2, memory: 2Gi}}}
+`,
+			expected: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - {image: web:abcdef, name: web, resources: {requests: {cpu: 1, memory: 1Gi}, limits: {cpu: 2, memory: 2Gi}}}
+`,
+		},
+		{
+			description: "sidecars are left untouched",
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - {image: proxy:1.0, name: sidecar}
+`,
+			expected: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - {image: proxy:1.0, name: sidecar}
+`,
+		},
+		{
+			description:     "include sidecars",
+			includeSidecars: true,
+			manifest: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  initContainers:
+  - {image: proxy:1.0, name: init}
+`,
+			expected: `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  initContainers:
+  - image: proxy:1.0
+    name: init
+    resources:
+      requests:
+        cpu: 100m
+        memory: 128Mi
+      limits:
+        cpu: 500m
+        memory: 512Mi
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cfg := cfg
+			cfg.IncludeSidecars = test.includeSidecars
+			manifests := ManifestList{[]byte(test.manifest)}
+
+			expected := ManifestList{[]byte(test.expected)}
+
+			updated, err := manifests.SetDefaultResources(cfg, images)
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), updated.String())
+		})
+	}
+}
+
+func TestSetDefaultResourcesInvalid(t *testing.T) {
+	manifests := ManifestList{[]byte(podYAML)}
+
+	_, err := manifests.SetDefaultResources(v1alpha3.DefaultResources{Requests: map[string]string{"cpu": "a lot"}}, nil)
+	testutil.CheckError(t, true, err)
+
+	_, err = manifests.SetDefaultResources(v1alpha3.DefaultResources{
+		Requests: map[string]string{"memory": "1Gi"},
+		Limits:   map[string]string{"memory": "512Mi"},
+	}, nil)
+	testutil.CheckError(t, true, err)
+}
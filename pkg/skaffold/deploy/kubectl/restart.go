@@ -0,0 +1,191 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// restartableKinds are the workloads that `kubectl rollout restart` supports.
+var restartableKinds = map[string]bool{
+	"DaemonSet":   true,
+	"Deployment":  true,
+	"StatefulSet": true,
+}
+
+// changedConfigs returns the ConfigMaps and Secrets that already exist
+// in the cluster and that an apply of the manifests would modify.
+func (c *CLI) changedConfigs(ctx context.Context, manifests ManifestList) (map[Resource]bool, error) {
+	configs := manifests.Filter(func(r Resource) bool {
+		return r.Kind == "ConfigMap" || r.Kind == "Secret"
+	})
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	changes, err := c.LiveDiff(ctx, configs)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := map[Resource]bool{}
+	for _, r := range changes.Changed.Resources() {
+		resources[configRef(r.Kind, r.Namespace, r.Name)] = true
+	}
+
+	return resources, nil
+}
+
+// restartConfigConsumers restarts the rollout of the workloads that read
+// one of the changed ConfigMaps or Secrets. Workloads that were modified
+// by the apply are skipped, since they are already rolled out if needed.
+func (c *CLI) restartConfigConsumers(ctx context.Context, out io.Writer, manifests ManifestList, updated ManifestList, configs map[Resource]bool) error {
+	if len(configs) == 0 {
+		return nil
+	}
+
+	modified := map[Resource]bool{}
+	for _, r := range updated.Resources() {
+		modified[r] = true
+	}
+
+	for _, manifest := range manifests {
+		r := resourceOf(manifest)
+		if !restartableKinds[r.Kind] || modified[r] {
+			continue
+		}
+
+		refs, err := configRefs(manifest, r.Namespace)
+		if err != nil {
+			return errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		for _, ref := range refs {
+			if !configs[ref] {
+				continue
+			}
+
+			name := strings.ToLower(r.Kind) + "/" + r.Name
+			color.Default.Fprintln(out, "Restarting", name, "since", strings.ToLower(ref.Kind)+"/"+ref.Name, "changed")
+			if err := c.run(ctx, nil, out, r.Namespace, "rollout", nil, "restart", name); err != nil {
+				return errors.Wrapf(err, "restarting %s", name)
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// configRef identifies a ConfigMap or a Secret, whatever its apiVersion.
+func configRef(kind, namespace, name string) Resource {
+	return Resource{Kind: kind, Namespace: namespace, Name: name}
+}
+
+type nameRef struct {
+	Name string `yaml:"name"`
+}
+
+type containerConfigRefs struct {
+	EnvFrom []struct {
+		ConfigMapRef *nameRef `yaml:"configMapRef"`
+		SecretRef    *nameRef `yaml:"secretRef"`
+	} `yaml:"envFrom"`
+	Env []struct {
+		ValueFrom *struct {
+			ConfigMapKeyRef *nameRef `yaml:"configMapKeyRef"`
+			SecretKeyRef    *nameRef `yaml:"secretKeyRef"`
+		} `yaml:"valueFrom"`
+	} `yaml:"env"`
+}
+
+// configRefs lists the ConfigMaps and Secrets that the pod template of a
+// workload mounts as volumes or reads as environment variables.
+func configRefs(manifest []byte, namespace string) ([]Resource, error) {
+	var m struct {
+		Spec struct {
+			Template struct {
+				Spec struct {
+					Volumes []struct {
+						ConfigMap *nameRef `yaml:"configMap"`
+						Secret    *struct {
+							SecretName string `yaml:"secretName"`
+						} `yaml:"secret"`
+						Projected *struct {
+							Sources []struct {
+								ConfigMap *nameRef `yaml:"configMap"`
+								Secret    *nameRef `yaml:"secret"`
+							} `yaml:"sources"`
+						} `yaml:"projected"`
+					} `yaml:"volumes"`
+					Containers     []containerConfigRefs `yaml:"containers"`
+					InitContainers []containerConfigRefs `yaml:"initContainers"`
+				} `yaml:"spec"`
+			} `yaml:"template"`
+		} `yaml:"spec"`
+	}
+	if err := yaml.Unmarshal(manifest, &m); err != nil {
+		return nil, err
+	}
+
+	var refs []Resource
+	configMap := func(ref *nameRef) {
+		if ref != nil {
+			refs = append(refs, configRef("ConfigMap", namespace, ref.Name))
+		}
+	}
+	secret := func(ref *nameRef) {
+		if ref != nil {
+			refs = append(refs, configRef("Secret", namespace, ref.Name))
+		}
+	}
+
+	podSpec := m.Spec.Template.Spec
+	for _, v := range podSpec.Volumes {
+		configMap(v.ConfigMap)
+		if v.Secret != nil {
+			secret(&nameRef{Name: v.Secret.SecretName})
+		}
+		if v.Projected != nil {
+			for _, s := range v.Projected.Sources {
+				configMap(s.ConfigMap)
+				secret(s.Secret)
+			}
+		}
+	}
+
+	for _, c := range append(podSpec.Containers, podSpec.InitContainers...) {
+		for _, e := range c.EnvFrom {
+			configMap(e.ConfigMapRef)
+			secret(e.SecretRef)
+		}
+		for _, e := range c.Env {
+			if e.ValueFrom != nil {
+				configMap(e.ValueFrom.ConfigMapKeyRef)
+				secret(e.ValueFrom.SecretKeyRef)
+			}
+		}
+	}
+
+	return refs, nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const configDeploymentYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - image: web
+        name: web
+      volumes:
+      - configMap:
+          name: config
+        name: config
+`
+
+const secretDeploymentYAML = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - env:
+        - name: PASSWORD
+          valueFrom:
+            secretKeyRef:
+              key: password
+              name: creds
+        image: api
+        name: api
+`
+
+func TestApplyRestartsConfigConsumers(t *testing.T) {
+	fakeClusterScopedKinds("kubecontext", "Namespace")
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut(getLiveYAML, `apiVersion: v1
+kind: List
+items:
+- {apiVersion: v1, kind: ConfigMap, metadata: {namespace: ns, name: config}}
+- {apiVersion: v1, kind: Secret, metadata: {namespace: ns, name: creds}}
+`, nil),
+		testutil.NewFakeCmdOut(liveDiff, "diff -u -N /tmp/LIVE-1/v1.ConfigMap.ns.config /tmp/MERGED-2/v1.ConfigMap.ns.config\n-  debug: false\n+  debug: true\n", fmt.Errorf("exit status 1")),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext --namespace ns rollout restart deployment/web", nil),
+	)
+
+	manifests := ManifestList{
+		[]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: ns\n"),
+		[]byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\n  namespace: ns\n"),
+		[]byte(configDeploymentYAML),
+		[]byte(secretDeploymentYAML),
+	}
+
+	cli := &CLI{KubeContext: "kubecontext", Namespace: "ns", RestartOnConfigChange: true}
+	_, err := cli.Apply(context.Background(), ioutil.Discard, manifests)
+
+	testutil.CheckError(t, false, err)
+}
+
+func TestRestartSkipsModifiedWorkloads(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds()
+
+	manifests := ManifestList{[]byte(configDeploymentYAML)}
+	configs := map[Resource]bool{configRef("ConfigMap", "", "config"): true}
+
+	cli := &CLI{KubeContext: "kubecontext"}
+	err := cli.restartConfigConsumers(context.Background(), ioutil.Discard, manifests, manifests, configs)
+
+	testutil.CheckError(t, false, err)
+}
+
+func TestConfigRefs(t *testing.T) {
+	manifest := []byte(`apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  template:
+    spec:
+      initContainers:
+      - envFrom:
+        - configMapRef:
+            name: init-env
+        - secretRef:
+            name: init-secret
+        name: init
+      volumes:
+      - name: tls
+        secret:
+          secretName: tls
+      - name: all
+        projected:
+          sources:
+          - configMap:
+              name: projected-config
+          - secret:
+              name: projected-secret
+`)
+
+	refs, err := configRefs(manifest, "ns")
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []Resource{
+		configRef("Secret", "ns", "tls"),
+		configRef("ConfigMap", "ns", "projected-config"),
+		configRef("Secret", "ns", "projected-secret"),
+		configRef("ConfigMap", "ns", "init-env"),
+		configRef("Secret", "ns", "init-secret"),
+	}, refs)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/constants"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// selectorKinds are the workloads whose selector must match their pod template.
+var selectorKinds = map[string]bool{
+	"DaemonSet":   true,
+	"Deployment":  true,
+	"ReplicaSet":  true,
+	"StatefulSet": true,
+}
+
+type selectorRequirement struct {
+	Key      string   `yaml:"key"`
+	Operator string   `yaml:"operator"`
+	Values   []string `yaml:"values"`
+}
+
+// SelectorMismatches returns, for each workload whose selector doesn't match
+// the labels of its pod template, the requirements that aren't met.
+// The labels that skaffold adds to what it deploys are ignored:
+// they are never part of the pod templates. Manifests that can't be decoded are ignored.
+func (l *ManifestList) SelectorMismatches() []string {
+	var mismatches []string
+
+	for _, manifest := range *l {
+		r := resourceOf(manifest)
+		if !selectorKinds[r.Kind] {
+			continue
+		}
+
+		var m struct {
+			Spec struct {
+				Selector *struct {
+					MatchLabels      map[string]string     `yaml:"matchLabels"`
+					MatchExpressions []selectorRequirement `yaml:"matchExpressions"`
+				} `yaml:"selector"`
+				Template struct {
+					Metadata struct {
+						Labels map[string]string `yaml:"labels"`
+					} `yaml:"metadata"`
+				} `yaml:"template"`
+			} `yaml:"spec"`
+		}
+		if err := yaml.Unmarshal(manifest, &m); err != nil || m.Spec.Selector == nil {
+			continue
+		}
+
+		templateLabels := m.Spec.Template.Metadata.Labels
+
+		var unmet []string
+		for key, value := range m.Spec.Selector.MatchLabels {
+			if isSkaffoldLabel(key) {
+				continue
+			}
+			if actual, found := templateLabels[key]; !found {
+				unmet = append(unmet, fmt.Sprintf("%s=%s (the label is missing)", key, value))
+			} else if actual != value {
+				unmet = append(unmet, fmt.Sprintf("%s=%s (the template has %s=%s)", key, value, key, actual))
+			}
+		}
+		for _, req := range m.Spec.Selector.MatchExpressions {
+			if isSkaffoldLabel(req.Key) {
+				continue
+			}
+			if problem := unmetRequirement(req, templateLabels); problem != "" {
+				unmet = append(unmet, problem)
+			}
+		}
+		if len(unmet) == 0 {
+			continue
+		}
+
+		sort.Strings(unmet)
+		mismatches = append(mismatches, fmt.Sprintf("%s/%s: the selector doesn't match the pod template labels: %s", strings.ToLower(r.Kind), r.Name, strings.Join(unmet, ", ")))
+	}
+
+	return mismatches
+}
+
+func unmetRequirement(req selectorRequirement, labels map[string]string) string {
+	value, found := labels[req.Key]
+
+	switch req.Operator {
+	case "In":
+		for _, v := range req.Values {
+			if found && v == value {
+				return ""
+			}
+		}
+		return fmt.Sprintf("%s in (%s)", req.Key, strings.Join(req.Values, ","))
+	case "NotIn":
+		for _, v := range req.Values {
+			if found && v == value {
+				return fmt.Sprintf("%s notin (%s)", req.Key, strings.Join(req.Values, ","))
+			}
+		}
+		return ""
+	case "Exists":
+		if !found {
+			return fmt.Sprintf("%s (the label is missing)", req.Key)
+		}
+		return ""
+	case "DoesNotExist":
+		if found {
+			return fmt.Sprintf("!%s", req.Key)
+		}
+		return ""
+	default:
+		return fmt.Sprintf("%s has an invalid operator %q", req.Key, req.Operator)
+	}
+}
+
+// isSkaffoldLabel tells if a label is one of those that skaffold adds to the deployed resources.
+func isSkaffoldLabel(key string) bool {
+	if _, found := constants.Labels.DefaultLabels[key]; found {
+		return true
+	}
+
+	switch key {
+	case constants.Labels.TagPolicy, constants.Labels.Deployer, constants.Labels.Builder, constants.Labels.DockerAPIVersion:
+		return true
+	}
+
+	return false
+}
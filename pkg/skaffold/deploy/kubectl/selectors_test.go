@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestSelectorMismatches(t *testing.T) {
+	workload := func(kind, selector, labels string) []byte {
+		return []byte(`apiVersion: apps/v1
+kind: ` + kind + `
+metadata:
+  name: web
+spec:
+  selector:
+` + selector + `
+  template:
+    metadata:
+      labels:
+` + labels + `
+`)
+	}
+
+	var tests = []struct {
+		description string
+		manifest    []byte
+		expected    []string
+	}{
+		{
+			description: "matching labels",
+			manifest:    workload("Deployment", "    matchLabels:\n      app: web", "        app: web\n        tier: front"),
+		},
+		{
+			description: "different value",
+			manifest:    workload("Deployment", "    matchLabels:\n      app: web", "        app: webapp"),
+			expected:    []string{"deployment/web: the selector doesn't match the pod template labels: app=web (the template has app=webapp)"},
+		},
+		{
+			description: "missing label",
+			manifest:    workload("StatefulSet", "    matchLabels:\n      app: web\n      tier: front", "        app: web"),
+			expected:    []string{"statefulset/web: the selector doesn't match the pod template labels: tier=front (the label is missing)"},
+		},
+		{
+			description: "skaffold labels are ignored",
+			manifest:    workload("Deployment", "    matchLabels:\n      app: web\n      skaffold-deployer: kustomize", "        app: web"),
+		},
+		{
+			description: "match expressions",
+			manifest: workload("DaemonSet", `    matchExpressions:
+    - {key: app, operator: In, values: [web, api]}
+    - {key: tier, operator: NotIn, values: [back]}
+    - {key: track, operator: Exists}
+    - {key: canary, operator: DoesNotExist}`, "        app: api\n        tier: back\n        canary: \"true\""),
+			expected: []string{"daemonset/web: the selector doesn't match the pod template labels: !canary, tier notin (back), track (the label is missing)"},
+		},
+		{
+			description: "kinds without selector check",
+			manifest:    workload("Job", "    matchLabels:\n      app: web", "        app: other"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			manifests := ManifestList{test.manifest}
+
+			testutil.CheckDeepEqual(t, test.expected, manifests.SelectorMismatches())
+		})
+	}
+}
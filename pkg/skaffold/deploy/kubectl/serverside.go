@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// lastAppliedAnnotation is set by client-side `kubectl apply`.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// serverSideApplyArgs returns the flags to apply the given manifests server-side.
+// Resources that were previously applied client-side still carry the
+// last-applied annotation, which causes spurious conflicts with the field
+// managers. Those are reconciled once with `--force-conflicts`.
+func (c *CLI) serverSideApplyArgs(ctx context.Context, out io.Writer, manifests ManifestList) []string {
+	if !c.needsServerSideMigration(ctx, manifests) {
+		return []string{"--server-side"}
+	}
+
+	color.Default.Fprintln(out, "Resources were applied client-side: migrating to server-side apply with --force-conflicts")
+	return []string{"--server-side", "--force-conflicts"}
+}
+
+// needsServerSideMigration checks if any of the live resources carries
+// the client-side last-applied annotation.
+func (c *CLI) needsServerSideMigration(ctx context.Context, manifests ManifestList) bool {
+	live, err := c.runOut(ctx, manifests.Reader(), "", "get", nil, "--ignore-not-found=true", "-o", "yaml", "-f", "-")
+	if err != nil {
+		logrus.Debugln("Unable to get live resources, assuming no migration to server-side apply is needed:", err)
+		return false
+	}
+
+	return bytes.Contains(live, []byte(lastAppliedAnnotation))
+}
+
+// removeLastApplied removes the client-side annotation once resources are
+// migrated so that the next deploys are normal server-side applies.
+func (c *CLI) removeLastApplied(ctx context.Context, out io.Writer, manifests ManifestList) error {
+	if err := c.run(ctx, manifests.Reader(), out, "", "annotate", nil, "-f", "-", lastAppliedAnnotation+"-"); err != nil {
+		return errors.Wrap(err, "removing last-applied annotation")
+	}
+
+	return nil
+}
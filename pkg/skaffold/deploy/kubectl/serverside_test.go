@@ -0,0 +1,87 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const getLive = "kubectl --context kubecontext get --ignore-not-found=true -o yaml -f -"
+
+func TestServerSideApply(t *testing.T) {
+	var tests = []struct {
+		description string
+		command     util.Command
+	}{
+		{
+			description: "not applied client-side",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getLive, "", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply --server-side -f -", nil),
+			),
+		},
+		{
+			description: "migrate from client-side",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getLive, `metadata:
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: '{}'
+`, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply --server-side --force-conflicts -f -", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext annotate -f - kubectl.kubernetes.io/last-applied-configuration-", nil),
+			),
+		},
+		{
+			description: "unable to get live resources",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut(getLive, "", fmt.Errorf("not found")),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply --server-side -f -", nil),
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			cli := &CLI{KubeContext: "kubecontext", ServerSideApply: true}
+			_, err := cli.Apply(context.Background(), ioutil.Discard, ManifestList{[]byte(podYAML)})
+
+			testutil.CheckError(t, false, err)
+		})
+	}
+}
+
+func TestServerSideApplyFieldManager(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut(getLive, "", nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply --server-side --field-manager=skaffold -f -", nil),
+	)
+
+	cli := &CLI{KubeContext: "kubecontext", ServerSideApply: true, PruneFieldManager: "skaffold"}
+	_, err := cli.Apply(context.Background(), ioutil.Discard, ManifestList{[]byte(podYAML)})
+
+	testutil.CheckError(t, false, err)
+}
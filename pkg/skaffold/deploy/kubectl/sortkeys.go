@@ -0,0 +1,51 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SortKeys re-marshals every manifest with the keys of each mapping sorted,
+// recursively, for a stable output. Lists keep their order since it's
+// meaningful. Comments and formatting are lost.
+func (l *ManifestList) SortKeys() (ManifestList, error) {
+	var sorted ManifestList
+
+	for _, manifest := range *l {
+		// yaml.v2 marshals the keys of generic maps in sorted order.
+		var m interface{}
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		if m == nil {
+			sorted = append(sorted, manifest)
+			continue
+		}
+
+		sortedManifest, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling yaml")
+		}
+
+		sorted = append(sorted, sortedManifest)
+	}
+
+	return sorted, nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestSortKeys(t *testing.T) {
+	manifests := ManifestList{[]byte(`kind: Deployment
+metadata:
+  name: web
+  labels: {tier: front, app: web}
+apiVersion: apps/v1
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: web
+      - image: sidecar
+        name: sidecar
+`), []byte("")}
+
+	expected := ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    app: web
+    tier: front
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - image: web
+        name: web
+      - image: sidecar
+        name: sidecar
+`), []byte("")}
+
+	sorted, err := manifests.SortKeys()
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), sorted.String())
+
+	// Sorting is idempotent.
+	again, err := sorted.SortKeys()
+	testutil.CheckErrorAndDeepEqual(t, false, err, sorted, again)
+}
+
+func TestSortKeysInvalidManifest(t *testing.T) {
+	manifests := ManifestList{[]byte("INVALID: [")}
+
+	_, err := manifests.SortKeys()
+
+	testutil.CheckError(t, true, err)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// StripFields removes fields, given as dotted paths like `metadata.uid`,
+// from every manifest. The last keys of a path can themselves contain dots. This cleans up manifests exported from a live cluster.
+// The order of the other keys is preserved and manifests without those fields
+// are returned byte for byte.
+func (l *ManifestList) StripFields(fields []string) (ManifestList, error) {
+	var updated ManifestList
+
+	for _, manifest := range *l {
+		var m yaml.MapSlice
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		changed := false
+		for _, field := range fields {
+			changed = stripField(&m, strings.Split(field, ".")) || changed
+		}
+
+		if !changed {
+			updated = append(updated, manifest)
+			continue
+		}
+
+		updatedManifest, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling yaml")
+		}
+
+		updated = append(updated, updatedManifest)
+	}
+
+	return updated, nil
+}
+
+func stripField(m *yaml.MapSlice, path []string) bool {
+	// Keys can contain dots, like the `skaffold.dev/kustomization` annotation.
+	if len(path) > 1 && deleteMapSliceValue(m, strings.Join(path, ".")) {
+		return true
+	}
+
+	if len(path) == 1 {
+		return deleteMapSliceValue(m, path[0])
+	}
+
+	value, _ := mapSliceValue(*m, path[0])
+	child, ok := value.(yaml.MapSlice)
+	if !ok || !stripField(&child, path[1:]) {
+		return false
+	}
+
+	setMapSliceValue(m, path[0], child)
+	return true
+}
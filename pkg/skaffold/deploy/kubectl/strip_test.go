@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestStripFields(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  creationTimestamp: "2018-09-01T00:00:00Z"
+  name: exported
+  uid: 0b6c4c5e
+spec:
+  containers:
+  - image: example
+    name: example
+status:
+  phase: Running
+`), []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: clean
+`)}
+
+	expected := ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: exported
+spec:
+  containers:
+  - image: example
+    name: example
+`), manifests[1]}
+
+	resultManifest, err := manifests.StripFields([]string{"status", "metadata.creationTimestamp", "metadata.uid", "metadata.resourceVersion"})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
+}
+
+func TestStripDottedKeys(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: v1
+kind: Service
+metadata:
+  annotations:
+    other: kept
+    skaffold.dev/kustomization: overlays/dev
+  name: annotated
+`)}
+
+	expected := ManifestList{[]byte(`apiVersion: v1
+kind: Service
+metadata:
+  annotations:
+    other: kept
+  name: annotated
+`)}
+
+	resultManifest, err := manifests.StripFields([]string{"metadata.annotations.skaffold.dev/kustomization"})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected.String(), resultManifest.String())
+}
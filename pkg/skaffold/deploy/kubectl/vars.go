@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// varReference matches `$(VAR)`. `$$(VAR)` is an escaped reference.
+var varReference = regexp.MustCompile(`\$?\$\(([A-Za-z_][A-Za-z0-9_.-]*)\)`)
+
+// UnresolvedVar is a `$(VAR)` reference left in a rendered manifest.
+type UnresolvedVar struct {
+	Resource string
+	Field    string
+	Var      string
+}
+
+func (v UnresolvedVar) String() string {
+	return fmt.Sprintf("$(%s) in %s at %s", v.Var, v.Resource, v.Field)
+}
+
+// UnresolvedVars lists the `$(VAR)` references that kustomize didn't expand.
+// References in the command, args and env of a container to one of
+// that container's env variables are expanded by Kubernetes and are ignored.
+func (l *ManifestList) UnresolvedVars() ([]UnresolvedVar, error) {
+	var unresolved []UnresolvedVar
+
+	for _, manifest := range *l {
+		m := make(map[interface{}]interface{})
+		if err := yaml.Unmarshal(manifest, &m); err != nil {
+			return nil, errors.Wrap(err, "reading kubernetes YAML")
+		}
+
+		r := resourceOf(manifest)
+		name := strings.ToLower(r.Kind) + "/" + r.Name
+		collectUnresolvedVars(m, "", nil, func(field, v string) {
+			unresolved = append(unresolved, UnresolvedVar{Resource: name, Field: field, Var: v})
+		})
+	}
+
+	return unresolved, nil
+}
+
+func collectUnresolvedVars(value interface{}, path string, envNames map[string]bool, found func(field, v string)) {
+	switch t := value.(type) {
+	case string:
+		for _, match := range varReference.FindAllStringSubmatch(t, -1) {
+			if !strings.HasPrefix(match[0], "$$") && !envNames[match[1]] {
+				found(path, match[1])
+			}
+		}
+	case []interface{}:
+		for i, v := range t {
+			collectUnresolvedVars(v, fmt.Sprintf("%s[%d]", path, i), envNames, found)
+		}
+	case map[interface{}]interface{}:
+		values := map[string]interface{}{}
+		var keys []string
+		for k, v := range t {
+			key := fmt.Sprint(k)
+			values[key] = v
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			v := values[key]
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+
+			switch key {
+			case "containers", "initContainers", "ephemeralContainers":
+				containers, _ := v.([]interface{})
+				for i, c := range containers {
+					container, _ := c.(map[interface{}]interface{})
+					collectUnresolvedVars(c, fmt.Sprintf("%s[%d]", field, i), containerEnvNames(container), found)
+				}
+			default:
+				collectUnresolvedVars(v, field, envNames, found)
+			}
+		}
+	}
+}
+
+// containerEnvNames lists the env variables that Kubernetes expands in a container.
+func containerEnvNames(container map[interface{}]interface{}) map[string]bool {
+	names := map[string]bool{}
+
+	env, _ := container["env"].([]interface{})
+	for _, e := range env {
+		entry, _ := e.(map[interface{}]interface{})
+		if name, ok := entry["name"].(string); ok {
+			names[name] = true
+		}
+	}
+
+	return names
+}
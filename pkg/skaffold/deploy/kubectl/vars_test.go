@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestUnresolvedVars(t *testing.T) {
+	manifests := ManifestList{[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  annotations:
+    endpoint: http://$(SERVICE_NAME):8080
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: web
+        args: ["--pod=$(POD_NAME)", "--db=$(DB_HOST)", "--literal=$$(ESCAPED)"]
+        env:
+        - name: POD_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: metadata.name
+`), []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`)}
+
+	unresolved, err := manifests.UnresolvedVars()
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []UnresolvedVar{
+		{Resource: "deployment/web", Field: "metadata.annotations.endpoint", Var: "SERVICE_NAME"},
+		{Resource: "deployment/web", Field: "spec.template.spec.containers[0].args[1]", Var: "DB_HOST"},
+	}, unresolved)
+	testutil.CheckDeepEqual(t, "$(DB_HOST) in deployment/web at spec.template.spec.containers[0].args[1]", unresolved[1].String())
+}
@@ -29,11 +29,13 @@ type Replacer interface {
 }
 
 // Visit recursively visits a list of manifests and applies transformations of them.
+// The order of keys is preserved and manifests that are not transformed are kept
+// byte for byte.
 func (l *ManifestList) Visit(replacer Replacer) (ManifestList, error) {
 	var updated ManifestList
 
 	for _, manifest := range *l {
-		m := make(map[interface{}]interface{})
+		var m yaml.MapSlice
 		if err := yaml.Unmarshal(manifest, &m); err != nil {
 			return nil, errors.Wrap(err, "reading kubernetes YAML")
 		}
@@ -42,7 +44,10 @@ func (l *ManifestList) Visit(replacer Replacer) (ManifestList, error) {
 			continue
 		}
 
-		recursiveVisit(m, replacer)
+		if !recursiveVisit(m, replacer) {
+			updated = append(updated, manifest)
+			continue
+		}
 
 		updatedManifest, err := yaml.Marshal(m)
 		if err != nil {
@@ -55,25 +60,68 @@ func (l *ManifestList) Visit(replacer Replacer) (ManifestList, error) {
 	return updated, nil
 }
 
-func recursiveVisit(i interface{}, replacer Replacer) {
+// recursiveVisit returns true if a value was replaced.
+func recursiveVisit(i interface{}, replacer Replacer) bool {
+	changed := false
+
 	switch t := i.(type) {
 	case []interface{}:
 		for _, v := range t {
-			recursiveVisit(v, replacer)
+			if recursiveVisit(v, replacer) {
+				changed = true
+			}
 		}
-	case map[interface{}]interface{}:
-		for k, v := range t {
-			key := k.(string)
-
-			if !replacer.Matches(key) {
-				recursiveVisit(v, replacer)
+	case yaml.MapSlice:
+		for i, item := range t {
+			key, ok := item.Key.(string)
+			if !ok || !replacer.Matches(key) {
+				if recursiveVisit(item.Value, replacer) {
+					changed = true
+				}
 				continue
 			}
 
-			ok, newValue := replacer.NewValue(key, v)
+			ok, newValue := replacer.NewValue(key, item.Value)
 			if ok {
-				t[k] = newValue
+				t[i].Value = newValue
+				changed = true
 			}
 		}
 	}
+
+	return changed
+}
+
+// mapSliceValue returns the value of a key in a yaml.MapSlice.
+func mapSliceValue(m yaml.MapSlice, key string) (interface{}, bool) {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+// setMapSliceValue sets the value of a key in a yaml.MapSlice, keeping its
+// position if it's already present or appending it otherwise.
+func setMapSliceValue(m *yaml.MapSlice, key string, value interface{}) {
+	for i, item := range *m {
+		if item.Key == key {
+			(*m)[i].Value = value
+			return
+		}
+	}
+	*m = append(*m, yaml.MapItem{Key: key, Value: value})
+}
+
+// deleteMapSliceValue removes a key from a yaml.MapSlice, keeping the
+// order of the other keys. It returns true if the key was present.
+func deleteMapSliceValue(m *yaml.MapSlice, key string) bool {
+	for i, item := range *m {
+		if item.Key == key {
+			*m = append((*m)[:i], (*m)[i+1:]...)
+			return true
+		}
+	}
+	return false
 }
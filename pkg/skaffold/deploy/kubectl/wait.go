@@ -0,0 +1,210 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/pkg/errors"
+)
+
+// WaitForReadiness waits for the given resources to be ready:
+// workloads are waited on until they are rolled out, Jobs until
+// they complete and Pods until they are Ready. Kinds with a configured
+// condition are waited on until that condition is met.
+//
+// With a ResourceTimeout, each check is bounded by that timeout and
+// Timeout bounds the whole wait. A resource that isn't ready doesn't
+// stop the other checks: the failures are reported together.
+//
+// With ImagePullRetries, checks that fail because one of the built
+// images can't be pulled yet are retried. With WaitForClaims, the rendered
+// PersistentVolumeClaims that a resource mounts are waited on first.
+// With a Parallelism greater than one, the resources are waited on concurrently.
+func (c *CLI) WaitForReadiness(ctx context.Context, out io.Writer, manifests ManifestList, builds []build.Artifact, cfg v1alpha3.ReadinessConfig) error {
+	checks := readinessChecks(manifests, cfg)
+	if cfg.Parallelism > 1 {
+		return c.waitInParallel(ctx, out, checks, builds, cfg)
+	}
+	if cfg.ResourceTimeout != "" {
+		return c.waitForEachResource(ctx, out, checks, builds, cfg)
+	}
+
+	for _, check := range checks {
+		if check.skipped != "" {
+			color.Default.Fprintln(out, "Not waiting for", check.name+":", check.skipped)
+			continue
+		}
+
+		args := check.args
+		if cfg.Timeout != "" {
+			args = append(args, fmt.Sprintf("--timeout=%s", cfg.Timeout))
+		}
+
+		color.Default.Fprintln(out, "Waiting for", check.name, "to be ready...")
+		if err := c.runCheck(ctx, out, check, args, builds, cfg); err != nil {
+			return errors.Wrapf(err, "waiting for %s", check.name)
+		}
+	}
+
+	return nil
+}
+
+// waitForEachResource runs every readiness check, each bounded by
+// the resource timeout, and aggregates the resources that aren't ready.
+func (c *CLI) waitForEachResource(ctx context.Context, out io.Writer, checks []readinessCheck, builds []build.Artifact, cfg v1alpha3.ReadinessConfig) error {
+	if _, err := time.ParseDuration(cfg.ResourceTimeout); err != nil {
+		return errors.Wrapf(err, "parsing resource timeout %q", cfg.ResourceTimeout)
+	}
+
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return errors.Wrapf(err, "parsing readiness timeout %q", cfg.Timeout)
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	total := 0
+	for _, check := range checks {
+		if check.skipped == "" {
+			total++
+		}
+	}
+
+	var failures []string
+	current := 0
+	for _, check := range checks {
+		if check.skipped != "" {
+			color.Default.Fprintln(out, "Not waiting for", check.name+":", check.skipped)
+			continue
+		}
+		current++
+
+		if ctx.Err() != nil {
+			failures = append(failures, fmt.Sprintf("%s: not checked, readiness timeout of %s exceeded", check.name, cfg.Timeout))
+			continue
+		}
+
+		args := append(check.args, fmt.Sprintf("--timeout=%s", cfg.ResourceTimeout))
+
+		color.Default.Fprintf(out, "Waiting for %s to be ready (%d/%d)...\n", check.name, current, total)
+		if err := c.runCheck(ctx, out, check, args, builds, cfg); err != nil {
+			color.Yellow.Fprintln(out, check.name, "is not ready:", err)
+			failures = append(failures, fmt.Sprintf("%s: %v", check.name, err))
+		}
+	}
+
+	return notReadyError(failures, total)
+}
+
+// notReadyError aggregates the resources that aren't ready, if any.
+func notReadyError(failures []string, total int) error {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%d of %d resources are not ready:\n - %s", len(failures), total, strings.Join(failures, "\n - "))
+}
+
+// readinessCheck is how a resource is waited on. Resources that aren't
+// waited on have the reason why in skipped.
+type readinessCheck struct {
+	manifest  []byte
+	name      string
+	namespace string
+	command   string
+	args      []string
+	skipped   string
+	claims    []string
+}
+
+func readinessChecks(manifests ManifestList, cfg v1alpha3.ReadinessConfig) []readinessCheck {
+	var claims map[Resource]bool
+	if cfg.WaitForClaims {
+		claims = claimsOf(manifests)
+	}
+
+	var checks []readinessCheck
+	for _, manifest := range manifests {
+		r := resourceOf(manifest)
+		if r.Kind == "" || r.Name == "" {
+			continue
+		}
+
+		check := readinessCheck{
+			manifest:  manifest,
+			name:      strings.ToLower(r.Kind) + "/" + r.Name,
+			namespace: r.Namespace,
+		}
+
+		if skipReadiness(r.Kind, cfg.SkipKinds) {
+			check.skipped = "kind is configured to be skipped"
+		} else if condition, found := waitCondition(r.Kind, cfg.Conditions); found {
+			check.command, check.args = "wait", []string{"--for=condition=" + condition, check.name}
+		} else {
+			switch r.Kind {
+			case "Deployment", "StatefulSet", "DaemonSet":
+				check.command, check.args = "rollout", []string{"status", check.name}
+			case "Job":
+				check.command, check.args = "wait", []string{"--for=condition=complete", check.name}
+			case "Pod":
+				check.command, check.args = "wait", []string{"--for=condition=Ready", check.name}
+			default:
+				check.skipped = "no readiness check for kind " + r.Kind
+			}
+		}
+
+		if cfg.WaitForClaims && check.skipped == "" {
+			check.claims = renderedClaims(claims, manifest)
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+func skipReadiness(kind string, skipKinds []string) bool {
+	for _, skipKind := range skipKinds {
+		if strings.EqualFold(kind, skipKind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func waitCondition(kind string, conditions map[string]string) (string, bool) {
+	for k, condition := range conditions {
+		if strings.EqualFold(kind, k) {
+			return condition, true
+		}
+	}
+
+	return "", false
+}
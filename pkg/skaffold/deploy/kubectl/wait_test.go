@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+var waitManifests = ManifestList{
+	[]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: ns\n"),
+	[]byte("apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n"),
+	[]byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: debug\n"),
+	[]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"),
+}
+
+func TestWaitForReadiness(t *testing.T) {
+	var tests = []struct {
+		description string
+		cfg         v1alpha3.ReadinessConfig
+		command     util.Command
+		expectedOut string
+		shouldErr   bool
+	}{
+		{
+			description: "wait for all kinds",
+			cfg:         v1alpha3.ReadinessConfig{Timeout: "1m"},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd("kubectl --context kubecontext --namespace ns rollout status deployment/web --timeout=1m", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=complete job/migrate --timeout=1m", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=Ready pod/debug --timeout=1m", nil),
+			),
+			expectedOut: "Waiting for deployment/web to be ready...\n" +
+				"Waiting for job/migrate to be ready...\n" +
+				"Waiting for pod/debug to be ready...\n" +
+				"Not waiting for configmap/config: no readiness check for kind ConfigMap\n",
+		},
+		{
+			description: "skip kinds",
+			cfg:         v1alpha3.ReadinessConfig{SkipKinds: []string{"job", "Pod"}},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd("kubectl --context kubecontext --namespace ns rollout status deployment/web", nil),
+			),
+			expectedOut: "Waiting for deployment/web to be ready...\n" +
+				"Not waiting for job/migrate: kind is configured to be skipped\n" +
+				"Not waiting for pod/debug: kind is configured to be skipped\n" +
+				"Not waiting for configmap/config: no readiness check for kind ConfigMap\n",
+		},
+		{
+			description: "rollout error",
+			cfg:         v1alpha3.ReadinessConfig{SkipKinds: []string{"Job", "Pod"}},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd("kubectl --context kubecontext --namespace ns rollout status deployment/web", fmt.Errorf("timeout")),
+			),
+			expectedOut: "Waiting for deployment/web to be ready...\n",
+			shouldErr:   true,
+		},
+		{
+			description: "per resource timeout",
+			cfg:         v1alpha3.ReadinessConfig{Timeout: "5m", ResourceTimeout: "1m", SkipKinds: []string{"Pod"}},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd("kubectl --context kubecontext --namespace ns rollout status deployment/web --timeout=1m", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=complete job/migrate --timeout=1m", nil),
+			),
+			expectedOut: "Waiting for deployment/web to be ready (1/2)...\n" +
+				"Waiting for job/migrate to be ready (2/2)...\n" +
+				"Not waiting for pod/debug: kind is configured to be skipped\n" +
+				"Not waiting for configmap/config: no readiness check for kind ConfigMap\n",
+		},
+		{
+			description: "per resource timeout keeps checking",
+			cfg:         v1alpha3.ReadinessConfig{ResourceTimeout: "1m"},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmd("kubectl --context kubecontext --namespace ns rollout status deployment/web --timeout=1m", fmt.Errorf("timeout")),
+				testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=complete job/migrate --timeout=1m", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=Ready pod/debug --timeout=1m", fmt.Errorf("timeout")),
+			),
+			expectedOut: "Waiting for deployment/web to be ready (1/3)...\n" +
+				"deployment/web is not ready: timeout\n" +
+				"Waiting for job/migrate to be ready (2/3)...\n" +
+				"Waiting for pod/debug to be ready (3/3)...\n" +
+				"pod/debug is not ready: timeout\n" +
+				"Not waiting for configmap/config: no readiness check for kind ConfigMap\n",
+			shouldErr: true,
+		},
+		{
+			description: "invalid resource timeout",
+			cfg:         v1alpha3.ReadinessConfig{ResourceTimeout: "soon"},
+			command:     testutil.NewFakeCmds(),
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			var out bytes.Buffer
+			cli := &CLI{KubeContext: "kubecontext"}
+			err := cli.WaitForReadiness(context.Background(), &out, waitManifests, nil, test.cfg)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expectedOut, out.String())
+		})
+	}
+}
+
+func TestWaitForEachResourceFailures(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmd("kubectl --context kubecontext --namespace ns rollout status deployment/web --timeout=1m", fmt.Errorf("timeout")),
+		testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=complete job/migrate --timeout=1m", fmt.Errorf("timeout")),
+	)
+
+	cfg := v1alpha3.ReadinessConfig{ResourceTimeout: "1m", SkipKinds: []string{"Pod"}}
+
+	var out bytes.Buffer
+	cli := &CLI{KubeContext: "kubecontext"}
+	err := cli.WaitForReadiness(context.Background(), &out, waitManifests, nil, cfg)
+
+	testutil.CheckError(t, true, err)
+	testutil.CheckDeepEqual(t, "2 of 2 resources are not ready:\n - deployment/web: timeout\n - job/migrate: timeout", err.Error())
+}
+
+func TestWaitForConditions(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=Ready crontab/my-crontab", nil)
+
+	manifests := ManifestList{
+		[]byte(crYAML),
+		[]byte("apiVersion: example.com/v1\nkind: Database\nmetadata:\n  name: db\n"),
+	}
+	cfg := v1alpha3.ReadinessConfig{Conditions: map[string]string{"crontab": "Ready"}}
+
+	var out bytes.Buffer
+	cli := &CLI{KubeContext: "kubecontext"}
+	err := cli.WaitForReadiness(context.Background(), &out, manifests, nil, cfg)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Waiting for crontab/my-crontab to be ready...\n"+
+		"Not waiting for database/db: no readiness check for kind Database\n", out.String())
+}
@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
 	"github.com/GoogleContainerTools/skaffold/testutil"
@@ -88,7 +89,7 @@ func TestKubectlDeploy(t *testing.T) {
 			cfg: &v1alpha3.KubectlDeploy{
 				Manifests: []string{"deployment.yaml"},
 			},
-			command: testutil.NewFakeCmd("kubectl --context kubecontext --namespace testNamespace apply -f -", nil),
+			command: testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
 			builds: []build.Artifact{
 				{
 					ImageName: "leeroy-web",
@@ -102,7 +103,7 @@ func TestKubectlDeploy(t *testing.T) {
 			cfg: &v1alpha3.KubectlDeploy{
 				Manifests: []string{"deployment.yaml"},
 			},
-			command: testutil.NewFakeCmd("kubectl --context kubecontext --namespace testNamespace apply -f -", fmt.Errorf("")),
+			command: testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", fmt.Errorf("")),
 			builds: []build.Artifact{
 				{
 					ImageName: "leeroy-web",
@@ -121,7 +122,7 @@ func TestKubectlDeploy(t *testing.T) {
 					Delete: []string{"ignored"},
 				},
 			},
-			command: testutil.NewFakeCmd("kubectl --context kubecontext --namespace testNamespace -v=0 apply -f -", fmt.Errorf("")),
+			command: testutil.NewFakeCmd("kubectl --context kubecontext -v=0 apply -f -", fmt.Errorf("")),
 			builds: []build.Artifact{
 				{
 					ImageName: "leeroy-web",
@@ -163,14 +164,14 @@ func TestKubectlCleanup(t *testing.T) {
 			cfg: &v1alpha3.KubectlDeploy{
 				Manifests: []string{"deployment.yaml"},
 			},
-			command: testutil.NewFakeCmd("kubectl --context kubecontext --namespace testNamespace delete --ignore-not-found=true -f -", nil),
+			command: testutil.NewFakeCmd("kubectl --context kubecontext delete --ignore-not-found=true -f -", nil),
 		},
 		{
 			description: "cleanup error",
 			cfg: &v1alpha3.KubectlDeploy{
 				Manifests: []string{"deployment.yaml"},
 			},
-			command:   testutil.NewFakeCmd("kubectl --context kubecontext --namespace testNamespace delete --ignore-not-found=true -f -", errors.New("BUG")),
+			command:   testutil.NewFakeCmd("kubectl --context kubecontext delete --ignore-not-found=true -f -", errors.New("BUG")),
 			shouldErr: true,
 		},
 		{
@@ -183,7 +184,7 @@ func TestKubectlCleanup(t *testing.T) {
 					Delete: []string{"--grace-period=1"},
 				},
 			},
-			command: testutil.NewFakeCmd("kubectl --context kubecontext --namespace testNamespace -v=0 delete --grace-period=1 --ignore-not-found=true -f -", nil),
+			command: testutil.NewFakeCmd("kubectl --context kubecontext -v=0 delete --grace-period=1 --ignore-not-found=true -f -", nil),
 		},
 	}
 
@@ -209,7 +210,7 @@ func TestKubectlCleanup(t *testing.T) {
 
 func TestKubectlRedeploy(t *testing.T) {
 	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
-	util.DefaultExecCommand = testutil.NewFakeCmd("kubectl --context kubecontext --namespace testNamespace apply -f -", nil)
+	util.DefaultExecCommand = testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil)
 
 	tmpDir, cleanup := testutil.NewTempDir(t)
 	defer cleanup()
@@ -242,3 +243,17 @@ func TestKubectlRedeploy(t *testing.T) {
 	})
 	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(deployed))
 }
+
+func TestParseManifestsForDeploys(t *testing.T) {
+	manifests := kubectl.ManifestList{
+		[]byte(deploymentWebYAML),
+		[]byte("apiVersion: v1\nkind: Pod\nmetadata: [malformed"),
+		[]byte(deploymentAppYaml + "\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: leeroy-app\n"),
+	}
+
+	deployed, err := parseManifestsForDeploys(manifests, false)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 3, len(deployed))
+
+	_, err = parseManifestsForDeploys(manifests, true)
+	testutil.CheckError(t, true, err)
+}
@@ -17,132 +17,1648 @@ limitations under the License.
 package deploy
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/constants"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	defaultHistoryConfigMap = "skaffold-history"
+	defaultHistoryLimit     = 10
+	defaultLeaseDuration    = time.Minute
+)
+
+// When the skaffold labels are set on the resources.
+const (
+	// LabelsAfterDeploy labels the deployed resources, after the post-deploy hook.
+	LabelsAfterDeploy = "afterDeploy"
+	// LabelsBeforeImages sets the labels in the manifests, before the images are replaced.
+	LabelsBeforeImages = "beforeImages"
+	// LabelsAfterTransforms sets the labels in the manifests, after every other transform.
+	LabelsAfterTransforms = "afterTransforms"
 )
 
 type KustomizeDeployer struct {
 	*v1alpha3.KustomizeDeploy
 
-	kubectl kubectl.CLI
+	workingDir      string
+	kustomizePath   string
+	overlaySelector string
+	selector        string
+	imageOverrides  []string
+	cacheDir        string
+	refreshCache    bool
+	devMode         bool
+	namespaceFlag   string
+	kubectl         kubectl.CLI
+	crdsKubectl     kubectl.CLI
+	metrics         MetricsSink
+	cache           *renderCache
+	contextChecked  bool
+	injectedLabels  map[string]string
+	deployLabels    map[string]string
 }
 
-func NewKustomizeDeployer(cfg *v1alpha3.KustomizeDeploy, kubeContext string, namespace string) *KustomizeDeployer {
-	return &KustomizeDeployer{
+// NewKustomizeDeployer returns a new KustomizeDeployer. A relative kustomizePath
+// is resolved against workingDir, the folder containing the skaffold configuration.
+func NewKustomizeDeployer(workingDir string, cfg *v1alpha3.KustomizeDeploy, kubeContext string, opts *config.SkaffoldOptions) *KustomizeDeployer {
+	k := &KustomizeDeployer{
 		KustomizeDeploy: cfg,
+		workingDir:      workingDir,
+		kustomizePath:   resolveKustomizePath(workingDir, cfg.KustomizePath),
+		overlaySelector: opts.OverlaySelector,
+		selector:        opts.Selector,
+		imageOverrides:  opts.ImageOverrides,
+		cacheDir:        resolvePath(workingDir, cfg.CacheDir),
+		refreshCache:    opts.RefreshKustomizeCache,
+		devMode:         opts.DevMode,
+		namespaceFlag:   opts.Namespace,
 		kubectl: kubectl.CLI{
-			Namespace:   namespace,
+			Namespace:   opts.Namespace,
 			KubeContext: kubeContext,
 			Flags:       cfg.Flags,
+
+			DeleteGracePeriodSeconds: cfg.DeleteGracePeriodSeconds,
+			ForceDelete:              cfg.ForceDelete,
+			DeleteNamespaces:         cfg.DeleteNamespaces,
+			DeletePropagationPolicy:  cfg.DeletePropagationPolicy,
+			DryRun:                   opts.DryRun,
+			Validation:               cfg.Validation,
+			ServerSideApply:          cfg.ServerSideApply,
+			CRDApply:                 cfg.CRDApply,
+			NoOverwriteKinds:         cfg.NoOverwriteKinds,
+			ApplyBatchSize:           cfg.ApplyBatchSize,
+			ServerDryRun:             cfg.ServerDryRun,
+			ApplyByNamespace:         cfg.ApplyByNamespace,
+			ApplyRateLimit:           cfg.ApplyRateLimit,
+			RestartOnConfigChange:    cfg.RestartOnConfigChange,
+			Adopt:                    cfg.Adopt,
+			ApplyOutput:              applyOutputFormat(cfg.ApplyOutput),
+			ForceApply:               cfg.ForceApply,
+			ApplyCascade:             cfg.ApplyCascade,
+			ApplyLogFormat:           cfg.ApplyLogFormat,
+			RecreateImmutable:        cfg.RecreateImmutable,
+			ServerSideFallback:       cfg.ServerSideFallback,
+			PruneFieldManager:        cfg.PruneFieldManager,
 		},
+		metrics: noopMetricsSink{},
+		cache:   &renderCache{},
+	}
+
+	// CRDs are applied separately, so that they are not part of the diff of the app manifests.
+	k.crdsKubectl = kubectl.CLI{
+		Namespace:   opts.Namespace,
+		KubeContext: kubeContext,
+		Flags:       cfg.Flags,
+		DryRun:      opts.DryRun,
+	}
+
+	if cfg.DriftCheck {
+		k.kubectl.DriftSelector = labels.SelectorFromSet(k.Labels())
+	}
+	if cfg.History != nil {
+		k.kubectl.HistoryConfigMap = cfg.History.Name
+		if k.kubectl.HistoryConfigMap == "" {
+			k.kubectl.HistoryConfigMap = defaultHistoryConfigMap
+		}
+	}
+
+	return k
+}
+
+// AppliedObjects returns the objects returned by the API server during the last
+// deploy, when the apply output is captured.
+func (k *KustomizeDeployer) AppliedObjects() []map[string]interface{} {
+	return k.kubectl.AppliedObjects()
+}
+
+// SetMetricsSink sets the sink that records the durations of each deploy phase.
+func (k *KustomizeDeployer) SetMetricsSink(sink MetricsSink) {
+	k.metrics = sink
+}
+
+// OnApplyEvent sets the handler that is called for every resource reported by `kubectl apply`.
+func (k *KustomizeDeployer) OnApplyEvent(handler func(kubectl.ApplyEvent)) {
+	k.kubectl.OnApplyEvent = handler
+}
+
+// ExportConfig marshals the effective kustomize configuration.
+func (k *KustomizeDeployer) ExportConfig() ([]byte, error) {
+	return yaml.Marshal(v1alpha3.DeployType{KustomizeDeploy: k.KustomizeDeploy})
+}
+
+// injectLabels keeps the labels to set in the rendered manifests, unless
+// the resources are labelled after the deploy.
+func (k *KustomizeDeployer) injectLabels(labels map[string]string) bool {
+	k.deployLabels = labels
+	if k.LabelInjection == "" || k.LabelInjection == LabelsAfterDeploy {
+		return false
+	}
+
+	k.injectedLabels = map[string]string{}
+	for key, value := range constants.Labels.DefaultLabels {
+		k.injectedLabels[key] = value
+	}
+	for key, value := range labels {
+		k.injectedLabels[key] = value
 	}
+	return true
+}
+
+// Namespaces returns the namespace that the manifests are applied to. A
+// namespace template is only resolved on deploy: those deployers are assumed
+// to target the default namespace.
+func (k *KustomizeDeployer) Namespaces() []string {
+	return []string{k.kubectl.Namespace}
 }
 
 func (k *KustomizeDeployer) Labels() map[string]string {
 	return map[string]string{
-		constants.Labels.Deployer: "kustomize",
+		constants.Labels.Deployer:      "kustomize",
+		constants.Labels.Kustomization: kustomizationID(k.kustomizePath),
+	}
+}
+
+// leftoversSelector selects the resources labelled by the previous deploys of
+// this kustomization, and not those of other kustomizations of the namespace.
+func (k *KustomizeDeployer) leftoversSelector() string {
+	return labels.SelectorFromSet(k.Labels()).String()
+}
+
+// kustomizationID identifies a kustomization by a short hash of its absolute
+// path, or of its url for a remote kustomization, that fits in a label value.
+func kustomizationID(path string) string {
+	if !isRemoteKustomization(path) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
 	}
+
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:])[:16]
 }
 
 func (k *KustomizeDeployer) Deploy(ctx context.Context, out io.Writer, builds []build.Artifact) ([]Artifact, error) {
-	manifests, err := k.readManifests(ctx)
+	if k.DeployTimeout == "" {
+		return k.deploy(ctx, out, builds)
+	}
+
+	timeout, err := time.ParseDuration(k.DeployTimeout)
 	if err != nil {
-		return nil, errors.Wrap(err, "reading manifests")
+		return nil, errors.Wrap(err, "parsing deploy timeout")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The error tells which phase was in progress.
+	deployed, err := k.deploy(ctx, out, builds)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return nil, errors.Wrapf(err, "deploy timed out after %s", timeout)
+	}
+
+	return deployed, err
+}
+
+func (k *KustomizeDeployer) deploy(ctx context.Context, out io.Writer, builds []build.Artifact) ([]Artifact, error) {
+	if err := k.checkKubeContext(); err != nil {
+		return nil, err
+	}
+
+	if err := k.resolveNamespace(ctx); err != nil {
+		return nil, err
+	}
+
+	// The lock is a Lease in the deploy namespace.
+	if k.DeployLock != nil {
+		if err := k.createNamespace(ctx, out); err != nil {
+			return nil, err
+		}
+
+		lockedCtx, release, err := k.acquireLock(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "acquiring deploy lock")
+		}
+		defer release()
+		ctx = lockedCtx
 	}
 
+	replay := k.Bundle != nil && k.Bundle.Replay != ""
+
+	var manifests kubectl.ManifestList
+	var err error
+	if replay {
+		manifests, builds, err = k.replayBundle(out, resolvePath(k.workingDir, k.Bundle.Replay))
+	} else {
+		manifests, builds, err = k.prepare(ctx, out, builds)
+	}
+	if err != nil {
+		return nil, err
+	}
 	if len(manifests) == 0 {
 		return nil, nil
 	}
 
-	manifests, err = manifests.ReplaceImages(builds)
+	// What was applied before tells nothing about another target.
+	target := k.deployTarget()
+	if k.cache.retargeted(target) {
+		k.kubectl.ForgetApplied()
+	}
+
+	// An input can change without changing what kustomize builds.
+	if !replay && k.cache.unchangedSinceDeploy(builds, target) {
+		if !k.ReapplyUnchanged {
+			logrus.Debugln("kustomize output unchanged, skipping apply.")
+			return nil, nil
+		}
+		logrus.Debugln("kustomize output unchanged, applying it again.")
+		k.kubectl.ForgetApplied()
+	}
+
+	if k.DeployLock == nil {
+		if err := k.createNamespace(ctx, out); err != nil {
+			return nil, err
+		}
+	}
+
+	if k.Bundle != nil && k.Bundle.Record != "" {
+		if err := k.recordBundle(resolvePath(k.workingDir, k.Bundle.Record), manifests, builds); err != nil {
+			return nil, errors.Wrap(err, "recording deploy bundle")
+		}
+	}
+
+	// The CRDs are not part of a bundle.
+	if k.CRDsPath != "" && !replay {
+		if err := k.applyCRDs(ctx, out); err != nil {
+			return nil, errors.Wrap(err, "applying CRDs")
+		}
+	}
+
+	budget := &retryBudget{remaining: k.RetryBudget}
+
+	start := time.Now()
+	var updated kubectl.ManifestList
+	if err := budget.run(ctx, out, "apply", func() error {
+		updated, err = k.kubectl.Apply(ctx, out, manifests)
+		return err
+	}); err != nil {
+		return nil, errors.Wrap(err, "apply")
+	}
+	k.observeDuration(MetricApply, start)
+	if !replay {
+		k.cache.deployed(builds, target)
+	}
+
+	if k.Prune {
+		selector := k.leftoversSelector()
+		if err := k.kubectl.DeleteLeftovers(ctx, out, selector, manifests); err != nil {
+			return nil, errors.Wrap(err, "pruning resources left by previous deploys")
+		}
+	}
+
+	if k.ApplyOutput != nil && k.ApplyOutput.File != "" && len(updated) > 0 {
+		if err := writeAppliedObjects(resolvePath(k.workingDir, k.ApplyOutput.File), k.kubectl.AppliedObjects()); err != nil {
+			return nil, errors.Wrap(err, "writing apply output")
+		}
+	}
+
+	if k.WaitForReadiness != nil {
+		if err := budget.run(ctx, out, "readiness", func() error {
+			return k.kubectl.WaitForReadiness(ctx, out, updated, builds, *k.WaitForReadiness)
+		}); err != nil {
+			return nil, errors.Wrap(err, "waiting for readiness")
+		}
+	}
+
+	if k.ReportImageDigests {
+		reportPulledImages(out, k.kubectl.PulledImages(ctx, updated, builds))
+	}
+
+	if k.PostDeploy != nil {
+		if err := runPostDeployHook(ctx, out, k.PostDeploy, builds, updated); err != nil {
+			return nil, errors.Wrap(err, "post-deploy")
+		}
+	}
+
+	if k.History != nil && len(updated) > 0 {
+		k.recordHistory(ctx, manifests, builds)
+	}
+
+	return parseManifestsForDeploys(updated, k.StrictParsing)
+}
+
+// deployTarget describes where and how the manifests are applied.
+func (k *KustomizeDeployer) deployTarget() deployTarget {
+	return deployTarget{
+		kubeContext: k.kubectl.KubeContext,
+		namespace:   k.kubectl.Namespace,
+		labels:      k.deployLabels,
+		forceApply:  k.kubectl.ForceApply,
+	}
+}
+
+// selectKinds only keeps the resources of the given kinds, whatever their case.
+func selectKinds(out io.Writer, manifests kubectl.ManifestList, kinds []string) kubectl.ManifestList {
+	selected := manifests.Filter(func(r kubectl.Resource) bool {
+		for _, kind := range kinds {
+			if strings.EqualFold(r.Kind, kind) {
+				return true
+			}
+		}
+		return false
+	})
+
+	if skipped := len(manifests) - len(selected); skipped > 0 {
+		color.Default.Fprintf(out, "Skipping %d resources whose kind is not one of %s\n", skipped, strings.Join(kinds, ", "))
+	}
+
+	return selected
+}
+
+// prepare renders the manifests and readies them to be applied.
+func (k *KustomizeDeployer) prepare(ctx context.Context, out io.Writer, builds []build.Artifact) (kubectl.ManifestList, []build.Artifact, error) {
+	manifests, builds, err := k.render(ctx, out, builds)
+	if err != nil || len(manifests) == 0 {
+		return nil, nil, err
+	}
+
+	if k.LocalCluster != "" {
+		manifests, err = k.loadImagesIntoLocalCluster(ctx, out, builds, manifests)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "loading images into local cluster")
+		}
+	}
+
+	if k.Lint != nil {
+		warnings, err := manifests.Lint(k.Lint.Disable)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "linting manifests")
+		}
+		for _, warning := range warnings {
+			color.Yellow.Fprintln(out, "Warning:", warning)
+		}
+	}
+
+	return manifests, builds, nil
+}
+
+// Plan renders the manifests as Deploy would and compares them to the
+// live resources. Nothing is changed in the cluster.
+func (k *KustomizeDeployer) Plan(ctx context.Context, out io.Writer, builds []build.Artifact) (*Plan, error) {
+	if err := k.checkKubeContext(); err != nil {
+		return nil, err
+	}
+
+	if err := k.resolveNamespace(ctx); err != nil {
+		return nil, err
+	}
+
+	manifests, builds, err := k.render(ctx, out, builds)
+	if err != nil {
+		return nil, err
+	}
+
+	if k.LocalCluster != "" {
+		manifests, err = manifests.SetImagePullPolicy("IfNotPresent")
+		if err != nil {
+			return nil, errors.Wrap(err, "setting image pull policy")
+		}
+	}
+
+	plan := &Plan{}
+
+	images := map[string]bool{}
+	for _, image := range manifests.GetImages() {
+		images[image] = true
+	}
+	for _, b := range builds {
+		if images[b.Tag] {
+			plan.Images = append(plan.Images, ImageSubstitution{Image: b.ImageName, Tag: b.Tag})
+		}
+	}
+
+	if k.Lint != nil {
+		plan.Warnings, err = manifests.Lint(k.Lint.Disable)
+		if err != nil {
+			return nil, errors.Wrap(err, "linting manifests")
+		}
+	}
+
+	changes, err := k.kubectl.LiveDiff(ctx, manifests)
 	if err != nil {
-		return nil, errors.Wrap(err, "replacing images in manifests")
+		return nil, errors.Wrap(err, "comparing to live resources")
 	}
+	plan.Resources = append(plan.Resources, plannedResources(ActionAdd, changes.Added)...)
+	plan.Resources = append(plan.Resources, plannedResources(ActionChange, changes.Changed)...)
+	plan.Resources = append(plan.Resources, plannedResources(ActionUnchanged, changes.Unchanged)...)
+	plan.Resources = append(plan.Resources, plannedResources(ActionSkip, changes.Skipped)...)
 
-	updated, err := k.kubectl.Apply(ctx, out, manifests)
+	selector := k.leftoversSelector()
+	leftovers, err := k.kubectl.Leftovers(ctx, selector, manifests)
 	if err != nil {
-		return nil, errors.Wrap(err, "apply")
+		return nil, err
+	}
+	for _, name := range leftovers {
+		parts := strings.SplitN(name, "/", 2)
+		plan.Resources = append(plan.Resources, PlannedResource{
+			Action:    ActionRemove,
+			Kind:      parts[0],
+			Namespace: k.kubectl.Namespace,
+			Name:      parts[len(parts)-1],
+		})
 	}
 
-	return parseManifestsForDeploys(updated)
+	return plan, nil
 }
 
-func (k *KustomizeDeployer) Cleanup(ctx context.Context, out io.Writer) error {
+// render reads the manifests and transforms them as configured, up to the
+// point where they would be applied. It returns the builds with their
+// image names mapped. Nothing is changed in the cluster.
+func (k *KustomizeDeployer) render(ctx context.Context, out io.Writer, builds []build.Artifact) (kubectl.ManifestList, []build.Artifact, error) {
+	if k.CheckKustomizations {
+		k.checkKustomizations(out)
+	}
+
+	start := time.Now()
 	manifests, err := k.readManifests(ctx)
 	if err != nil {
-		return errors.Wrap(err, "reading manifests")
+		return nil, nil, errors.Wrap(err, "reading manifests")
 	}
+	k.observeDuration(MetricReadManifests, start)
 
-	if err := k.kubectl.Delete(ctx, out, manifests); err != nil {
-		return errors.Wrap(err, "delete")
+	if k.selector != "" {
+		selector, err := labels.Parse(k.selector)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "parsing selector %s", k.selector)
+		}
+		manifests = manifests.SelectByLabels(selector)
 	}
 
-	return nil
-}
+	if len(k.ApplyKinds) > 0 {
+		manifests = selectKinds(out, manifests, k.ApplyKinds)
+	}
 
-func dependenciesForKustomization(dir string) ([]string, error) {
-	path := filepath.Join(dir, "kustomization.yaml")
-	deps := []string{path}
+	if k.SkipUnsupportedAPIs {
+		manifests, err = k.kubectl.SkipUnsupportedAPIs(ctx, out, manifests)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "checking cluster capabilities")
+		}
+	}
 
-	file, err := os.Open(path)
+	if len(manifests) == 0 {
+		return nil, builds, nil
+	}
+
+	if k.UnresolvedVars != "" {
+		if err := checkUnresolvedVars(out, k.UnresolvedVars, manifests); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch k.LabelInjection {
+	case "", LabelsAfterDeploy, LabelsBeforeImages, LabelsAfterTransforms:
+	default:
+		return nil, nil, fmt.Errorf("invalid label injection %q: should be one of %s, %s or %s", k.LabelInjection, LabelsAfterDeploy, LabelsBeforeImages, LabelsAfterTransforms)
+	}
+
+	if k.ValidateSelectors {
+		if mismatches := manifests.SelectorMismatches(); len(mismatches) > 0 {
+			return nil, nil, fmt.Errorf("%d workloads have a selector that doesn't match their pod template:\n - %s", len(mismatches), strings.Join(mismatches, "\n - "))
+		}
+	}
+
+	if k.LabelInjection == LabelsBeforeImages && k.injectedLabels != nil {
+		manifests, err = manifests.SetLabels(k.injectedLabels)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "setting labels")
+		}
+	}
+
+	builds, err = kubectl.MapImageNames(builds, k.ImageNames)
 	if err != nil {
-		return deps, err
+		return nil, nil, errors.Wrap(err, "mapping image names")
 	}
-	defer file.Close()
 
-	contents := struct {
-		Bases     []string `yaml:"bases"`
-		Resources []string `yaml:"resources"`
-		Patches   []string `yaml:"patches"`
-	}{}
-	decoder := yaml.NewDecoder(file)
-	err = decoder.Decode(&contents)
+	builds, err = overrideImages(builds, k.imageOverrides)
 	if err != nil {
-		return deps, err
+		return nil, nil, errors.Wrap(err, "overriding images")
 	}
 
-	for _, base := range contents.Bases {
-		baseDeps, err := dependenciesForKustomization(filepath.Join(dir, base))
-		deps = append(deps, baseDeps...)
+	if len(builds) == 0 {
+		if err := k.checkEmptyBuilds(out); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var lock *kubectl.ImageLock
+	if k.ImageLock != nil {
+		lock, err = kubectl.ReadImageLock(resolvePath(k.workingDir, k.ImageLock.File))
 		if err != nil {
-			return deps, err
+			return nil, nil, errors.Wrap(err, "reading image lock")
+		}
+		lock.Strict = k.ImageLock.Strict
+	}
+
+	var mirrors *kubectl.RegistryMirrors
+	if k.RegistryMirrors != nil {
+		mirrors, err = kubectl.NewRegistryMirrors(k.RegistryMirrors.Rewrites, k.RegistryMirrors.Write)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "reading registry mirrors")
 		}
 	}
 
-	for _, resource := range contents.Resources {
-		deps = append(deps, filepath.Join(dir, resource))
+	start = time.Now()
+	manifests, err = manifests.ReplaceImages(builds, kubectl.ReplaceOptions{Lock: lock, Mirrors: mirrors, Match: k.ImageMatch})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "replacing images in manifests")
 	}
+	k.observeDuration(MetricReplaceImages, start)
 
-	for _, patch := range contents.Patches {
-		deps = append(deps, filepath.Join(dir, patch))
+	// From now on, the built images are referred to as they are written in the manifests.
+	builds = mirrors.WrittenBuilds(builds)
+
+	if k.UnqualifiedImages != "" {
+		if err := checkUnqualifiedImages(out, k.UnqualifiedImages, manifests); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return deps, nil
+	if k.ImageCheck != nil {
+		if err := checkImagesExist(k.ImageCheck, builds); err != nil {
+			return nil, nil, errors.Wrap(err, "checking images")
+		}
+	}
+
+	if len(k.StripFields) > 0 {
+		manifests, err = manifests.StripFields(k.StripFields)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "stripping fields")
+		}
+	}
+
+	if k.DevProbes != nil && k.devMode {
+		images := map[string]bool{}
+		for _, b := range builds {
+			images[b.Tag] = true
+		}
+
+		manifests, err = manifests.OverrideProbes(*k.DevProbes, images)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "overriding probes")
+		}
+	}
+
+	if k.DefaultResources != nil {
+		images := map[string]bool{}
+		for _, b := range builds {
+			images[b.Tag] = true
+		}
+
+		manifests, err = manifests.SetDefaultResources(*k.DefaultResources, images)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "setting default resources")
+		}
+	}
+
+	if k.Replicas != nil {
+		manifests, err = manifests.SetReplicas(*k.Replicas)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "setting replicas")
+		}
+	}
+
+	if k.Owner != nil {
+		clusterScoped, err := k.kubectl.ClusterScopedKinds(ctx, manifests)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "listing cluster-scoped kinds")
+		}
+
+		manifests, err = manifests.SetOwnerReference(*k.Owner, clusterScoped)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "setting owner references")
+		}
+	}
+
+	if k.LabelInjection == LabelsAfterTransforms && k.injectedLabels != nil {
+		manifests, err = manifests.SetLabels(k.injectedLabels)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "setting labels")
+		}
+	}
+
+	if k.SortKeys {
+		manifests, err = manifests.SortKeys()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "sorting keys")
+		}
+	}
+
+	return manifests, builds, nil
 }
-func (k *KustomizeDeployer) Dependencies() ([]string, error) {
-	return dependenciesForKustomization(k.KustomizePath)
+
+// recordHistory adds the deploy to the history ConfigMap. The deploy
+// has succeeded already, so failures are only logged.
+func (k *KustomizeDeployer) recordHistory(ctx context.Context, manifests kubectl.ManifestList, builds []build.Artifact) {
+	limit := k.History.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+
+	images := map[string]string{}
+	for _, b := range builds {
+		images[b.ImageName] = b.Tag
+	}
+
+	if err := k.kubectl.RecordHistory(ctx, kubectl.NewHistoryEntry(manifests, images), limit); err != nil {
+		logrus.Warnln("Unable to record deploy history:", err)
+	}
 }
 
-func (k *KustomizeDeployer) readManifests(ctx context.Context) (kubectl.ManifestList, error) {
-	cmd := exec.CommandContext(ctx, "kustomize", "build", k.KustomizePath)
-	out, err := util.RunCmdOut(cmd)
+// checkUnqualifiedImages warns about, or rejects, the images that
+// rely on the default registry of the cluster.
+func checkUnqualifiedImages(out io.Writer, policy string, manifests kubectl.ManifestList) error {
+	unqualified := manifests.UnqualifiedImages()
+	if len(unqualified) == 0 {
+		return nil
+	}
+
+	switch policy {
+	case "warn":
+		for _, image := range unqualified {
+			color.Yellow.Fprintln(out, "Warning: image", image, "doesn't name its registry")
+		}
+		return nil
+	case "error":
+		return fmt.Errorf("images don't name their registry: %s", strings.Join(unqualified, ", "))
+	default:
+		return fmt.Errorf("invalid unqualifiedImages %q: should be warn or error", policy)
+	}
+}
+
+// checkUnresolvedVars warns about, or rejects, the `$(VAR)` references
+// that kustomize left in the manifests, usually because of a misconfigured var.
+func checkUnresolvedVars(out io.Writer, policy string, manifests kubectl.ManifestList) error {
+	switch policy {
+	case "warn", "error":
+	default:
+		return fmt.Errorf("invalid unresolvedVars %q: should be warn or error", policy)
+	}
+
+	unresolved, err := manifests.UnresolvedVars()
 	if err != nil {
-		return nil, errors.Wrap(err, "kustomize build")
+		return errors.Wrap(err, "checking vars")
+	}
+	if len(unresolved) == 0 {
+		return nil
 	}
 
-	var manifests kubectl.ManifestList
-	manifests.Append(out)
-	return manifests, nil
+	if policy == "warn" {
+		for _, v := range unresolved {
+			color.Yellow.Fprintln(out, "Warning: unresolved var", v)
+		}
+		return nil
+	}
+
+	var refs []string
+	for _, v := range unresolved {
+		refs = append(refs, v.String())
+	}
+	return fmt.Errorf("unresolved vars: %s", strings.Join(refs, ", "))
+}
+
+// checkEmptyBuilds applies the emptyBuilds policy when there are no images
+// to substitute in the manifests. It defaults to `warn` in dev and to
+// `proceed` otherwise, for deploy-only flows with pinned images.
+func (k *KustomizeDeployer) checkEmptyBuilds(out io.Writer) error {
+	policy := k.EmptyBuilds
+	if policy == "" {
+		policy = "proceed"
+		if k.devMode {
+			policy = "warn"
+		}
+	}
+
+	switch policy {
+	case "proceed":
+		return nil
+	case "warn":
+		color.Yellow.Fprintln(out, "Warning: no images were built, the manifests are deployed with their images unchanged")
+		return nil
+	case "error":
+		return errors.New("no images were built, nothing to substitute in the manifests")
+	default:
+		return fmt.Errorf("invalid emptyBuilds %q: should be one of warn, error or proceed", policy)
+	}
+}
+
+// loadImagesIntoLocalCluster loads the built images into the local cluster.
+// Those images can't be pulled from a registry so the manifests are changed
+// to only pull images that are not present.
+func (k *KustomizeDeployer) loadImagesIntoLocalCluster(ctx context.Context, out io.Writer, builds []build.Artifact, manifests kubectl.ManifestList) (kubectl.ManifestList, error) {
+	clusterType, err := localClusterType(k.LocalCluster, k.kubectl.KubeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadImages(ctx, out, clusterType, k.kubectl.KubeContext, builds); err != nil {
+		return nil, err
+	}
+
+	return manifests.SetImagePullPolicy("IfNotPresent")
+}
+
+// checkImagesExist checks that the built images can be pulled from their registry
+// before they are deployed.
+func checkImagesExist(cfg *v1alpha3.ImageCheck, builds []build.Artifact) error {
+	client, err := docker.NewRegistryClient(cfg.CABundle)
+	if err != nil {
+		return err
+	}
+
+	var images []string
+	for _, b := range builds {
+		images = append(images, b.Tag)
+	}
+
+	return client.CheckImagesExist(images)
+}
+
+// acquireLock acquires the deploy lock and returns a function that releases it.
+// The lock is renewed in the background until it is released. The returned
+// context is cancelled if the lock can't be renewed, since another deploy
+// may then take it over.
+func (k *KustomizeDeployer) acquireLock(ctx context.Context) (context.Context, func(), error) {
+	var timeout time.Duration
+	if k.DeployLock.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(k.DeployLock.Timeout); err != nil {
+			return nil, nil, errors.Wrap(err, "parsing deploy lock timeout")
+		}
+	}
+
+	duration := defaultLeaseDuration
+	if k.DeployLock.LeaseDuration != "" {
+		var err error
+		if duration, err = time.ParseDuration(k.DeployLock.LeaseDuration); err != nil {
+			return nil, nil, errors.Wrap(err, "parsing deploy lock lease duration")
+		}
+		if duration < time.Second {
+			return nil, nil, fmt.Errorf("deploy lock lease duration %s should be at least 1s", duration)
+		}
+	}
+
+	holder := lockHolder()
+	if err := k.kubectl.AcquireLock(ctx, k.DeployLock.Name, holder, timeout, duration); err != nil {
+		return nil, nil, err
+	}
+
+	deployCtx, cancelDeploy := context.WithCancel(ctx)
+	renewCtx, stopRenewing := context.WithCancel(context.Background())
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+
+		ticker := time.NewTicker(duration / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-renewCtx.Done():
+				return
+			case <-ticker.C:
+				if err := k.kubectl.RenewLock(renewCtx, k.DeployLock.Name, holder); err != nil {
+					if renewCtx.Err() != nil {
+						return
+					}
+					logrus.Warnln("Unable to renew deploy lock, stopping the deploy:", err)
+					cancelDeploy()
+					return
+				}
+			}
+		}
+	}()
+
+	return deployCtx, func() {
+		stopRenewing()
+		<-renewed
+		cancelDeploy()
+
+		if err := k.kubectl.ReleaseLock(context.Background(), k.DeployLock.Name, holder); err != nil {
+			logrus.Warnln("Unable to release deploy lock:", err)
+		}
+	}, nil
+}
+
+// lockHolder identifies the current process, to debug stuck locks.
+func lockHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+func (k *KustomizeDeployer) observeDuration(name string, start time.Time) {
+	k.metrics.ObserveDuration(name, map[string]string{
+		"deployer":      "kustomize",
+		"kustomizePath": k.kustomizePath,
+	}, time.Since(start))
+}
+
+func (k *KustomizeDeployer) Cleanup(ctx context.Context, out io.Writer) error {
+	if err := k.checkKubeContext(); err != nil {
+		return err
+	}
+
+	if err := k.resolveNamespace(ctx); err != nil {
+		return err
+	}
+
+	manifests, err := k.readManifests(ctx)
+	if err != nil {
+		return errors.Wrap(err, "reading manifests")
+	}
+
+	if err := k.kubectl.Delete(ctx, out, manifests); err != nil {
+		return errors.Wrap(err, "delete")
+	}
+
+	if k.CleanupByLabel {
+		selector := k.leftoversSelector()
+		if err := k.kubectl.DeleteLeftovers(ctx, out, selector, manifests); err != nil {
+			return errors.Wrap(err, "deleting resources left by previous deploys")
+		}
+	}
+
+	if k.CRDsPath != "" && k.DeleteCRDs {
+		if err := k.deleteCRDs(ctx, out); err != nil {
+			return errors.Wrap(err, "deleting CRDs")
+		}
+	}
+
+	return nil
+}
+
+// kustomization holds the fields of a kustomization.yaml that reference other files.
+// The manifests built by kustomize are cached until one of those files changes,
+// so a field that is missing here means edits to its files are ignored in dev.
+type kustomization struct {
+	Bases                 []string     `yaml:"bases"`
+	Resources             []string     `yaml:"resources"`
+	Components            []string     `yaml:"components"`
+	Patches               []patchEntry `yaml:"patches"`
+	PatchesStrategicMerge []string     `yaml:"patchesStrategicMerge"`
+	PatchesJSON6902       []patchEntry `yaml:"patchesJson6902"`
+	ConfigMapGenerator    []generator  `yaml:"configMapGenerator"`
+	SecretGenerator       []generator  `yaml:"secretGenerator"`
+	Replacements          []patchEntry `yaml:"replacements"`
+	CRDs                  []string     `yaml:"crds"`
+	Configurations        []string     `yaml:"configurations"`
+	Generators            []string     `yaml:"generators"`
+	Transformers          []string     `yaml:"transformers"`
+	Validators            []string     `yaml:"validators"`
+	OpenAPI               struct {
+		Path string `yaml:"path"`
+	} `yaml:"openapi"`
+	HelmGlobals struct {
+		ChartHome string `yaml:"chartHome"`
+	} `yaml:"helmGlobals"`
+	HelmCharts []helmChart `yaml:"helmCharts"`
+}
+
+// generator is a configMapGenerator or a secretGenerator.
+type generator struct {
+	Files []string `yaml:"files"`
+	Envs  []string `yaml:"envs"`
+	Env   string   `yaml:"env"`
+}
+
+// sources lists the files a generator reads, relative to the kustomization.
+// A file can be given a key with `key=path`.
+func (g *generator) sources() []string {
+	var sources []string
+	for _, file := range g.Files {
+		sources = append(sources, file[strings.Index(file, "=")+1:])
+	}
+	sources = append(sources, g.Envs...)
+	if g.Env != "" {
+		sources = append(sources, g.Env)
+	}
+	return sources
+}
+
+// helmChart is a chart that kustomize inflates.
+type helmChart struct {
+	Name                  string   `yaml:"name"`
+	Repo                  string   `yaml:"repo"`
+	ValuesFile            string   `yaml:"valuesFile"`
+	AdditionalValuesFiles []string `yaml:"additionalValuesFiles"`
+}
+
+// patchEntry is either a path to a patch file or an object
+// with a path to a patch file or an inline patch.
+type patchEntry struct {
+	Path  string `yaml:"path"`
+	Patch string `yaml:"patch"`
+}
+
+func (p *patchEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var path string
+	if err := unmarshal(&path); err == nil {
+		if isInlinePatch(path) {
+			p.Patch = path
+		} else {
+			p.Path = path
+		}
+		return nil
+	}
+
+	type plain patchEntry
+	return unmarshal((*plain)(p))
+}
+
+// isInlinePatch tells if a patch entry is the patch content itself, rather than a path.
+func isInlinePatch(patch string) bool {
+	return strings.Contains(patch, "\n") || strings.Contains(patch, ": ") || strings.HasPrefix(strings.TrimSpace(patch), "{")
+}
+
+func readKustomization(path string) (*kustomization, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	contents := &kustomization{}
+	decoder := yaml.NewDecoder(file)
+	if err := decoder.Decode(contents); err != nil {
+		return nil, err
+	}
+
+	return contents, nil
+}
+
+func dependenciesForKustomization(dir string) ([]string, error) {
+	// Relative paths are relative to the directory a symlink points to.
+	dir, err := resolveSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, "kustomization.yaml")
+	deps := []string{path}
+
+	contents, err := readKustomization(path)
+	if err != nil {
+		return deps, err
+	}
+
+	for _, base := range contents.Bases {
+		baseDeps, err := dependenciesForKustomization(filepath.Join(dir, base))
+		deps = append(deps, baseDeps...)
+		if err != nil {
+			return deps, err
+		}
+	}
+
+	// Plugin configurations are listed like resources.
+	var resources []string
+	resources = append(resources, contents.Resources...)
+	resources = append(resources, contents.Components...)
+	resources = append(resources, contents.Generators...)
+	resources = append(resources, contents.Transformers...)
+	resources = append(resources, contents.Validators...)
+	for _, resource := range resources {
+		resourceDeps, err := dependenciesForResource(filepath.Join(dir, resource))
+		deps = append(deps, resourceDeps...)
+		if err != nil {
+			return deps, err
+		}
+	}
+
+	// Changes to inline patches are captured by the kustomization.yaml itself.
+	for _, patches := range [][]patchEntry{contents.Patches, contents.PatchesJSON6902, contents.Replacements} {
+		for _, patch := range patches {
+			if patch.Path != "" {
+				deps = append(deps, filepath.Join(dir, patch.Path))
+			}
+		}
+	}
+
+	for _, patch := range contents.PatchesStrategicMerge {
+		if !isInlinePatch(patch) {
+			deps = append(deps, filepath.Join(dir, patch))
+		}
+	}
+
+	for _, generator := range append(contents.ConfigMapGenerator, contents.SecretGenerator...) {
+		for _, source := range generator.sources() {
+			deps = append(deps, filepath.Join(dir, source))
+		}
+	}
+
+	var files []string
+	files = append(files, contents.CRDs...)
+	files = append(files, contents.Configurations...)
+	if contents.OpenAPI.Path != "" {
+		files = append(files, contents.OpenAPI.Path)
+	}
+	for _, file := range files {
+		deps = append(deps, filepath.Join(dir, file))
+	}
+
+	chartDeps, err := dependenciesForHelmCharts(dir, contents)
+	deps = append(deps, chartDeps...)
+	if err != nil {
+		return deps, err
+	}
+
+	return deps, nil
+}
+
+// dependenciesForHelmCharts lists the values files of the inflated charts
+// and the files of the local charts. Charts pulled from a repository
+// don't have local dependencies.
+func dependenciesForHelmCharts(dir string, contents *kustomization) ([]string, error) {
+	chartHome := contents.HelmGlobals.ChartHome
+	if chartHome == "" {
+		chartHome = "charts"
+	}
+
+	var deps []string
+	for _, chart := range contents.HelmCharts {
+		for _, values := range append([]string{chart.ValuesFile}, chart.AdditionalValuesFiles...) {
+			if values != "" {
+				deps = append(deps, filepath.Join(dir, values))
+			}
+		}
+
+		if chart.Repo != "" {
+			continue
+		}
+
+		chartDir := filepath.Join(dir, chartHome, chart.Name)
+		if _, err := os.Stat(chartDir); err != nil {
+			continue
+		}
+		err := filepath.Walk(chartDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				deps = append(deps, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return deps, errors.Wrapf(err, "listing files of chart %s", chart.Name)
+		}
+	}
+
+	return deps, nil
+}
+
+// dependenciesForResource lists the files read for a resources entry.
+// A directory is either a kustomization itself or holds plain yaml files.
+func dependenciesForResource(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	path, err = resolveSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(path, "kustomization.yaml")); err == nil {
+		return dependenciesForKustomization(path)
+	}
+
+	var deps []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		files, err := filepath.Glob(filepath.Join(path, pattern))
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing yaml files in %s", path)
+		}
+		deps = append(deps, files...)
+	}
+
+	return deps, nil
+}
+
+// kustomizationRoots lists the root directories of a kustomization and of all its bases,
+// including the resources and components entries that are kustomizations.
+func kustomizationRoots(dir string) ([]string, error) {
+	contents, err := readKustomization(filepath.Join(dir, "kustomization.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	roots := []string{dir}
+	for _, base := range contents.Bases {
+		baseRoots, err := kustomizationRoots(filepath.Join(dir, base))
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, baseRoots...)
+	}
+
+	for _, resource := range append(contents.Resources, contents.Components...) {
+		path := filepath.Join(dir, resource)
+		if _, err := os.Stat(filepath.Join(path, "kustomization.yaml")); err != nil {
+			continue
+		}
+
+		resourceRoots, err := kustomizationRoots(path)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, resourceRoots...)
+	}
+
+	return roots, nil
+}
+
+// accurateDependenciesForKustomization lists every file that `kustomize build` may read.
+// Kustomize only loads files from within the root of a kustomization, or of one of its
+// bases, so every file in those directories is a dependency.
+func accurateDependenciesForKustomization(dir string) ([]string, error) {
+	roots, err := kustomizationRoots(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing kustomization roots")
+	}
+
+	seen := map[string]bool{}
+	var deps []string
+	for _, root := range roots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != root && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !seen[path] {
+				seen[path] = true
+				deps = append(deps, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "walking %s", root)
+		}
+	}
+
+	return deps, nil
+}
+
+func (k *KustomizeDeployer) Dependencies() ([]string, error) {
+	paths, err := k.dependencyPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	var deps []string
+	for _, path := range paths {
+		pathDeps, err := k.dependencies(path)
+		deps = append(deps, pathDeps...)
+		if err != nil {
+			return deps, err
+		}
+	}
+
+	return deps, nil
+}
+
+// DependenciesByRoot returns the dependencies of each kustomization that is
+// built, keyed by its root. Paths are absolute, sorted and de-duplicated.
+func (k *KustomizeDeployer) DependenciesByRoot() (map[string][]string, error) {
+	paths, err := k.dependencyPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	byRoot := map[string][]string{}
+	for _, path := range paths {
+		root := path
+		if !isRemoteKustomization(path) {
+			if root, err = filepath.Abs(path); err != nil {
+				return nil, errors.Wrapf(err, "resolving %s", path)
+			}
+		}
+
+		deps, err := k.dependencies(path)
+		if err != nil {
+			return nil, err
+		}
+
+		absDeps, err := absDependencies(append(byRoot[root], deps...))
+		if err != nil {
+			return nil, err
+		}
+		byRoot[root] = absDeps
+	}
+
+	return byRoot, nil
+}
+
+// WriteDependencies writes the dependencies of each kustomization as JSON,
+// so that external build systems can invalidate their caches on them.
+func (k *KustomizeDeployer) WriteDependencies(out io.Writer) error {
+	byRoot, err := k.DependenciesByRoot()
+	if err != nil {
+		return errors.Wrap(err, "listing dependencies")
+	}
+
+	buf, err := json.MarshalIndent(byRoot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(append(buf, '\n'))
+	return err
+}
+
+func absDependencies(deps []string) ([]string, error) {
+	seen := map[string]bool{}
+	abs := []string{}
+	for _, dep := range deps {
+		path, err := filepath.Abs(dep)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolving %s", dep)
+		}
+		if !seen[path] {
+			seen[path] = true
+			abs = append(abs, path)
+		}
+	}
+
+	sort.Strings(abs)
+	return abs, nil
+}
+
+func (k *KustomizeDeployer) dependencies(path string) ([]string, error) {
+	if isRemoteKustomization(path) {
+		logrus.Debugf("%s is a remote kustomization, without local dependencies", path)
+		return nil, nil
+	}
+
+	if k.AccurateDependencies {
+		deps, err := accurateDependenciesForKustomization(path)
+		if err == nil {
+			return resolveDependencySymlinks(deps)
+		}
+		logrus.Warnln("Unable to list all the kustomize inputs, falling back to parsing kustomization files:", err)
+	}
+
+	deps, err := dependenciesForKustomization(path)
+	if err != nil {
+		return deps, err
+	}
+
+	deps, err = resolveDependencySymlinks(deps)
+	if err != nil || !k.StrictDependencies {
+		return deps, err
+	}
+
+	return deps, checkDependenciesExist(deps)
+}
+
+// resolveDependencySymlinks replaces the dependencies that are symlinks by
+// the files they point to, since those are the files to watch.
+func resolveDependencySymlinks(deps []string) ([]string, error) {
+	var resolved []string
+	for _, dep := range deps {
+		real, err := resolveSymlinks(dep)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, real)
+	}
+
+	return resolved, nil
+}
+
+// resolveSymlinks returns the path that a file or a directory really is, behind
+// any symlink. Missing paths are returned as is, while broken symlinks are an error.
+func resolveSymlinks(path string) (string, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return real, nil
+	}
+
+	if target, linkErr := os.Readlink(path); linkErr == nil {
+		return "", errors.Errorf("%s is a broken symlink to %s", path, target)
+	}
+
+	return path, nil
+}
+
+// checkDependenciesExist fails if a file referenced by a kustomization doesn't
+// exist, which is usually a typo that would otherwise never trigger a redeploy.
+func checkDependenciesExist(deps []string) error {
+	var missing []string
+	for _, dep := range deps {
+		if _, err := os.Stat(dep); os.IsNotExist(err) {
+			missing = append(missing, dep)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("kustomization references files that don't exist: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// dependencyPaths returns the kustomizations to watch: the paths to build and the crdsPath.
+func (k *KustomizeDeployer) dependencyPaths() ([]string, error) {
+	paths, err := k.kustomizePaths()
+	if err != nil || k.CRDsPath == "" {
+		return paths, err
+	}
+
+	return append(paths, k.crdsPath()), nil
+}
+
+// kustomizePaths returns the paths to build: the overlays matching the
+// overlay selector if overlays are configured, the kustomizePath otherwise.
+func (k *KustomizeDeployer) kustomizePaths() ([]string, error) {
+	if len(k.Overlays) == 0 {
+		return []string{k.kustomizePath}, nil
+	}
+
+	selector, err := labels.Parse(k.overlaySelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing overlay selector %s", k.overlaySelector)
+	}
+
+	var paths []string
+	for _, overlay := range k.Overlays {
+		if !selector.Matches(labels.Set(overlay.Labels)) {
+			logrus.Debugf("Skipping overlay %s that doesn't match %s", overlay.Name, selector)
+			continue
+		}
+
+		paths = append(paths, resolveKustomizePath(k.workingDir, overlay.Path))
+	}
+
+	return paths, nil
+}
+
+// resolveKustomizePath resolves a relative path against the working directory,
+// falling back to the path relative to the current directory if it doesn't exist.
+func resolveKustomizePath(workingDir string, path string) string {
+	if workingDir == "" || filepath.IsAbs(path) || isRemoteKustomization(path) {
+		return path
+	}
+
+	resolved := filepath.Join(workingDir, path)
+	if _, err := os.Stat(resolved); err != nil {
+		logrus.Debugf("%s doesn't exist, using %s relative to the current directory", resolved, path)
+		return path
+	}
+
+	return resolved
+}
+
+// isRemoteKustomization returns true for the git urls that kustomize
+// builds from a remote repository, like `github.com/org/repo//path?ref=v1`.
+func isRemoteKustomization(path string) bool {
+	return strings.Contains(path, "://") || strings.HasPrefix(path, "git@") || strings.HasPrefix(path, "github.com/")
+}
+
+// readManifests builds the kustomizations. The result is reused until
+// one of the dependencies changes, to save kustomize builds in dev.
+func (k *KustomizeDeployer) readManifests(ctx context.Context) (kubectl.ManifestList, error) {
+	k.cache.Lock()
+	defer k.cache.Unlock()
+
+	paths, err := k.kustomizePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	// Remote kustomizations can change without notice.
+	cacheable := true
+	for _, path := range paths {
+		if isRemoteKustomization(path) {
+			cacheable = false
+		}
+	}
+
+	var key string
+	if deps, err := k.Dependencies(); err == nil {
+		key = dependenciesKey(deps)
+	}
+
+	if manifests, cached := k.cache.get(key); cacheable && cached {
+		logrus.Debugln("Reusing the manifests built by kustomize")
+		k.cache.read(manifests)
+		return manifests, nil
+	}
+
+	manifests, err := k.buildManifests(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheable {
+		k.cache.set(key, manifests)
+	}
+	k.cache.read(manifests)
+	return manifests, nil
+}
+
+func (k *KustomizeDeployer) buildManifests(ctx context.Context) (kubectl.ManifestList, error) {
+	paths, err := k.kustomizePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return nil, errors.Wrap(err, "finding current directory")
+	}
+
+	if err := k.refreshCacheDir(); err != nil {
+		return nil, err
+	}
+
+	var manifests kubectl.ManifestList
+	for _, path := range paths {
+		out, err := kustomizeBuild(ctx, path, k.kustomizeEnv(), k.kustomizeArgs(), workingDir)
+		if err != nil {
+			return nil, err
+		}
+
+		var built kubectl.ManifestList
+		built.Append(out)
+
+		// Each resource is annotated with the path of the overlay that produced it.
+		if k.AnnotateSource {
+			built, err = built.SetAnnotation(kubectl.KustomizationAnnotation, path)
+			if err != nil {
+				return nil, errors.Wrap(err, "annotating manifests")
+			}
+		}
+
+		manifests = append(manifests, built...)
+	}
+
+	return manifests, nil
+}
+
+// kustomizeEnv is added to the environment of `kustomize build`.
+func (k *KustomizeDeployer) kustomizeEnv() []string {
+	var env []string
+	if k.cacheDir != "" {
+		env = append(env, "XDG_CACHE_HOME="+k.cacheDir)
+	}
+	if k.Plugins != nil && k.Plugins.Dir != "" {
+		env = append(env, "KUSTOMIZE_PLUGIN_HOME="+resolvePath(k.workingDir, k.Plugins.Dir))
+	}
+	if env == nil {
+		return k.Env
+	}
+
+	return append(env, k.Env...)
+}
+
+// kustomizeArgs are the flags of `kustomize build` that enable the configured plugins.
+func (k *KustomizeDeployer) kustomizeArgs() []string {
+	if k.Plugins == nil {
+		return nil
+	}
+
+	var args []string
+	if k.Plugins.Dir != "" {
+		args = append(args, "--enable-alpha-plugins")
+	}
+	if k.Plugins.Exec {
+		args = append(args, "--enable-exec")
+	}
+	if k.Plugins.Helm {
+		args = append(args, "--enable-helm")
+	}
+
+	return args
+}
+
+func kustomizeBuild(ctx context.Context, path string, env []string, args []string, workingDir string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "kustomize", append(append([]string{"build"}, args...), path)...)
+	if len(env) > 0 {
+		// Later entries win, so the configured env overrides the inherited one.
+		cmd.Env = append(os.Environ(), env...)
+	}
+	commandLine := strings.Join(cmd.Args, " ")
+
+	logrus.Debugf("Running kustomize build: command: %s, binary: %s, path: %s, working dir: %s", commandLine, cmd.Path, path, workingDir)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := util.RunCmdOut(cmd)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.Wrap(err, msg)
+		}
+		if isRemoteKustomization(path) {
+			return nil, errors.Wrapf(err, "kustomize build of remote target %s: the kustomize binary must support remote targets (run `%s` in %s to reproduce)", path, commandLine, workingDir)
+		}
+		return nil, errors.Wrapf(err, "kustomize build (run `%s` in %s to reproduce)", commandLine, workingDir)
+	}
+
+	// A successful build only prints warnings to stderr.
+	out, warnings := splitKustomizeWarnings(out)
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if warning := strings.TrimSpace(line); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	for _, warning := range warnings {
+		logrus.Infof("kustomize build %s: %s", path, warning)
+	}
+
+	return out, nil
+}
+
+// splitKustomizeWarnings separates the manifests from the warnings that some
+// kustomize versions and plugins print to stdout before the first document.
+func splitKustomizeWarnings(out []byte) ([]byte, []string) {
+	var warnings []string
+
+	rest := string(out)
+	for rest != "" {
+		line := rest
+		if i := strings.Index(rest, "\n"); i >= 0 {
+			line = rest[:i+1]
+		}
+
+		trimmed := strings.TrimRight(line, "\r\n")
+		if trimmed == "---" || strings.HasPrefix(trimmed, "apiVersion:") || strings.HasPrefix(trimmed, "kind:") {
+			return []byte(rest), warnings
+		}
+
+		if warning := strings.TrimSpace(trimmed); warning != "" {
+			warnings = append(warnings, warning)
+		}
+		rest = rest[len(line):]
+	}
+
+	// Without a document to start from, the output is left to the YAML parser.
+	return out, nil
+}
+
+// refreshCacheDir empties the kustomize cache directory, once, when asked to
+// on the command line. The fetches of remote bases are then done again.
+func (k *KustomizeDeployer) refreshCacheDir() error {
+	if !k.refreshCache || k.cacheDir == "" {
+		return nil
+	}
+	k.refreshCache = false
+
+	logrus.Debugln("Emptying the kustomize cache directory", k.cacheDir)
+	if err := os.RemoveAll(k.cacheDir); err != nil {
+		return errors.Wrap(err, "emptying kustomize cache directory")
+	}
+
+	return nil
+}
+
+// resolvePath resolves a relative path against the working directory.
+// Unlike kustomize paths, the path doesn't need to exist yet.
+func resolvePath(workingDir string, path string) string {
+	if path == "" || workingDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(workingDir, path)
 }
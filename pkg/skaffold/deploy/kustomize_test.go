@@ -0,0 +1,1493 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestKustomizePathRelativeToConfig(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("app/kustomization.yaml", "resources: [deployment.yaml]")
+
+	var tests = []struct {
+		description   string
+		workingDir    string
+		kustomizePath string
+		expectedPath  string
+	}{
+		{
+			description:   "relative to config",
+			workingDir:    tmpDir.Path("app"),
+			kustomizePath: ".",
+			expectedPath:  tmpDir.Path("app"),
+		},
+		{
+			description:   "fallback to current directory",
+			workingDir:    tmpDir.Path("app"),
+			kustomizePath: "missing",
+			expectedPath:  "missing",
+		},
+		{
+			description:   "absolute path",
+			workingDir:    tmpDir.Path("app"),
+			kustomizePath: tmpDir.Path("other"),
+			expectedPath:  tmpDir.Path("other"),
+		},
+		{
+			description:   "no working directory",
+			kustomizePath: ".",
+			expectedPath:  ".",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			k := NewKustomizeDeployer(test.workingDir, &v1alpha3.KustomizeDeploy{KustomizePath: test.kustomizePath}, testKubeContext, &config.SkaffoldOptions{Namespace: testNamespace})
+
+			testutil.CheckDeepEqual(t, test.expectedPath, k.kustomizePath)
+		})
+	}
+}
+
+func TestKustomizeDependencies(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("app/kustomization.yaml", "resources: [deployment.yaml]")
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: "app"}, testKubeContext, &config.SkaffoldOptions{Namespace: testNamespace})
+	deps, err := k.Dependencies()
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{
+		tmpDir.Path("app/kustomization.yaml"),
+		tmpDir.Path("app/deployment.yaml"),
+	}, deps)
+}
+
+func TestKustomizeWriteDependencies(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("base/kustomization.yaml", "resources: [deployment.yaml]").
+		Write("base/deployment.yaml", "").
+		Write("dev/kustomization.yaml", "bases: [../base]").
+		Write("prod/kustomization.yaml", "bases: [../base]\nresources: [../base/deployment.yaml]")
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{
+		Overlays: []v1alpha3.KustomizeOverlay{
+			{Name: "dev", Path: "dev"},
+			{Name: "prod", Path: "prod"},
+		},
+	}, testKubeContext, &config.SkaffoldOptions{Namespace: testNamespace})
+
+	var out bytes.Buffer
+	err := k.WriteDependencies(&out)
+
+	expected := fmt.Sprintf(`{
+  %[1]q: [
+    %[3]q,
+    %[4]q,
+    %[5]q
+  ],
+  %[2]q: [
+    %[3]q,
+    %[4]q,
+    %[6]q
+  ]
+}
+`, tmpDir.Path("dev"), tmpDir.Path("prod"),
+		tmpDir.Path("base/deployment.yaml"), tmpDir.Path("base/kustomization.yaml"),
+		tmpDir.Path("dev/kustomization.yaml"), tmpDir.Path("prod/kustomization.yaml"))
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected, out.String())
+}
+
+func TestKustomizeStrictDependencies(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("app/kustomization.yaml", "resources: [deployment.yaml, deploymnet.yaml]\npatches:\n- path: patch.yaml").
+		Write("app/deployment.yaml", "").
+		Write("app/patch.yaml", "")
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: "app", StrictDependencies: true}, testKubeContext, &config.SkaffoldOptions{Namespace: testNamespace})
+	_, err := k.Dependencies()
+
+	testutil.CheckError(t, true, err)
+	if err != nil && !strings.Contains(err.Error(), tmpDir.Path("app/deploymnet.yaml")) {
+		t.Errorf("expected the error to name the missing file, got %v", err)
+	}
+
+	tmpDir.Write("app/kustomization.yaml", "resources: [deployment.yaml]\npatches:\n- path: patch.yaml")
+	_, err = k.Dependencies()
+
+	testutil.CheckError(t, false, err)
+}
+
+func TestKustomizeDependenciesSymlinkedBase(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("shared/base/kustomization.yaml", "resources: [deployment.yaml, ../common/config.yaml]").
+		Write("shared/base/deployment.yaml", "").
+		Write("shared/common/config.yaml", "").
+		Write("repo/app/kustomization.yaml", "bases: [base]\nresources: [service.yaml]").
+		Write("shared/service.yaml", "")
+	if err := os.Symlink(tmpDir.Path("shared/base"), tmpDir.Path("repo/app/base")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(tmpDir.Path("shared/service.yaml"), tmpDir.Path("repo/app/service.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	k := NewKustomizeDeployer(tmpDir.Path("repo"), &v1alpha3.KustomizeDeploy{KustomizePath: "app"}, testKubeContext, &config.SkaffoldOptions{})
+	deps, err := k.Dependencies()
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{
+		tmpDir.Path("repo/app/kustomization.yaml"),
+		tmpDir.Path("shared/base/kustomization.yaml"),
+		tmpDir.Path("shared/base/deployment.yaml"),
+		tmpDir.Path("shared/common/config.yaml"),
+		tmpDir.Path("shared/service.yaml"),
+	}, deps)
+}
+
+func TestKustomizeDependenciesBrokenSymlink(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("app/kustomization.yaml", "bases: [base]")
+	if err := os.Symlink(tmpDir.Path("missing"), tmpDir.Path("app/base")); err != nil {
+		t.Fatal(err)
+	}
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: "app"}, testKubeContext, &config.SkaffoldOptions{})
+	_, err := k.Dependencies()
+
+	testutil.CheckError(t, true, err)
+	testutil.CheckDeepEqual(t, true, strings.Contains(err.Error(), "is a broken symlink to "+tmpDir.Path("missing")))
+}
+
+func TestKustomizeDependenciesResourceDirectories(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("app/kustomization.yaml", "resources: [deployment.yaml, db, manifests]").
+		Write("app/db/kustomization.yaml", "resources: [statefulset.yaml]").
+		Write("app/manifests/service.yaml", "").
+		Write("app/manifests/ingress.yml", "").
+		Write("app/manifests/README.md", "")
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: "app"}, testKubeContext, &config.SkaffoldOptions{})
+	deps, err := k.Dependencies()
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{
+		tmpDir.Path("app/kustomization.yaml"),
+		tmpDir.Path("app/deployment.yaml"),
+		tmpDir.Path("app/db/kustomization.yaml"),
+		tmpDir.Path("app/db/statefulset.yaml"),
+		tmpDir.Path("app/manifests/service.yaml"),
+		tmpDir.Path("app/manifests/ingress.yml"),
+	}, deps)
+}
+
+type fakeMetricsSink struct {
+	names  []string
+	labels map[string]string
+}
+
+func (f *fakeMetricsSink) ObserveDuration(name string, labels map[string]string, _ time.Duration) {
+	f.names = append(f.names, name)
+	f.labels = labels
+}
+
+func TestKustomizeDeployMetrics(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+	)
+
+	sink := &fakeMetricsSink{}
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: "."}, testKubeContext, &config.SkaffoldOptions{Namespace: testNamespace})
+	k.SetMetricsSink(sink)
+
+	_, err := k.Deploy(context.Background(), ioutil.Discard, []build.Artifact{
+		{ImageName: "leeroy-web", Tag: "leeroy-web:v1"},
+	})
+
+	sort.Strings(sink.names)
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{MetricApply, MetricReadManifests, MetricReplaceImages}, sink.names)
+	testutil.CheckDeepEqual(t, map[string]string{"deployer": "kustomize", "kustomizePath": "."}, sink.labels)
+}
+
+func TestKustomizeDependenciesInlinePatches(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("kustomization.yaml", `resources:
+- deployment.yaml
+patches:
+- legacy-patch.yaml
+- path: patch.yaml
+  target:
+    kind: Deployment
+- patch: |-
+    - op: replace
+      path: /spec/replicas
+      value: 3
+  target:
+    kind: Deployment
+patchesStrategicMerge:
+- strategic-patch.yaml
+- |-
+  apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+`)
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: "."}, testKubeContext, &config.SkaffoldOptions{})
+	deps, err := k.Dependencies()
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{
+		tmpDir.Path("kustomization.yaml"),
+		tmpDir.Path("deployment.yaml"),
+		tmpDir.Path("legacy-patch.yaml"),
+		tmpDir.Path("patch.yaml"),
+		tmpDir.Path("strategic-patch.yaml"),
+	}, deps)
+}
+
+func TestKustomizeDependenciesHelmCharts(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("kustomization.yaml", `helmGlobals:
+  chartHome: helm
+helmCharts:
+- name: local
+  valuesFile: values-dev.yaml
+  additionalValuesFiles: [values-common.yaml]
+- name: remote
+  repo: https://charts.example.com
+  valuesFile: values-remote.yaml
+`).
+		Write("helm/local/Chart.yaml", "").
+		Write("helm/local/templates/deployment.yaml", "").
+		Write("helm/remote/Chart.yaml", "")
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: "."}, testKubeContext, &config.SkaffoldOptions{})
+	deps, err := k.Dependencies()
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{
+		tmpDir.Path("kustomization.yaml"),
+		tmpDir.Path("values-dev.yaml"),
+		tmpDir.Path("values-common.yaml"),
+		tmpDir.Path("helm/local/Chart.yaml"),
+		tmpDir.Path("helm/local/templates/deployment.yaml"),
+		tmpDir.Path("values-remote.yaml"),
+	}, deps)
+}
+
+func TestKustomizeDependenciesGeneratedAndComponents(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("kustomization.yaml", `components: [monitoring]
+patchesJson6902:
+- target: {kind: Deployment, name: web}
+  path: replicas.json
+configMapGenerator:
+- name: config
+  files: [app.properties, log=logging.properties]
+  envs: [app.env]
+secretGenerator:
+- name: secret
+  env: secret.env
+crds: [crd.json]
+`).
+		Write("monitoring/kustomization.yaml", "kind: Component\nresources: [monitor.yaml]")
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: "."}, testKubeContext, &config.SkaffoldOptions{})
+	deps, err := k.Dependencies()
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{
+		tmpDir.Path("kustomization.yaml"),
+		tmpDir.Path("monitoring/kustomization.yaml"),
+		tmpDir.Path("monitoring/monitor.yaml"),
+		tmpDir.Path("replicas.json"),
+		tmpDir.Path("app.properties"),
+		tmpDir.Path("logging.properties"),
+		tmpDir.Path("app.env"),
+		tmpDir.Path("secret.env"),
+		tmpDir.Path("crd.json"),
+	}, deps)
+}
+
+func TestKustomizeRebuildsWhenGeneratedFileChanges(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("kustomization.yaml", "configMapGenerator: [{name: config, files: [app.properties]}]").
+		Write("app.properties", "debug=false")
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kustomize build "+tmpDir.Root(), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  app.properties: debug=false\n", nil),
+		testutil.NewFakeCmdOut("kustomize build "+tmpDir.Root(), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  app.properties: debug=on\n", nil),
+	)
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: "."}, testKubeContext, &config.SkaffoldOptions{})
+	_, err := k.readManifests(context.Background())
+	testutil.CheckError(t, false, err)
+
+	tmpDir.Write("app.properties", "debug=on")
+	manifests, err := k.readManifests(context.Background())
+
+	testutil.CheckError(t, false, err)
+	if !strings.Contains(manifests.String(), "debug=on") {
+		t.Errorf("expected the manifests to be built again, got: %s", manifests.String())
+	}
+}
+
+func TestKustomizeAccurateDependencies(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("base/kustomization.yaml", "resources: [deployment.yaml]").
+		Write("base/deployment.yaml", "").
+		Write("overlay/kustomization.yaml", "bases: [../base]\nconfigMapGenerator: [{name: cfg, files: [app.properties]}]").
+		Write("overlay/app.properties", "").
+		Write("overlay/.git/HEAD", "").
+		Write("unrelated/file.yaml", "")
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: "overlay", AccurateDependencies: true}, testKubeContext, &config.SkaffoldOptions{Namespace: testNamespace})
+	deps, err := k.Dependencies()
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{
+		tmpDir.Path("overlay/app.properties"),
+		tmpDir.Path("overlay/kustomization.yaml"),
+		tmpDir.Path("base/deployment.yaml"),
+		tmpDir.Path("base/kustomization.yaml"),
+	}, deps)
+}
+
+func TestKustomizeCleanup(t *testing.T) {
+	zero := 0
+	negative := -1
+
+	var tests = []struct {
+		description string
+		cfg         *v1alpha3.KustomizeDeploy
+		command     util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "cleanup success",
+			cfg:         &v1alpha3.KustomizeDeploy{KustomizePath: "."},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext delete --ignore-not-found=true -f -", nil),
+			),
+		},
+		{
+			description: "grace period and force",
+			cfg: &v1alpha3.KustomizeDeploy{
+				KustomizePath:            ".",
+				DeleteGracePeriodSeconds: &zero,
+				ForceDelete:              true,
+			},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext delete --ignore-not-found=true --grace-period=0 --force -f -", nil),
+			),
+		},
+		{
+			description: "propagation policy",
+			cfg: &v1alpha3.KustomizeDeploy{
+				KustomizePath:           ".",
+				DeletePropagationPolicy: "orphan",
+			},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext delete --ignore-not-found=true --cascade=orphan -f -", nil),
+			),
+		},
+		{
+			description: "invalid propagation policy",
+			cfg: &v1alpha3.KustomizeDeploy{
+				KustomizePath:           ".",
+				DeletePropagationPolicy: "cascade",
+			},
+			command:   testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+			shouldErr: true,
+		},
+		{
+			description: "negative grace period is kubectl default",
+			cfg: &v1alpha3.KustomizeDeploy{
+				KustomizePath:            ".",
+				DeleteGracePeriodSeconds: &negative,
+			},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext delete --ignore-not-found=true -f -", nil),
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			k := NewKustomizeDeployer("", test.cfg, testKubeContext, &config.SkaffoldOptions{Namespace: testNamespace})
+			err := k.Cleanup(context.Background(), ioutil.Discard)
+
+			testutil.CheckError(t, test.shouldErr, err)
+		})
+	}
+}
+
+func TestKustomizeCleanupDryRun(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil)
+
+	var out bytes.Buffer
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: "."}, testKubeContext, &config.SkaffoldOptions{Namespace: testNamespace, DryRun: true})
+	err := k.Cleanup(context.Background(), &out)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Would delete pod/leeroy-web in namespace testNamespace\n", out.String())
+}
+
+func TestKustomizeOverlays(t *testing.T) {
+	cfg := &v1alpha3.KustomizeDeploy{
+		KustomizePath: ".",
+		Overlays: []v1alpha3.KustomizeOverlay{
+			{Name: "frontend", Path: "frontend", Labels: map[string]string{"tier": "frontend"}},
+			{Name: "backend", Path: "backend", Labels: map[string]string{"tier": "backend"}},
+		},
+	}
+
+	var tests = []struct {
+		description string
+		selector    string
+		command     util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "no selector",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build frontend", deploymentWebYAML, nil),
+				testutil.NewFakeCmdOut("kustomize build backend", deploymentAppYaml, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+			),
+		},
+		{
+			description: "matching selector",
+			selector:    "tier=backend",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build backend", deploymentAppYaml, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+			),
+		},
+		{
+			description: "no matching overlay",
+			selector:    "tier=database",
+			command:     testutil.NewFakeCmds(),
+		},
+		{
+			description: "invalid selector",
+			selector:    "tier in (a",
+			command:     testutil.NewFakeCmds(),
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			k := NewKustomizeDeployer("", cfg, testKubeContext, &config.SkaffoldOptions{OverlaySelector: test.selector})
+			_, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			testutil.CheckError(t, test.shouldErr, err)
+		})
+	}
+}
+
+func TestKustomizeAnnotateSourceOverlays(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+	tmpDir.Mkdir("frontend").Mkdir("backend")
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kustomize build "+tmpDir.Path("frontend"), deploymentWebYAML, nil),
+		testutil.NewFakeCmdOut("kustomize build "+tmpDir.Path("backend"), deploymentAppYaml, nil),
+	)
+
+	cfg := &v1alpha3.KustomizeDeploy{
+		KustomizePath:  ".",
+		AnnotateSource: true,
+		Overlays: []v1alpha3.KustomizeOverlay{
+			{Name: "frontend", Path: "frontend"},
+			{Name: "backend", Path: "backend"},
+		},
+	}
+	k := NewKustomizeDeployer(tmpDir.Root(), cfg, testKubeContext, &config.SkaffoldOptions{})
+	manifests, err := k.readManifests(context.Background())
+	testutil.CheckErrorAndDeepEqual(t, false, err, 2, len(manifests))
+
+	for i, overlay := range []string{"frontend", "backend"} {
+		var m struct {
+			Metadata struct {
+				Annotations map[string]string `yaml:"annotations"`
+			} `yaml:"metadata"`
+		}
+		err := yaml.Unmarshal(manifests[i], &m)
+
+		testutil.CheckErrorAndDeepEqual(t, false, err, tmpDir.Path(overlay), m.Metadata.Annotations[kubectl.KustomizationAnnotation])
+	}
+}
+
+func TestKustomizeImageLockRelativeToConfig(t *testing.T) {
+	digest := "sha256:81daf011d63b68cfa514ddab7741a1adddd59d3264118dfb0fd9266328bb8883"
+
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+	tmpDir.Write("images.lock", "leeroy-web: "+digest)
+
+	var tests = []struct {
+		description string
+		file        string
+		shouldErr   bool
+	}{
+		{
+			description: "relative to the config",
+			file:        "images.lock",
+		},
+		{
+			description: "missing",
+			file:        "missing.lock",
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = testutil.NewFakeCmdOut("kustomize build "+tmpDir.Root(), deploymentWebYAML, nil)
+
+			cfg := &v1alpha3.KustomizeDeploy{KustomizePath: ".", ImageLock: &v1alpha3.ImageLock{File: test.file}}
+			k := NewKustomizeDeployer(tmpDir.Root(), cfg, testKubeContext, &config.SkaffoldOptions{})
+			manifests, _, err := k.render(context.Background(), ioutil.Discard, nil)
+
+			testutil.CheckError(t, test.shouldErr, err)
+			if test.shouldErr {
+				if !strings.Contains(err.Error(), tmpDir.Path(test.file)) {
+					t.Errorf("error should mention the lockfile resolved against the config folder, got: %s", err)
+				}
+			} else if !strings.Contains(manifests.String(), "image: leeroy-web@"+digest) {
+				t.Errorf("image should be pinned, got: %s", manifests.String())
+			}
+		})
+	}
+}
+
+func TestKustomizeDeployValidation(t *testing.T) {
+	var tests = []struct {
+		description string
+		validation  string
+		command     util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "default validation",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+			),
+		},
+		{
+			description: "validation disabled",
+			validation:  "false",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply --validate=false -f -", nil),
+			),
+		},
+		{
+			description: "strict validation",
+			validation:  "strict",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply --validate=strict -f -", nil),
+			),
+		},
+		{
+			description: "invalid validation",
+			validation:  "sometimes",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+			),
+			shouldErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: ".", Validation: test.validation}, testKubeContext, &config.SkaffoldOptions{})
+			_, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			testutil.CheckError(t, test.shouldErr, err)
+		})
+	}
+}
+
+func TestKustomizeForceApply(t *testing.T) {
+	var tests = []struct {
+		description string
+		cfg         v1alpha3.KustomizeDeploy
+		command     util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "force apply",
+			cfg:         v1alpha3.KustomizeDeploy{ForceApply: true},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply --force -f -", nil),
+			),
+		},
+		{
+			description: "force apply with cascade",
+			cfg:         v1alpha3.KustomizeDeploy{ForceApply: true, ApplyCascade: "orphan"},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply --force --cascade=orphan -f -", nil),
+			),
+		},
+		{
+			description: "cascade without force",
+			cfg:         v1alpha3.KustomizeDeploy{ApplyCascade: "orphan"},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+			),
+			shouldErr: true,
+		},
+		{
+			description: "invalid cascade",
+			cfg:         v1alpha3.KustomizeDeploy{ForceApply: true, ApplyCascade: "true"},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+			),
+			shouldErr: true,
+		},
+		{
+			description: "force with server-side apply",
+			cfg:         v1alpha3.KustomizeDeploy{ForceApply: true, ServerSideApply: true},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+			),
+			shouldErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			test.cfg.KustomizePath = "."
+			k := NewKustomizeDeployer("", &test.cfg, testKubeContext, &config.SkaffoldOptions{})
+			_, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			testutil.CheckError(t, test.shouldErr, err)
+		})
+	}
+}
+
+func TestKustomizeDeployLock(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kubectl --context kubecontext --namespace testNamespace create -f -", "", nil),
+		testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+		testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+		testutil.NewFakeCmdOut("kubectl --context kubecontext --namespace testNamespace get lease lock -o yaml", "metadata:\n  name: lock\n  namespace: testNamespace\n  resourceVersion: \"1\"\nspec:\n  holderIdentity: "+lockHolder()+"\n", nil),
+		testutil.NewFakeCmdOut("kubectl --context kubecontext delete --raw /apis/coordination.k8s.io/v1/namespaces/testNamespace/leases/lock -f -", "", nil),
+	)
+
+	cfg := &v1alpha3.KustomizeDeploy{
+		KustomizePath: ".",
+		DeployLock:    &v1alpha3.DeployLock{Name: "lock"},
+	}
+	k := NewKustomizeDeployer("", cfg, testKubeContext, &config.SkaffoldOptions{Namespace: testNamespace})
+	_, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+
+	testutil.CheckError(t, false, err)
+}
+
+// lostLease fakes a cluster where the deploy lock is taken over right
+// after it was acquired.
+type lostLease struct{}
+
+func (l *lostLease) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	switch command := strings.Join(cmd.Args, " "); {
+	case command == "kubectl --context kubecontext --namespace testNamespace create -f -":
+		return []byte{}, nil
+	case strings.HasPrefix(command, "kubectl --context kubecontext --namespace testNamespace patch lease lock "):
+		return nil, fmt.Errorf("Error from server (Invalid): the server rejected our request due to an error in our request")
+	default:
+		return nil, fmt.Errorf(`Error from server (NotFound): leases.coordination.k8s.io "lock" not found`)
+	}
+}
+
+func (l *lostLease) RunCmd(cmd *exec.Cmd) error {
+	return fmt.Errorf("unexpected command: %s", strings.Join(cmd.Args, " "))
+}
+
+func TestKustomizeDeployLockLost(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = &lostLease{}
+
+	cfg := &v1alpha3.KustomizeDeploy{
+		KustomizePath: ".",
+		DeployLock:    &v1alpha3.DeployLock{Name: "lock", LeaseDuration: "1s"},
+	}
+	k := NewKustomizeDeployer("", cfg, testKubeContext, &config.SkaffoldOptions{Namespace: testNamespace})
+	ctx, release, err := k.acquireLock(context.Background())
+	testutil.CheckError(t, false, err)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("deploy should be cancelled when the lock can't be renewed")
+	}
+	release()
+}
+
+func TestKustomizeDeployLockInvalidLeaseDuration(t *testing.T) {
+	cfg := &v1alpha3.KustomizeDeploy{
+		KustomizePath: ".",
+		DeployLock:    &v1alpha3.DeployLock{Name: "lock", LeaseDuration: "10ms"},
+	}
+	k := NewKustomizeDeployer("", cfg, testKubeContext, &config.SkaffoldOptions{Namespace: testNamespace})
+	_, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+
+	testutil.CheckError(t, true, err)
+}
+
+func TestKustomizeBuildErrorShowsCommand(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmdOut("kustomize build overlays/dev", "", fmt.Errorf("missing base"))
+
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: "overlays/dev"}, testKubeContext, &config.SkaffoldOptions{})
+	_, err := k.readManifests(context.Background())
+
+	testutil.CheckError(t, true, err)
+	if !strings.Contains(err.Error(), "run `kustomize build overlays/dev` in") {
+		t.Errorf("error should show the command to reproduce it, got: %s", err)
+	}
+}
+
+type envRecorder struct {
+	env  []string
+	args []string
+}
+
+func (r *envRecorder) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	r.env = cmd.Env
+	r.args = cmd.Args
+	return nil, nil
+}
+
+func (r *envRecorder) RunCmd(cmd *exec.Cmd) error {
+	return nil
+}
+
+func TestKustomizeBuildEnv(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	recorder := &envRecorder{}
+	util.DefaultExecCommand = recorder
+
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: ".", Env: []string{"PLUGIN_CONFIG=dev"}}, testKubeContext, &config.SkaffoldOptions{})
+	_, err := k.readManifests(context.Background())
+
+	testutil.CheckError(t, false, err)
+	if len(recorder.env) == 0 || recorder.env[len(recorder.env)-1] != "PLUGIN_CONFIG=dev" {
+		t.Errorf("expected PLUGIN_CONFIG=dev to be set last, got %v", recorder.env)
+	}
+}
+
+func TestKustomizeCacheDir(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+	tmpDir.Write("cache/kustomize/remote-base", "")
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	recorder := &envRecorder{}
+	util.DefaultExecCommand = recorder
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: ".", CacheDir: "cache", Env: []string{"PLUGIN_CONFIG=dev"}}, testKubeContext, &config.SkaffoldOptions{RefreshKustomizeCache: true})
+	_, err := k.readManifests(context.Background())
+
+	testutil.CheckError(t, false, err)
+	testutil.CheckDeepEqual(t, []string{"XDG_CACHE_HOME=" + tmpDir.Path("cache"), "PLUGIN_CONFIG=dev"}, recorder.env[len(recorder.env)-2:])
+	if _, err := os.Stat(tmpDir.Path("cache")); !os.IsNotExist(err) {
+		t.Errorf("expected the cache directory to be emptied, got %v", err)
+	}
+}
+
+func TestKustomizePlugins(t *testing.T) {
+	var tests = []struct {
+		description  string
+		plugins      *v1alpha3.KustomizePlugins
+		expectedArgs []string
+		expectedDir  string
+	}{
+		{
+			description:  "no plugins",
+			expectedArgs: []string{"kustomize", "build"},
+		},
+		{
+			description:  "plugin dir",
+			plugins:      &v1alpha3.KustomizePlugins{Dir: "plugins"},
+			expectedArgs: []string{"kustomize", "build", "--enable-alpha-plugins"},
+			expectedDir:  "plugins",
+		},
+		{
+			description:  "exec functions and helm charts",
+			plugins:      &v1alpha3.KustomizePlugins{Exec: true, Helm: true},
+			expectedArgs: []string{"kustomize", "build", "--enable-exec", "--enable-helm"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tmpDir, cleanup := testutil.NewTempDir(t)
+			defer cleanup()
+
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			recorder := &envRecorder{}
+			util.DefaultExecCommand = recorder
+
+			k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: ".", Plugins: test.plugins, Env: []string{"PLUGIN_CONFIG=dev"}}, testKubeContext, &config.SkaffoldOptions{})
+			_, err := k.readManifests(context.Background())
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, append(test.expectedArgs, tmpDir.Root()), recorder.args)
+			if test.expectedDir != "" {
+				testutil.CheckDeepEqual(t, []string{"KUSTOMIZE_PLUGIN_HOME=" + tmpDir.Path(test.expectedDir), "PLUGIN_CONFIG=dev"}, recorder.env[len(recorder.env)-2:])
+			}
+		})
+	}
+}
+
+func TestKustomizeApplyOutputFile(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	err := writeAppliedObjects(tmpDir.Path("applied.json"), []map[string]interface{}{{"kind": "Pod"}})
+	testutil.CheckError(t, false, err)
+
+	written, err := ioutil.ReadFile(tmpDir.Path("applied.json"))
+	testutil.CheckErrorAndDeepEqual(t, false, err, `{
+  "apiVersion": "v1",
+  "items": [
+    {
+      "kind": "Pod"
+    }
+  ],
+  "kind": "List"
+}
+`, string(written))
+}
+
+func TestKustomizeExportConfig(t *testing.T) {
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: "overlays/dev", ServerSideApply: true}, testKubeContext, &config.SkaffoldOptions{})
+	exported, err := k.ExportConfig()
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, `kustomize:
+  kustomizePath: overlays/dev
+  serverSideApply: true
+`, string(exported))
+}
+
+func TestKustomizeDeploySelector(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil)
+
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: "."}, testKubeContext, &config.SkaffoldOptions{Selector: "app=api"})
+	deployed, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(deployed))
+}
+
+// kustomizeApplyRecorder fakes `kustomize build` and records what is applied.
+type kustomizeApplyRecorder struct {
+	built   string
+	applied string
+}
+
+func (r *kustomizeApplyRecorder) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	return []byte(r.built), nil
+}
+
+func (r *kustomizeApplyRecorder) RunCmd(cmd *exec.Cmd) error {
+	in, err := ioutil.ReadAll(cmd.Stdin)
+	r.applied = string(in)
+	return err
+}
+
+func TestKustomizeEmptyBuilds(t *testing.T) {
+	var tests = []struct {
+		description string
+		policy      string
+		devMode     bool
+		shouldErr   bool
+		expected    string
+	}{
+		{
+			description: "proceed by default",
+		},
+		{
+			description: "warn by default in dev",
+			devMode:     true,
+			expected:    "Warning: no images were built, the manifests are deployed with their images unchanged\n",
+		},
+		{
+			description: "proceed in dev",
+			policy:      "proceed",
+			devMode:     true,
+		},
+		{
+			description: "error",
+			policy:      "error",
+			shouldErr:   true,
+		},
+		{
+			description: "invalid",
+			policy:      "ignore",
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: ".", EmptyBuilds: test.policy}, testKubeContext, &config.SkaffoldOptions{DevMode: test.devMode})
+
+			var out bytes.Buffer
+			err := k.checkEmptyBuilds(&out)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, out.String())
+		})
+	}
+}
+
+func TestKustomizeUnqualifiedImages(t *testing.T) {
+	manifests := kubectl.ManifestList{[]byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: leeroy-web
+spec:
+  containers:
+  - image: nginx
+    name: nginx
+`)}
+
+	var out bytes.Buffer
+	err := checkUnqualifiedImages(&out, "warn", manifests)
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Warning: image nginx doesn't name its registry\n", out.String())
+
+	err = checkUnqualifiedImages(&out, "error", manifests)
+	testutil.CheckError(t, true, err)
+
+	err = checkUnqualifiedImages(&out, "error", nil)
+	testutil.CheckError(t, false, err)
+}
+
+func TestKustomizeUnresolvedVars(t *testing.T) {
+	manifests := kubectl.ManifestList{[]byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  url: http://$(SERVICE_NAME)
+`)}
+
+	var out bytes.Buffer
+	err := checkUnresolvedVars(&out, "warn", manifests)
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Warning: unresolved var $(SERVICE_NAME) in configmap/config at data.url\n", out.String())
+
+	err = checkUnresolvedVars(&out, "error", manifests)
+	testutil.CheckError(t, true, err)
+
+	err = checkUnresolvedVars(&out, "error", nil)
+	testutil.CheckError(t, false, err)
+
+	err = checkUnresolvedVars(&out, "ignore", nil)
+	testutil.CheckError(t, true, err)
+}
+
+// kustomizeWithStderr fakes a `kustomize build` that also prints to stderr.
+type kustomizeWithStderr struct {
+	stdout string
+	stderr string
+	err    error
+}
+
+func (k *kustomizeWithStderr) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	fmt.Fprint(cmd.Stderr, k.stderr)
+	return []byte(k.stdout), k.err
+}
+
+func (k *kustomizeWithStderr) RunCmd(cmd *exec.Cmd) error {
+	return nil
+}
+
+func TestKustomizeOutputWithWarnings(t *testing.T) {
+	output, err := ioutil.ReadFile("testdata/warnings/output.yaml")
+	testutil.CheckError(t, false, err)
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = &kustomizeWithStderr{stdout: string(output), stderr: "W1014 06:12:01.123456   4242 plugin.go:42] plugin is deprecated\n"}
+
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: "."}, testKubeContext, &config.SkaffoldOptions{})
+	manifests, err := k.readManifests(context.Background())
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  script: |
+    Hello from the config
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: leeroy-web
+spec:
+  containers:
+  - image: leeroy-web
+    name: leeroy-web`, manifests.String())
+}
+
+func TestKustomizeBuildFailureReportsStderr(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = &kustomizeWithStderr{stderr: "Error: accumulating resources: missing base\n", err: errors.New("exit status 1")}
+
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: "."}, testKubeContext, &config.SkaffoldOptions{})
+	_, err := k.readManifests(context.Background())
+
+	testutil.CheckError(t, true, err)
+	if !strings.Contains(err.Error(), "Error: accumulating resources: missing base: exit status 1") {
+		t.Errorf("expected the error to report stderr, got %v", err)
+	}
+}
+
+func TestSplitKustomizeWarnings(t *testing.T) {
+	manifests, warnings := splitKustomizeWarnings([]byte("# Warning: deprecated\nWarning: deprecated\n---\napiVersion: v1\nkind: Pod\n"))
+
+	testutil.CheckDeepEqual(t, "---\napiVersion: v1\nkind: Pod\n", string(manifests))
+	testutil.CheckDeepEqual(t, []string{"# Warning: deprecated", "Warning: deprecated"}, warnings)
+
+	manifests, warnings = splitKustomizeWarnings([]byte("apiVersion: v1\nkind: ConfigMap\ndata:\n  Key: Value\n"))
+
+	testutil.CheckDeepEqual(t, "apiVersion: v1\nkind: ConfigMap\ndata:\n  Key: Value\n", string(manifests))
+	testutil.CheckDeepEqual(t, []string(nil), warnings)
+
+	manifests, warnings = splitKustomizeWarnings([]byte("metadata:\n  name: unusual\n"))
+
+	testutil.CheckDeepEqual(t, "metadata:\n  name: unusual\n", string(manifests))
+	testutil.CheckDeepEqual(t, []string(nil), warnings)
+}
+
+func TestKustomizeDevProbesOnlyInDev(t *testing.T) {
+	var tests = []struct {
+		description string
+		devMode     bool
+		expected    string
+	}{
+		{
+			description: "dev",
+			devMode:     true,
+			expected:    "initialDelaySeconds: 0",
+		},
+		{
+			description: "not dev",
+			expected:    "initialDelaySeconds: 60",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			recorder := &kustomizeApplyRecorder{built: `apiVersion: v1
+kind: Pod
+metadata:
+  name: leeroy-web
+spec:
+  containers:
+  - image: leeroy-web
+    name: leeroy-web
+    readinessProbe:
+      initialDelaySeconds: 60
+`}
+			util.DefaultExecCommand = recorder
+
+			zero := 0
+			cfg := &v1alpha3.KustomizeDeploy{KustomizePath: ".", DevProbes: &v1alpha3.DevProbes{InitialDelaySeconds: &zero}}
+			k := NewKustomizeDeployer("", cfg, testKubeContext, &config.SkaffoldOptions{DevMode: test.devMode})
+			_, err := k.Deploy(context.Background(), ioutil.Discard, []build.Artifact{{ImageName: "leeroy-web", Tag: "leeroy-web:v1"}})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, true, strings.Contains(recorder.applied, test.expected))
+		})
+	}
+}
+
+// slowCmd fakes commands that take longer than the deploy timeout.
+type slowCmd struct {
+	out string
+}
+
+func (s *slowCmd) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	return []byte(s.out), nil
+}
+
+func (s *slowCmd) RunCmd(cmd *exec.Cmd) error {
+	time.Sleep(50 * time.Millisecond)
+	return errors.New("signal: killed")
+}
+
+func TestKustomizeDeployTimeout(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = &slowCmd{out: deploymentWebYAML}
+
+	cfg := &v1alpha3.KustomizeDeploy{KustomizePath: ".", DeployTimeout: "10ms"}
+	k := NewKustomizeDeployer("", cfg, testKubeContext, &config.SkaffoldOptions{})
+	_, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+
+	testutil.CheckError(t, true, err)
+	if !strings.HasPrefix(err.Error(), "deploy timed out after 10ms: apply:") {
+		t.Errorf("error should tell the phase that timed out, got: %s", err)
+	}
+}
+
+func TestKustomizePlan(t *testing.T) {
+	// The api resources are cached per kube context: this one is only used here.
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML+`
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: leeroy-web
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: manual
+  annotations:
+    skaffold.dev/skip: "true"
+`, nil),
+		testutil.NewFakeCmdOut("kubectl --context plan get --ignore-not-found=true -o yaml -f -", `apiVersion: v1
+kind: List
+items:
+- {apiVersion: v1, kind: Pod, metadata: {namespace: testNamespace, name: leeroy-web}}
+- {apiVersion: v1, kind: Service, metadata: {namespace: testNamespace, name: leeroy-web}}
+`, nil),
+		testutil.NewFakeCmdOut("kubectl --context plan diff -f -", "diff -u -N /tmp/LIVE-1/v1.Pod.testNamespace.leeroy-web /tmp/MERGED-2/v1.Pod.testNamespace.leeroy-web\n-    image: leeroy-web:v1\n+    image: leeroy-web:v2\n", fmt.Errorf("exit status 1")),
+		testutil.NewFakeCmdOut("kubectl --context plan api-resources --namespaced=true --verbs=delete,list -o name", "pods\ndeployments.apps\n", nil),
+		testutil.NewFakeCmdOut("kubectl --context plan --namespace testNamespace get pods,deployments.apps -l skaffold-deployer=kustomize,skaffold-kustomization="+kustomizationID(".")+" -o yaml", `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Pod
+  metadata: {namespace: testNamespace, name: leeroy-web, labels: {skaffold-deployer: kustomize, skaffold-kustomization: `+kustomizationID(".")+`}}
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata: {namespace: testNamespace, name: old, labels: {skaffold-deployer: kustomize, skaffold-kustomization: `+kustomizationID(".")+`}}
+`, nil),
+	)
+
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: ".", Lint: &v1alpha3.Lint{Disable: []string{"latest-tag", "liveness-probe"}}}, "plan", &config.SkaffoldOptions{Namespace: testNamespace})
+	plan, err := k.Plan(context.Background(), ioutil.Discard, []build.Artifact{{ImageName: "leeroy-web", Tag: "leeroy-web:v2"}})
+	testutil.CheckError(t, false, err)
+
+	var out bytes.Buffer
+	err = plan.Write(&out, "text")
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, `Images:
+  leeroy-web -> leeroy-web:v2
+Resources:
+  + configmap/config in namespace testNamespace
+  ~ pod/leeroy-web in namespace testNamespace
+  = service/leeroy-web in namespace testNamespace
+  ! configmap/manual
+  - deployment.apps/old in namespace testNamespace
+Warnings:
+  pod/leeroy-web: container leeroy-web has no resource limits (resource-limits)
+`, out.String())
+}
+
+func TestKustomizePrune(t *testing.T) {
+	// The api resources are cached per kube context: this one is only used here.
+	id := kustomizationID(".")
+	kustomizationLabels := "{skaffold-deployer: kustomize, skaffold-kustomization: " + id + "}"
+
+	var tests = []struct {
+		description string
+		cfg         v1alpha3.KustomizeDeploy
+		command     util.Command
+	}{
+		{
+			description: "not pruned by default",
+			cfg:         v1alpha3.KustomizeDeploy{KustomizePath: ".", ServerSideApply: true, PruneFieldManager: "skaffold"},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmdOut("kubectl --context prune get --ignore-not-found=true -o name -f -", "", nil),
+				testutil.NewFakeCmd("kubectl --context prune apply --server-side --field-manager=skaffold -f -", nil),
+			),
+		},
+		{
+			description: "foreign resources of the namespace are kept",
+			cfg:         v1alpha3.KustomizeDeploy{KustomizePath: ".", Prune: true},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context prune apply -f -", nil),
+				testutil.NewFakeCmdOut("kubectl --context prune api-resources --namespaced=true --verbs=delete,list -o name", "pods\nconfigmaps\n", nil),
+				testutil.NewFakeCmdOut("kubectl --context prune --namespace testNamespace get pods,configmaps -l skaffold-deployer=kustomize,skaffold-kustomization="+id+" -o yaml", `apiVersion: v1
+kind: List
+items:
+- {apiVersion: v1, kind: Pod, metadata: {namespace: testNamespace, name: leeroy-web, labels: `+kustomizationLabels+`}}
+- {apiVersion: v1, kind: ConfigMap, metadata: {namespace: testNamespace, name: old, labels: `+kustomizationLabels+`}}
+- {apiVersion: v1, kind: ConfigMap, metadata: {namespace: testNamespace, name: foreign, labels: {skaffold-deployer: kustomize, skaffold-kustomization: other}}}
+`, nil),
+				testutil.NewFakeCmd("kubectl --context prune --namespace testNamespace delete --ignore-not-found=true configmap/old", nil),
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			k := NewKustomizeDeployer("", &test.cfg, "prune", &config.SkaffoldOptions{Namespace: testNamespace})
+			_, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			testutil.CheckError(t, false, err)
+		})
+	}
+}
+
+func TestKustomizeRemoteTarget(t *testing.T) {
+	remote := "github.com/org/repo//deploy?ref=v1"
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kustomize build "+remote, deploymentWebYAML, nil),
+		testutil.NewFakeCmdOut("kustomize build "+remote, "", fmt.Errorf("must build at directory")),
+	)
+
+	k := NewKustomizeDeployer("config", &v1alpha3.KustomizeDeploy{KustomizePath: remote}, testKubeContext, &config.SkaffoldOptions{})
+
+	deps, err := k.Dependencies()
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(deps))
+
+	manifests, err := k.readManifests(context.Background())
+	testutil.CheckErrorAndDeepEqual(t, false, err, 1, len(manifests))
+
+	// Remote targets are never cached.
+	_, err = k.readManifests(context.Background())
+	testutil.CheckError(t, true, err)
+	if !strings.Contains(err.Error(), "must support remote targets") {
+		t.Errorf("error should say remote targets must be supported, got: %s", err)
+	}
+}
+
+func TestKustomizeImageOverrides(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	recorder := &kustomizeApplyRecorder{built: deploymentWebYAML}
+	util.DefaultExecCommand = recorder
+
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: "."}, testKubeContext, &config.SkaffoldOptions{ImageOverrides: []string{"leeroy-web=staging/leeroy-web:test"}})
+	_, err := k.Deploy(context.Background(), ioutil.Discard, []build.Artifact{{ImageName: "leeroy-web", Tag: "leeroy-web:built"}})
+
+	testutil.CheckError(t, false, err)
+	if !strings.Contains(recorder.applied, "image: staging/leeroy-web:test") {
+		t.Errorf("expected the override to be applied, got: %s", recorder.applied)
+	}
+}
+
+// fixedLabels is a Labeller of fixed labels.
+type fixedLabels map[string]string
+
+func (l fixedLabels) Labels() map[string]string { return l }
+
+func TestKustomizeLabelInjection(t *testing.T) {
+	var tests = []struct {
+		description string
+		injection   string
+		expected    string
+		shouldErr   bool
+	}{
+		{
+			description: "before images, labels can be stripped",
+			injection:   LabelsBeforeImages,
+			expected: `apiVersion: v1
+kind: Pod
+metadata:
+  name: leeroy-web
+  labels:
+    deployed-with: skaffold
+spec:
+  containers:
+  - image: leeroy-web:v1
+    name: leeroy-web`,
+		},
+		{
+			description: "after transforms",
+			injection:   LabelsAfterTransforms,
+			expected: `apiVersion: v1
+kind: Pod
+metadata:
+  name: leeroy-web
+  labels:
+    deployed-with: skaffold
+    skaffold-deployer: kustomize
+spec:
+  containers:
+  - image: leeroy-web:v1
+    name: leeroy-web`,
+		},
+		{
+			description: "invalid",
+			injection:   "first",
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			recorder := &kustomizeApplyRecorder{built: `apiVersion: v1
+kind: Pod
+metadata:
+  name: leeroy-web
+spec:
+  containers:
+  - image: leeroy-web
+    name: leeroy-web
+`}
+			util.DefaultExecCommand = recorder
+
+			cfg := &v1alpha3.KustomizeDeploy{KustomizePath: ".", LabelInjection: test.injection, StripFields: []string{"metadata.labels.skaffold-deployer"}}
+			deployer := WithLabels(NewKustomizeDeployer("", cfg, testKubeContext, &config.SkaffoldOptions{}), fixedLabels{"skaffold-deployer": "kustomize"})
+			_, err := deployer.Deploy(context.Background(), ioutil.Discard, []build.Artifact{{ImageName: "leeroy-web", Tag: "leeroy-web:v1"}})
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, recorder.applied)
+		})
+	}
+}
+
+func TestKustomizeApplyKinds(t *testing.T) {
+	rendered := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: token
+---
+` + deploymentWebYAML
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	recorder := &kustomizeApplyRecorder{built: rendered}
+	util.DefaultExecCommand = recorder
+
+	cfg := &v1alpha3.KustomizeDeploy{KustomizePath: ".", ApplyKinds: []string{"configmap", "Secret"}}
+	k := NewKustomizeDeployer("", cfg, testKubeContext, &config.SkaffoldOptions{})
+
+	var out bytes.Buffer
+	_, err := k.Deploy(context.Background(), &out, nil)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "Skipping 1 resources whose kind is not one of configmap, Secret\n", out.String())
+	testutil.CheckDeepEqual(t, false, strings.Contains(recorder.applied, "Deployment"))
+	testutil.CheckDeepEqual(t, true, strings.Contains(recorder.applied, "name: settings"))
+	testutil.CheckDeepEqual(t, true, strings.Contains(recorder.applied, "name: token"))
+}
+
+func TestKustomizeValidateSelectors(t *testing.T) {
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kustomize build .", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: webapp
+`, nil),
+	)
+
+	cfg := &v1alpha3.KustomizeDeploy{KustomizePath: ".", ValidateSelectors: true}
+	k := NewKustomizeDeployer("", cfg, testKubeContext, &config.SkaffoldOptions{})
+	_, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+
+	testutil.CheckError(t, true, err)
+	testutil.CheckDeepEqual(t, "1 workloads have a selector that doesn't match their pod template:\n - deployment/web: the selector doesn't match the pod template labels: app=web (the template has app=webapp)", err.Error())
+}
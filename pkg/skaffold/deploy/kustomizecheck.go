@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// checkKustomizations quickly checks the structure of the kustomizations
+// before they are built, to report mistakes earlier than kustomize would.
+// It only warns: kustomize has the last word on what is valid.
+func (k *KustomizeDeployer) checkKustomizations(out io.Writer) {
+	paths, err := k.kustomizePaths()
+	if err != nil {
+		logrus.Debugln("Unable to list the kustomizations to check:", err)
+		return
+	}
+
+	for _, path := range paths {
+		if isRemoteKustomization(path) {
+			continue
+		}
+
+		for _, problem := range kustomizationProblems(path) {
+			color.Yellow.Fprintln(out, "Warning:", problem)
+		}
+	}
+}
+
+// kustomizationProblems lists what looks wrong in a kustomization.
+func kustomizationProblems(dir string) []string {
+	path := filepath.Join(dir, "kustomization.yaml")
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("unable to read %s: %v", path, err)}
+	}
+
+	var contents kustomization
+	if err := yaml.Unmarshal(buf, &contents); err != nil {
+		return []string{fmt.Sprintf("%s is not a valid kustomization: %v", path, err)}
+	}
+
+	var problems []string
+	if len(contents.Bases)+len(contents.Resources)+len(contents.Components)+len(contents.HelmCharts)+len(contents.ConfigMapGenerator)+len(contents.SecretGenerator) == 0 {
+		problems = append(problems, fmt.Sprintf("%s doesn't list any resources", path))
+	}
+
+	problems = append(problems, duplicates(path, "bases and resources", contents.Bases, contents.Resources)...)
+
+	var patchPaths []string
+	for _, patch := range contents.Patches {
+		if patch.Path != "" {
+			patchPaths = append(patchPaths, patch.Path)
+		}
+	}
+	problems = append(problems, duplicates(path, "patches and patchesStrategicMerge", patchPaths, contents.PatchesStrategicMerge)...)
+
+	deps, _ := dependenciesForKustomization(dir)
+	for _, dep := range deps {
+		if _, err := os.Stat(dep); os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("%s references %s, which doesn't exist", path, dep))
+		}
+	}
+
+	return problems
+}
+
+// duplicates reports the entries listed in two fields, which kustomize would
+// apply twice.
+func duplicates(path, fields string, first, second []string) []string {
+	listed := map[string]bool{}
+	for _, entry := range first {
+		listed[filepath.Clean(entry)] = true
+	}
+
+	var problems []string
+	for _, entry := range second {
+		if listed[filepath.Clean(entry)] {
+			problems = append(problems, fmt.Sprintf("%s lists %s in both %s", path, entry, fields))
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestKustomizationProblems(t *testing.T) {
+	var tests = []struct {
+		description   string
+		kustomization string
+		expected      []string
+	}{
+		{
+			description:   "valid",
+			kustomization: "resources: [deployment.yaml]\npatches: [patch.yaml]",
+		},
+		{
+			description:   "generators only",
+			kustomization: "configMapGenerator:\n- name: config\n  literals: [key=value]",
+		},
+		{
+			description:   "no resources",
+			kustomization: "namePrefix: dev-",
+			expected:      []string{"{{kustomization}} doesn't list any resources"},
+		},
+		{
+			description:   "missing file",
+			kustomization: "resources: [deployment.yaml, service.yaml]",
+			expected:      []string{"{{kustomization}} references {{dir}}/service.yaml, which doesn't exist"},
+		},
+		{
+			description:   "same patch twice",
+			kustomization: "resources: [deployment.yaml]\npatches: [patch.yaml]\npatchesStrategicMerge: [./patch.yaml]",
+			expected:      []string{"{{kustomization}} lists ./patch.yaml in both patches and patchesStrategicMerge"},
+		},
+		{
+			description:   "invalid yaml",
+			kustomization: "resources: {",
+			expected:      []string{"{{kustomization}} is not a valid kustomization: yaml: line 1: did not find expected node content"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tmpDir, cleanup := testutil.NewTempDir(t)
+			defer cleanup()
+
+			tmpDir.Write("kustomization.yaml", test.kustomization).
+				Write("deployment.yaml", "").
+				Write("patch.yaml", "")
+
+			var expected []string
+			for _, problem := range test.expected {
+				problem = strings.Replace(problem, "{{kustomization}}", tmpDir.Path("kustomization.yaml"), -1)
+				expected = append(expected, strings.Replace(problem, "{{dir}}", tmpDir.Root(), -1))
+			}
+
+			testutil.CheckDeepEqual(t, expected, kustomizationProblems(tmpDir.Root()))
+		})
+	}
+}
+
+func TestKustomizeCheckKustomizationsOnlyWarns(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("kustomization.yaml", "resources: [deployment.yaml]")
+
+	k := NewKustomizeDeployer(tmpDir.Root(), &v1alpha3.KustomizeDeploy{KustomizePath: ".", CheckKustomizations: true}, testKubeContext, &config.SkaffoldOptions{})
+
+	var out bytes.Buffer
+	k.checkKustomizations(&out)
+
+	testutil.CheckDeepEqual(t, "Warning: "+tmpDir.Path("kustomization.yaml")+" references "+tmpDir.Path("deployment.yaml")+", which doesn't exist\n", out.String())
+}
@@ -60,10 +60,22 @@ func WithLabels(d Deployer, labellers ...Labeller) Deployer {
 	}
 }
 
+// labelInjector is implemented by the deployers that can set the labels
+// in the manifests they apply, rather than on the deployed resources.
+type labelInjector interface {
+	// injectLabels returns true if the deployer sets the labels itself.
+	injectLabels(labels map[string]string) bool
+}
+
 func (w *withLabels) Deploy(ctx context.Context, out io.Writer, artifacts []build.Artifact) ([]Artifact, error) {
+	labels := merge(w.labellers...)
+	if injector, ok := w.Deployer.(labelInjector); ok && injector.injectLabels(labels) {
+		return w.Deployer.Deploy(ctx, out, artifacts)
+	}
+
 	dRes, err := w.Deployer.Deploy(ctx, out, artifacts)
 
-	labelDeployResults(merge(w.labellers...), dRes)
+	labelDeployResults(labels, dRes)
 
 	return dRes, err
 }
@@ -138,6 +150,13 @@ func updateRuntimeObject(client dynamic.Interface, disco discovery.DiscoveryInte
 		return nil
 	}
 
+	// Labels owned by another controller or webhook would be reverted
+	// right away and patched again on every deploy.
+	if accessor.GetAnnotations()[constants.SkipLabelsAnnotation] == "true" {
+		logrus.Debugf("Labels are not applied to [%s] because it is annotated with %s", name, constants.SkipLabelsAnnotation)
+		return nil
+	}
+
 	namespace := res.Namespace
 	addLabels(labels, accessor)
 
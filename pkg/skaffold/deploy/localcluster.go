@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/constants"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	localClusterAuto     = "auto"
+	localClusterKind     = "kind"
+	localClusterMinikube = "minikube"
+
+	// kindContextPrefix prefixes the kube contexts created by kind.
+	kindContextPrefix = "kind-"
+)
+
+// localClusterType returns the type of local cluster, either declared by
+// the user or detected from the kube context.
+func localClusterType(declared string, kubeContext string) (string, error) {
+	switch declared {
+	case localClusterKind, localClusterMinikube:
+		return declared, nil
+	case localClusterAuto:
+		if kubeContext == constants.DefaultMinikubeContext {
+			return localClusterMinikube, nil
+		}
+		if strings.HasPrefix(kubeContext, kindContextPrefix) {
+			return localClusterKind, nil
+		}
+		return "", fmt.Errorf("unable to detect the type of local cluster from kube context %s", kubeContext)
+	default:
+		return "", fmt.Errorf("invalid local cluster %q: should be one of auto, kind or minikube", declared)
+	}
+}
+
+// loadImages loads the built images into the local cluster so that they
+// don't need to be pushed to a registry.
+func loadImages(ctx context.Context, out io.Writer, clusterType string, kubeContext string, builds []build.Artifact) error {
+	for _, b := range builds {
+		var cmd *exec.Cmd
+		switch clusterType {
+		case localClusterKind:
+			cmd = exec.CommandContext(ctx, "kind", "load", "docker-image", b.Tag, "--name", strings.TrimPrefix(kubeContext, kindContextPrefix))
+		case localClusterMinikube:
+			cmd = exec.CommandContext(ctx, "minikube", "image", "load", b.Tag, "--profile", kubeContext)
+		}
+		cmd.Stdout = out
+		cmd.Stderr = out
+
+		color.Default.Fprintln(out, "Loading image", b.Tag, "into", clusterType)
+		if err := util.RunCmd(cmd); err != nil {
+			return errors.Wrapf(err, "loading image %s into %s", b.Tag, clusterType)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestLocalClusterType(t *testing.T) {
+	var tests = []struct {
+		description string
+		declared    string
+		kubeContext string
+		shouldErr   bool
+		expected    string
+	}{
+		{
+			description: "declared",
+			declared:    "kind",
+			kubeContext: "other",
+			expected:    "kind",
+		},
+		{
+			description: "detect minikube",
+			declared:    "auto",
+			kubeContext: "minikube",
+			expected:    "minikube",
+		},
+		{
+			description: "detect kind",
+			declared:    "auto",
+			kubeContext: "kind-dev",
+			expected:    "kind",
+		},
+		{
+			description: "unable to detect",
+			declared:    "auto",
+			kubeContext: "gke_project_zone_cluster",
+			shouldErr:   true,
+		},
+		{
+			description: "invalid",
+			declared:    "k3d",
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			clusterType, err := localClusterType(test.declared, test.kubeContext)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, clusterType)
+		})
+	}
+}
+
+func TestLoadImages(t *testing.T) {
+	builds := []build.Artifact{{ImageName: "leeroy-web", Tag: "leeroy-web:v1"}}
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+
+	util.DefaultExecCommand = testutil.NewFakeCmd("kind load docker-image leeroy-web:v1 --name dev", nil)
+	err := loadImages(context.Background(), ioutil.Discard, "kind", "kind-dev", builds)
+	testutil.CheckError(t, false, err)
+
+	util.DefaultExecCommand = testutil.NewFakeCmd("minikube image load leeroy-web:v1 --profile minikube", nil)
+	err = loadImages(context.Background(), ioutil.Discard, "minikube", "minikube", builds)
+	testutil.CheckError(t, false, err)
+}
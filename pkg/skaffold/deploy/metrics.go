@@ -0,0 +1,38 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"time"
+)
+
+// Names of the durations observed during a deploy.
+const (
+	MetricReadManifests = "deploy_read_manifests_duration_seconds"
+	MetricReplaceImages = "deploy_replace_images_duration_seconds"
+	MetricApply         = "deploy_apply_duration_seconds"
+)
+
+// MetricsSink records metrics about deploys. It can be implemented
+// to forward the metrics to any backend, Prometheus for example.
+type MetricsSink interface {
+	ObserveDuration(name string, labels map[string]string, duration time.Duration)
+}
+
+type noopMetricsSink struct{}
+
+func (noopMetricsSink) ObserveDuration(string, map[string]string, time.Duration) {}
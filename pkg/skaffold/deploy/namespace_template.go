@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// resolveNamespace computes the namespace from the namespace template,
+// unless a namespace was given on the command line. The namespace is
+// resolved again on every deploy and cleanup, so that both target the same one.
+func (k *KustomizeDeployer) resolveNamespace(ctx context.Context) error {
+	if k.NamespaceTemplate == "" || k.namespaceFlag != "" {
+		return nil
+	}
+
+	namespace, err := executeNamespaceTemplate(k.NamespaceTemplate, namespaceMetadata(ctx, k.workingDir))
+	if err != nil {
+		return err
+	}
+
+	k.kubectl.Namespace = namespace
+	k.crdsKubectl.Namespace = namespace
+	return nil
+}
+
+// createNamespace creates the namespace resolved from the template, if needed.
+func (k *KustomizeDeployer) createNamespace(ctx context.Context, out io.Writer) error {
+	if !k.CreateNamespace || k.kubectl.Namespace == "" {
+		return nil
+	}
+
+	return k.kubectl.EnsureNamespace(ctx, out, k.kubectl.Namespace)
+}
+
+// executeNamespaceTemplate resolves a namespace template against the environment
+// and the metadata. Referencing a value that is not available is an error.
+func executeNamespaceTemplate(namespaceTemplate string, metadata map[string]string) (string, error) {
+	tmpl, err := util.ParseEnvTemplate(namespaceTemplate)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing namespace template %q", namespaceTemplate)
+	}
+	tmpl.Option("missingkey=error")
+
+	namespace, err := util.ExecuteEnvTemplate(tmpl, metadata)
+	if err != nil {
+		return "", errors.Wrapf(err, "resolving namespace template %q", namespaceTemplate)
+	}
+
+	if problems := validation.IsDNS1123Label(namespace); len(problems) > 0 {
+		return "", errors.Errorf("namespace template %q resolves to an invalid namespace %q: %s", namespaceTemplate, namespace, strings.Join(problems, ", "))
+	}
+
+	return namespace, nil
+}
+
+// namespaceMetadata returns the git metadata of the working directory
+// that namespace templates can use. Outside a git repository, there is none.
+func namespaceMetadata(ctx context.Context, workingDir string) map[string]string {
+	metadata := map[string]string{}
+
+	git := func(key string, args ...string) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = workingDir
+
+		out, err := util.RunCmdOut(cmd)
+		if err != nil {
+			logrus.Debugln("Unable to read", key, "from git:", err)
+			return
+		}
+		metadata[key] = string(bytes.TrimSpace(out))
+	}
+
+	git("GIT_COMMIT", "rev-parse", "HEAD")
+	git("GIT_SHORT_COMMIT", "rev-parse", "--short", "HEAD")
+	git("GIT_BRANCH", "rev-parse", "--abbrev-ref", "HEAD")
+
+	return metadata
+}
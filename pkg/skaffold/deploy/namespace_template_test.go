@@ -0,0 +1,180 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"github.com/pkg/errors"
+)
+
+func TestExecuteNamespaceTemplate(t *testing.T) {
+	var tests = []struct {
+		description string
+		template    string
+		metadata    map[string]string
+		expected    string
+		shouldErr   bool
+	}{
+		{
+			description: "environment variable",
+			template:    "pr-{{.PR}}",
+			expected:    "pr-42",
+		},
+		{
+			description: "git metadata",
+			template:    "{{.GIT_BRANCH}}-{{.GIT_SHORT_COMMIT}}",
+			metadata:    map[string]string{"GIT_BRANCH": "main", "GIT_SHORT_COMMIT": "abcdef"},
+			expected:    "main-abcdef",
+		},
+		{
+			description: "unavailable metadata",
+			template:    "pr-{{.PR_NUMBER}}",
+			shouldErr:   true,
+		},
+		{
+			description: "invalid namespace",
+			template:    "{{.BRANCH}}",
+			shouldErr:   true,
+		},
+		{
+			description: "invalid template",
+			template:    "pr-{{.PR",
+			shouldErr:   true,
+		},
+	}
+
+	defer func(e func() []string) { util.OSEnviron = e }(util.OSEnviron)
+	util.OSEnviron = func() []string {
+		return []string{"PR=42", "BRANCH=feature/Login"}
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			namespace, err := executeNamespaceTemplate(test.template, test.metadata)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, namespace)
+		})
+	}
+}
+
+func TestKustomizeNamespaceTemplate(t *testing.T) {
+	noGit := errors.New("not a git repository")
+
+	var tests = []struct {
+		description string
+		cfg         v1alpha3.KustomizeDeploy
+		namespace   string
+		command     util.Command
+		expected    string
+		expectedOut string
+		shouldErr   bool
+	}{
+		{
+			description: "create missing namespace",
+			cfg:         v1alpha3.KustomizeDeploy{NamespaceTemplate: "pr-{{.PR}}", CreateNamespace: true},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("git rev-parse HEAD", "", noGit),
+				testutil.NewFakeCmdOut("git rev-parse --short HEAD", "", noGit),
+				testutil.NewFakeCmdOut("git rev-parse --abbrev-ref HEAD", "", noGit),
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmdOut("kubectl --context kubecontext get namespace/pr-42 --ignore-not-found=true -o name", "", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext create namespace pr-42", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+			),
+			expected:    "pr-42",
+			expectedOut: "Creating namespace pr-42\n",
+		},
+		{
+			description: "lock in the created namespace",
+			cfg:         v1alpha3.KustomizeDeploy{NamespaceTemplate: "pr-{{.PR}}", CreateNamespace: true, DeployLock: &v1alpha3.DeployLock{Name: "lock"}},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("git rev-parse HEAD", "", noGit),
+				testutil.NewFakeCmdOut("git rev-parse --short HEAD", "", noGit),
+				testutil.NewFakeCmdOut("git rev-parse --abbrev-ref HEAD", "", noGit),
+				testutil.NewFakeCmdOut("kubectl --context kubecontext get namespace/pr-42 --ignore-not-found=true -o name", "", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext create namespace pr-42", nil),
+				testutil.NewFakeCmdOut("kubectl --context kubecontext --namespace pr-42 create -f -", "", nil),
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+				testutil.NewFakeCmdOut("kubectl --context kubecontext --namespace pr-42 get lease lock -o yaml", "", errors.New(`Error from server (NotFound): leases.coordination.k8s.io "lock" not found`)),
+			),
+			expected:    "pr-42",
+			expectedOut: "Creating namespace pr-42\n",
+		},
+		{
+			description: "existing namespace",
+			cfg:         v1alpha3.KustomizeDeploy{NamespaceTemplate: "{{.GIT_BRANCH}}", CreateNamespace: true},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("git rev-parse HEAD", "abcdef0123\n", nil),
+				testutil.NewFakeCmdOut("git rev-parse --short HEAD", "abcdef\n", nil),
+				testutil.NewFakeCmdOut("git rev-parse --abbrev-ref HEAD", "main\n", nil),
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmdOut("kubectl --context kubecontext get namespace/main --ignore-not-found=true -o name", "namespace/main\n", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+			),
+			expected: "main",
+		},
+		{
+			description: "namespace flag takes precedence",
+			cfg:         v1alpha3.KustomizeDeploy{NamespaceTemplate: "pr-{{.PR}}"},
+			namespace:   "staging",
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+			),
+			expected: "staging",
+		},
+		{
+			description: "unavailable metadata",
+			cfg:         v1alpha3.KustomizeDeploy{NamespaceTemplate: "pr-{{.PR_NUMBER}}"},
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("git rev-parse HEAD", "", noGit),
+				testutil.NewFakeCmdOut("git rev-parse --short HEAD", "", noGit),
+				testutil.NewFakeCmdOut("git rev-parse --abbrev-ref HEAD", "", noGit),
+			),
+			shouldErr: true,
+		},
+	}
+
+	defer func(e func() []string) { util.OSEnviron = e }(util.OSEnviron)
+	util.OSEnviron = func() []string {
+		return []string{"PR=42"}
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			test.cfg.KustomizePath = "."
+			k := NewKustomizeDeployer("", &test.cfg, testKubeContext, &config.SkaffoldOptions{Namespace: test.namespace})
+
+			var out bytes.Buffer
+			_, err := k.Deploy(context.Background(), &out, nil)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expectedOut, out.String())
+			testutil.CheckDeepEqual(t, test.expected, k.kubectl.Namespace)
+		})
+	}
+}
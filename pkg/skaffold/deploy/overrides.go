@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+)
+
+// overrideImages adds the `name=image` overrides given on the command line
+// to the builds. An override replaces the build of the same image name.
+func overrideImages(builds []build.Artifact, overrides []string) ([]build.Artifact, error) {
+	if len(overrides) == 0 {
+		return builds, nil
+	}
+
+	tags := map[string]string{}
+	var names []string
+	for _, override := range overrides {
+		parts := strings.SplitN(override, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid image override %q: should be name=image", override)
+		}
+
+		if _, found := tags[parts[0]]; !found {
+			names = append(names, parts[0])
+		}
+		tags[parts[0]] = parts[1]
+	}
+
+	var overridden []build.Artifact
+	for _, b := range builds {
+		if _, found := tags[b.ImageName]; !found {
+			overridden = append(overridden, b)
+		}
+	}
+	for _, name := range names {
+		overridden = append(overridden, build.Artifact{ImageName: name, Tag: tags[name]})
+	}
+
+	return overridden, nil
+}
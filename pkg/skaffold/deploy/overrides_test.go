@@ -0,0 +1,73 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestOverrideImages(t *testing.T) {
+	builds := []build.Artifact{
+		{ImageName: "api", Tag: "api:built"},
+		{ImageName: "web", Tag: "web:built"},
+	}
+
+	var tests = []struct {
+		description string
+		overrides   []string
+		expected    []build.Artifact
+		shouldErr   bool
+	}{
+		{
+			description: "no overrides",
+			expected:    builds,
+		},
+		{
+			description: "override takes precedence over build",
+			overrides:   []string{"api=myrepo/api:test", "db=postgres:10"},
+			expected: []build.Artifact{
+				{ImageName: "web", Tag: "web:built"},
+				{ImageName: "api", Tag: "myrepo/api:test"},
+				{ImageName: "db", Tag: "postgres:10"},
+			},
+		},
+		{
+			description: "last override wins",
+			overrides:   []string{"api=myrepo/api:v1", "api=myrepo/api:v2"},
+			expected: []build.Artifact{
+				{ImageName: "web", Tag: "web:built"},
+				{ImageName: "api", Tag: "myrepo/api:v2"},
+			},
+		},
+		{
+			description: "invalid override",
+			overrides:   []string{"myrepo/api:test"},
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			overridden, err := overrideImages(builds, test.overrides)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, overridden)
+		})
+	}
+}
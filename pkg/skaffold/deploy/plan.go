@@ -0,0 +1,126 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+)
+
+// Plan actions.
+const (
+	ActionAdd       = "add"
+	ActionChange    = "change"
+	ActionUnchanged = "unchanged"
+	ActionSkip      = "skip"
+	ActionRemove    = "remove"
+)
+
+// Plan describes what a deploy would change, for review.
+type Plan struct {
+	Images    []ImageSubstitution `json:"images"`
+	Resources []PlannedResource   `json:"resources"`
+	Warnings  []string            `json:"warnings,omitempty"`
+}
+
+// ImageSubstitution is an image of the manifests replaced by a built tag.
+type ImageSubstitution struct {
+	Image string `json:"image"`
+	Tag   string `json:"tag"`
+}
+
+// PlannedResource is a resource and what a deploy would do to it.
+// Resources to remove were labelled by a previous deploy but are not
+// rendered anymore.
+type PlannedResource struct {
+	Action    string `json:"action"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// Planner is implemented by the deployers that can plan a deploy
+// without changing the cluster.
+type Planner interface {
+	Plan(ctx context.Context, out io.Writer, builds []build.Artifact) (*Plan, error)
+}
+
+// Write prints the plan as `text` or `json`.
+func (p *Plan) Write(out io.Writer, format string) error {
+	switch format {
+	case "", "text":
+		p.writeText(out)
+		return nil
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(p)
+	default:
+		return fmt.Errorf("unknown plan format %q: should be text or json", format)
+	}
+}
+
+var actionSymbols = map[string]string{
+	ActionAdd:       "+",
+	ActionChange:    "~",
+	ActionUnchanged: "=",
+	ActionSkip:      "!",
+	ActionRemove:    "-",
+}
+
+func (p *Plan) writeText(out io.Writer) {
+	fmt.Fprintln(out, "Images:")
+	for _, image := range p.Images {
+		fmt.Fprintf(out, "  %s -> %s\n", image.Image, image.Tag)
+	}
+
+	fmt.Fprintln(out, "Resources:")
+	for _, r := range p.Resources {
+		name := strings.ToLower(r.Kind) + "/" + r.Name
+		if r.Namespace != "" {
+			name += " in namespace " + r.Namespace
+		}
+		fmt.Fprintf(out, "  %s %s\n", actionSymbols[r.Action], name)
+	}
+
+	if len(p.Warnings) > 0 {
+		fmt.Fprintln(out, "Warnings:")
+		for _, warning := range p.Warnings {
+			fmt.Fprintf(out, "  %s\n", warning)
+		}
+	}
+}
+
+func plannedResources(action string, manifests kubectl.ManifestList) []PlannedResource {
+	var planned []PlannedResource
+	for _, r := range manifests.Resources() {
+		planned = append(planned, PlannedResource{
+			Action:    action,
+			Kind:      r.Kind,
+			Namespace: r.Namespace,
+			Name:      r.Name,
+		})
+	}
+
+	return planned
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestWritePlan(t *testing.T) {
+	plan := &Plan{
+		Images:    []ImageSubstitution{{Image: "web", Tag: "web:v1"}},
+		Resources: []PlannedResource{{Action: ActionAdd, Kind: "Pod", Name: "web"}},
+	}
+
+	var tests = []struct {
+		description string
+		format      string
+		expected    string
+		shouldErr   bool
+	}{
+		{
+			description: "json",
+			format:      "json",
+			expected: `{
+  "images": [
+    {
+      "image": "web",
+      "tag": "web:v1"
+    }
+  ],
+  "resources": [
+    {
+      "action": "add",
+      "kind": "Pod",
+      "name": "web"
+    }
+  ]
+}
+`,
+		},
+		{
+			description: "text",
+			format:      "text",
+			expected:    "Images:\n  web -> web:v1\nResources:\n  + pod/web\n",
+		},
+		{
+			description: "unknown format",
+			format:      "yaml",
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var out bytes.Buffer
+			err := plan.Write(&out, test.format)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, out.String())
+		})
+	}
+}
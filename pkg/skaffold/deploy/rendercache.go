@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+)
+
+// renderCache keeps the last rendered manifests, until one of
+// the dependencies they were rendered from changes. It also remembers
+// the kustomize output of the last deploy, to detect the input changes
+// that don't change the output.
+type renderCache struct {
+	sync.Mutex
+
+	key       string
+	manifests kubectl.ManifestList
+
+	output         kubectl.ManifestList
+	deployedOutput kubectl.ManifestList
+	deployedBuilds []build.Artifact
+	deployedTarget deployTarget
+}
+
+// deployTarget is what an apply depends on besides the manifests and the
+// builds: the cluster, the namespace, the labels and the apply options.
+type deployTarget struct {
+	kubeContext string
+	namespace   string
+	labels      map[string]string
+	forceApply  bool
+}
+
+// get returns the manifests cached for the given key, if any.
+// The caller must hold the lock.
+func (c *renderCache) get(key string) (kubectl.ManifestList, bool) {
+	if key == "" || key != c.key {
+		return nil, false
+	}
+
+	return append(kubectl.ManifestList(nil), c.manifests...), true
+}
+
+// set caches the manifests for the given key. The caller must hold the lock.
+func (c *renderCache) set(key string, manifests kubectl.ManifestList) {
+	c.key = key
+	c.manifests = append(kubectl.ManifestList(nil), manifests...)
+}
+
+// read records the latest kustomize output, cached or not. The caller must hold the lock.
+func (c *renderCache) read(manifests kubectl.ManifestList) {
+	c.output = manifests
+}
+
+// unchangedSinceDeploy checks if the latest kustomize output, the builds
+// and the target are identical to those of the last deploy.
+func (c *renderCache) unchangedSinceDeploy(builds []build.Artifact, target deployTarget) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.deployedOutput != nil &&
+		reflect.DeepEqual(c.output, c.deployedOutput) &&
+		reflect.DeepEqual(builds, c.deployedBuilds) &&
+		reflect.DeepEqual(target, c.deployedTarget)
+}
+
+// retargeted checks if the target changed since the last deploy.
+func (c *renderCache) retargeted(target deployTarget) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.deployedOutput != nil && !reflect.DeepEqual(target, c.deployedTarget)
+}
+
+// deployed records that the latest kustomize output was deployed with the given builds and target.
+func (c *renderCache) deployed(builds []build.Artifact, target deployTarget) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.deployedOutput = c.output
+	c.deployedBuilds = builds
+	c.deployedTarget = target
+}
+
+// dependenciesKey identifies the state of a list of dependencies
+// by their modification time and size.
+func dependenciesKey(deps []string) string {
+	var key []string
+	for _, dep := range deps {
+		info, err := os.Stat(dep)
+		if err != nil {
+			key = append(key, dep+":missing")
+			continue
+		}
+
+		key = append(key, fmt.Sprintf("%s:%d:%d", dep, info.ModTime().UnixNano(), info.Size()))
+	}
+
+	return strings.Join(key, ",")
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestRenderCache(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+	tmpDir.Write("kustomization.yaml", "resources: [deployment.yaml]")
+	tmpDir.Write("deployment.yaml", deploymentWebYAML)
+
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = testutil.NewFakeCmds(
+		testutil.NewFakeCmdOut("kustomize build "+tmpDir.Root(), deploymentWebYAML, nil),
+		testutil.NewFakeCmdOut("kustomize build "+tmpDir.Root(), deploymentAppYaml, nil),
+	)
+
+	k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: tmpDir.Root()}, testKubeContext, &config.SkaffoldOptions{})
+
+	// The first read builds the kustomization
+	manifests, err := k.readManifests(context.Background())
+	testutil.CheckErrorAndDeepEqual(t, false, err, deploymentWebYAML, manifests.String())
+
+	// Nothing changed, the build is reused
+	manifests, err = k.readManifests(context.Background())
+	testutil.CheckErrorAndDeepEqual(t, false, err, deploymentWebYAML, manifests.String())
+
+	// A dependency changed, the kustomization is built again
+	tmpDir.Chtimes("deployment.yaml", time.Now().Add(time.Hour))
+	manifests, err = k.readManifests(context.Background())
+	testutil.CheckErrorAndDeepEqual(t, false, err, deploymentAppYaml, manifests.String())
+}
+
+// applyCounter fakes `kustomize build` and counts the applies.
+type applyCounter struct {
+	built   string
+	applies int
+}
+
+func (r *applyCounter) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	return []byte(r.built), nil
+}
+
+func (r *applyCounter) RunCmd(cmd *exec.Cmd) error {
+	for _, arg := range cmd.Args {
+		if arg == "apply" {
+			r.applies++
+		}
+	}
+	return nil
+}
+
+func TestKustomizeUnchangedOutput(t *testing.T) {
+	var tests = []struct {
+		description      string
+		reapplyUnchanged bool
+		secondBuild      string
+		change           func(k *KustomizeDeployer)
+		expectedApplies  int
+	}{
+		{
+			description:     "skip the apply",
+			secondBuild:     "leeroy-web:v1",
+			expectedApplies: 1,
+		},
+		{
+			description:      "apply anyway",
+			reapplyUnchanged: true,
+			secondBuild:      "leeroy-web:v1",
+			expectedApplies:  2,
+		},
+		{
+			description:     "images were rebuilt",
+			secondBuild:     "leeroy-web:v2",
+			expectedApplies: 2,
+		},
+		{
+			description:     "namespace changed",
+			secondBuild:     "leeroy-web:v1",
+			change:          func(k *KustomizeDeployer) { k.kubectl.Namespace = "other" },
+			expectedApplies: 2,
+		},
+		{
+			description:     "kube context changed",
+			secondBuild:     "leeroy-web:v1",
+			change:          func(k *KustomizeDeployer) { k.kubectl.KubeContext = "other-context" },
+			expectedApplies: 2,
+		},
+		{
+			description:     "labels changed",
+			secondBuild:     "leeroy-web:v1",
+			change:          func(k *KustomizeDeployer) { k.injectLabels(map[string]string{"run-id": "2"}) },
+			expectedApplies: 2,
+		},
+		{
+			description:     "forced apply",
+			secondBuild:     "leeroy-web:v1",
+			change:          func(k *KustomizeDeployer) { k.kubectl.ForceApply = true },
+			expectedApplies: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tmpDir, cleanup := testutil.NewTempDir(t)
+			defer cleanup()
+			tmpDir.Write("kustomization.yaml", "resources: [deployment.yaml]")
+			tmpDir.Write("deployment.yaml", deploymentWebYAML)
+
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			counter := &applyCounter{built: deploymentWebYAML}
+			util.DefaultExecCommand = counter
+
+			k := NewKustomizeDeployer("", &v1alpha3.KustomizeDeploy{KustomizePath: tmpDir.Root(), ReapplyUnchanged: test.reapplyUnchanged}, testKubeContext, &config.SkaffoldOptions{})
+			k.injectLabels(map[string]string{"run-id": "1"})
+
+			_, err := k.Deploy(context.Background(), ioutil.Discard, []build.Artifact{{ImageName: "leeroy-web", Tag: "leeroy-web:v1"}})
+			testutil.CheckError(t, false, err)
+
+			// An input changes but kustomize builds the same manifests.
+			tmpDir.Chtimes("deployment.yaml", time.Now().Add(time.Hour))
+			if test.change != nil {
+				test.change(k)
+			}
+			_, err = k.Deploy(context.Background(), ioutil.Discard, []build.Artifact{{ImageName: "leeroy-web", Tag: test.secondBuild}})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expectedApplies, counter.applies)
+		})
+	}
+}
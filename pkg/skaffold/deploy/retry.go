@@ -0,0 +1,64 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/pkg/errors"
+)
+
+// for testing
+var retryInterval = 2 * time.Second
+
+// retryBudget is a number of retries shared by all the phases of a deploy,
+// so that the total deploy time stays predictable when things are flaky.
+type retryBudget struct {
+	remaining int
+	retried   []string
+}
+
+// run runs fn and retries it while it fails and the budget isn't exhausted.
+func (b *retryBudget) run(ctx context.Context, out io.Writer, phase string, fn func() error) error {
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		if b.remaining <= 0 {
+			if len(b.retried) == 0 {
+				return err
+			}
+			return errors.Wrapf(err, "retry budget exhausted after %d retries (%s)", len(b.retried), strings.Join(b.retried, ", "))
+		}
+
+		b.remaining--
+		b.retried = append(b.retried, phase)
+		color.Default.Fprintf(out, "Retrying %s (%d retries left): %s\n", phase, b.remaining, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(retryInterval):
+		}
+	}
+}
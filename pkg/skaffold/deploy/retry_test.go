@@ -0,0 +1,95 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha3"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestRetryBudget(t *testing.T) {
+	defer func(d time.Duration) { retryInterval = d }(retryInterval)
+	retryInterval = 0
+
+	var tests = []struct {
+		description string
+		budget      int
+		command     util.Command
+		shouldErr   bool
+	}{
+		{
+			description: "no retries needed",
+			budget:      2,
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=Ready pod/leeroy-web", nil),
+			),
+		},
+		{
+			description: "retries shared by apply and readiness",
+			budget:      2,
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", fmt.Errorf("connection refused")),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=Ready pod/leeroy-web", fmt.Errorf("timeout")),
+				testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=Ready pod/leeroy-web", nil),
+			),
+		},
+		{
+			description: "budget exhausted",
+			budget:      1,
+			command: testutil.NewFakeCmds(
+				testutil.NewFakeCmdOut("kustomize build .", deploymentWebYAML, nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", fmt.Errorf("connection refused")),
+				testutil.NewFakeCmd("kubectl --context kubecontext apply -f -", nil),
+				testutil.NewFakeCmd("kubectl --context kubecontext wait --for=condition=Ready pod/leeroy-web", fmt.Errorf("timeout")),
+			),
+			shouldErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = test.command
+
+			cfg := &v1alpha3.KustomizeDeploy{
+				KustomizePath:    ".",
+				RetryBudget:      test.budget,
+				WaitForReadiness: &v1alpha3.ReadinessConfig{},
+			}
+			k := NewKustomizeDeployer("", cfg, testKubeContext, &config.SkaffoldOptions{})
+			_, err := k.Deploy(context.Background(), ioutil.Discard, nil)
+
+			testutil.CheckError(t, test.shouldErr, err)
+			if err != nil && !strings.Contains(err.Error(), "retry budget exhausted after 1 retries (apply)") {
+				t.Errorf("error should list the retries, got: %s", err)
+			}
+		})
+	}
+}
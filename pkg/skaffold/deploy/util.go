@@ -18,9 +18,11 @@ package deploy
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
@@ -39,24 +41,36 @@ func parseRuntimeObject(namespace string, b []byte) (Artifact, error) {
 	}, nil
 }
 
-func parseReleaseInfo(namespace string, b *bufio.Reader) []Artifact {
+// parseReleaseInfo decodes the documents of a yaml stream. Documents that
+// can't be decoded are logged and skipped, unless strict is set.
+func parseReleaseInfo(namespace string, b *bufio.Reader, strict bool) ([]Artifact, error) {
 	results := []Artifact{}
 	r := k8syaml.NewYAMLReader(b)
+	index := 0
 	for {
 		doc, err := r.Read()
 		if err == io.EOF {
 			break
 		}
-		if err != nil {
-			logrus.Infof("error parsing object from string: %s", err.Error())
+		if err == nil && len(bytes.TrimSpace(doc)) == 0 {
 			continue
 		}
-		obj, err := parseRuntimeObject(namespace, doc)
+
+		var obj Artifact
+		if err == nil {
+			obj, err = parseRuntimeObject(namespace, doc)
+		}
 		if err != nil {
-			logrus.Infof(err.Error())
-		} else {
-			results = append(results, obj)
+			if strict {
+				return nil, errors.Wrapf(err, "parsing object %d", index)
+			}
+			logrus.Infof("Skipping object %d: %s", index, err)
+			index++
+			continue
 		}
+
+		results = append(results, obj)
+		index++
 	}
-	return results
+	return results, nil
 }
@@ -16,13 +16,20 @@ limitations under the License.
 
 package docker
 
-import "github.com/docker/distribution/reference"
+import (
+	"strings"
+
+	"github.com/docker/distribution/reference"
+)
 
 // ImageReference is a parsed image name.
 type ImageReference struct {
 	BaseName       string
 	Tag            string
 	FullyQualified bool
+	// Registry is the explicit registry host of the image, if any.
+	// Images like `nginx` rely on the default registry.
+	Registry string
 }
 
 // ParseReference parses an image name to a reference.
@@ -51,5 +58,54 @@ func ParseReference(image string) (*ImageReference, error) {
 		BaseName:       baseName,
 		Tag:            tag,
 		FullyQualified: fullyQualified,
+		Registry:       registryHost(baseName),
 	}, nil
 }
+
+// registryHost returns the registry host of an image name, following the rule
+// docker uses: the first component is a host if it contains a `.` or a `:`,
+// or if it's `localhost`.
+func registryHost(name string) string {
+	i := strings.IndexRune(name, '/')
+	if i == -1 {
+		return ""
+	}
+
+	host := name[:i]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return ""
+	}
+
+	return host
+}
+
+// SameImage tells if two image references name the same image, once normalized
+// the way the kubelet reports them: `nginx` is `docker.io/library/nginx:latest`.
+// References that both have a digest are compared by digest, others by tag.
+func SameImage(a, b string) bool {
+	refA, errA := reference.ParseNormalizedNamed(a)
+	refB, errB := reference.ParseNormalizedNamed(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	if refA.Name() != refB.Name() {
+		return false
+	}
+
+	digestedA, okA := refA.(reference.Digested)
+	digestedB, okB := refB.(reference.Digested)
+	if okA && okB {
+		return digestedA.Digest() == digestedB.Digest()
+	}
+
+	return tagOf(reference.TagNameOnly(refA)) == tagOf(reference.TagNameOnly(refB))
+}
+
+func tagOf(ref reference.Named) string {
+	if tagged, ok := ref.(reference.Tagged); ok {
+		return tagged.Tag()
+	}
+
+	return ""
+}
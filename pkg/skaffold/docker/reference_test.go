@@ -29,6 +29,7 @@ func TestParseReference(t *testing.T) {
 		expectedName           string
 		expectedTag            string
 		expectedFullyQualified bool
+		expectedRegistry       string
 	}{
 		{
 			description:            "port and tag",
@@ -36,6 +37,7 @@ func TestParseReference(t *testing.T) {
 			expectedName:           "host:1234/user/container",
 			expectedTag:            "tag",
 			expectedFullyQualified: true,
+			expectedRegistry:       "host:1234",
 		},
 		{
 			description:            "port",
@@ -43,6 +45,7 @@ func TestParseReference(t *testing.T) {
 			expectedName:           "host:1234/user/container",
 			expectedTag:            "",
 			expectedFullyQualified: false,
+			expectedRegistry:       "host:1234",
 		},
 		{
 			description:            "tag",
@@ -64,6 +67,7 @@ func TestParseReference(t *testing.T) {
 			expectedName:           "gcr.io/k8s-skaffold/example",
 			expectedTag:            "",
 			expectedFullyQualified: true,
+			expectedRegistry:       "gcr.io",
 		},
 		{
 			description:            "docker library",
@@ -72,6 +76,19 @@ func TestParseReference(t *testing.T) {
 			expectedTag:            "latest",
 			expectedFullyQualified: false,
 		},
+		{
+			description:            "docker hub user",
+			image:                  "skaffold/example:v1",
+			expectedName:           "skaffold/example",
+			expectedTag:            "v1",
+			expectedFullyQualified: true,
+		},
+		{
+			description:      "localhost",
+			image:            "localhost/example",
+			expectedName:     "localhost/example",
+			expectedRegistry: "localhost",
+		},
 	}
 
 	for _, test := range tests {
@@ -81,6 +98,34 @@ func TestParseReference(t *testing.T) {
 			testutil.CheckErrorAndDeepEqual(t, false, err, test.expectedName, parsed.BaseName)
 			testutil.CheckDeepEqual(t, test.expectedTag, parsed.Tag)
 			testutil.CheckDeepEqual(t, test.expectedFullyQualified, parsed.FullyQualified)
+			testutil.CheckDeepEqual(t, test.expectedRegistry, parsed.Registry)
+		})
+	}
+}
+
+func TestSameImage(t *testing.T) {
+	digest := "sha256:81daf011d63b68cfa514ddab7741a1adddd59d3264118dfb0fd9266328bb8883"
+	otherDigest := "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+	var tests = []struct {
+		description string
+		a           string
+		b           string
+		expected    bool
+	}{
+		{description: "same", a: "gcr.io/k8s-skaffold/web:v1", b: "gcr.io/k8s-skaffold/web:v1", expected: true},
+		{description: "docker library", a: "nginx", b: "docker.io/library/nginx:latest", expected: true},
+		{description: "docker hub user", a: "skaffold/web:v1", b: "docker.io/skaffold/web:v1", expected: true},
+		{description: "tag and digest", a: "gcr.io/k8s-skaffold/web:v1@" + digest, b: "gcr.io/k8s-skaffold/web@" + digest, expected: true},
+		{description: "other digest", a: "gcr.io/k8s-skaffold/web:v1@" + digest, b: "gcr.io/k8s-skaffold/web@" + otherDigest},
+		{description: "other tag", a: "gcr.io/k8s-skaffold/web:v1", b: "gcr.io/k8s-skaffold/web:v2"},
+		{description: "other name", a: "gcr.io/k8s-skaffold/web:v1", b: "gcr.io/k8s-skaffold/app:v1"},
+		{description: "invalid", a: "INVALID", b: "INVALID", expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			testutil.CheckDeepEqual(t, test.expected, SameImage(test.a, test.b))
 		})
 	}
 }
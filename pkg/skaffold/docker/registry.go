@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+)
+
+// RegistryClient checks images on remote registries. Credentials are read
+// from the docker config, falling back to anonymous access.
+type RegistryClient struct {
+	base http.RoundTripper
+}
+
+// NewRegistryClient returns a RegistryClient that trusts the system CAs
+// and, optionally, the CAs of a PEM bundle.
+func NewRegistryClient(caBundle string) (*RegistryClient, error) {
+	if caBundle == "" {
+		return &RegistryClient{base: http.DefaultTransport}, nil
+	}
+
+	pem, err := ioutil.ReadFile(caBundle)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading CA bundle")
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.Errorf("no certificate found in CA bundle %s", caBundle)
+	}
+
+	return &RegistryClient{
+		base: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}, nil
+}
+
+// CheckImagesExist fails if any of the images is missing from its registry.
+// Registries are authenticated once, for all the images they host.
+func (c *RegistryClient) CheckImagesExist(images []string) error {
+	refs := map[string][]name.Reference{}
+	var registries []name.Registry
+	for _, image := range images {
+		ref, err := name.ParseReference(image, name.WeakValidation)
+		if err != nil {
+			return errors.Wrapf(err, "parsing image %s", image)
+		}
+
+		registry := ref.Context().Registry
+		if _, present := refs[registry.Name()]; !present {
+			registries = append(registries, registry)
+		}
+		refs[registry.Name()] = append(refs[registry.Name()], ref)
+	}
+
+	var missing []string
+	for _, registry := range registries {
+		auth, err := authn.DefaultKeychain.Resolve(registry)
+		if err != nil {
+			return errors.Wrapf(err, "getting credentials for %s", registry)
+		}
+
+		var scopes []string
+		for _, ref := range refs[registry.Name()] {
+			scopes = append(scopes, ref.Scope(transport.PullScope))
+		}
+
+		tr, err := transport.New(registry, auth, c.base, scopes)
+		if err != nil {
+			return errors.Wrapf(err, "authenticating to %s", registry)
+		}
+
+		for _, ref := range refs[registry.Name()] {
+			img, err := remote.Image(ref, remote.WithAuth(auth), remote.WithTransport(tr))
+			if err == nil {
+				_, err = img.RawManifest()
+			}
+			if err != nil {
+				missing = append(missing, ref.String())
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return errors.Errorf("images not found in their registry: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
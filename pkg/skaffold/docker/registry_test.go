@@ -0,0 +1,76 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestCheckImagesExist(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	// Anonymous access, without reading the user's docker config
+	defer os.Setenv("DOCKER_CONFIG", os.Getenv("DOCKER_CONFIG"))
+	os.Setenv("DOCKER_CONFIG", tmpDir.Root())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/", "/v2/app/manifests/v1":
+			w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	client, err := NewRegistryClient("")
+	testutil.CheckError(t, false, err)
+
+	err = client.CheckImagesExist([]string{registry + "/app:v1"})
+	testutil.CheckError(t, false, err)
+
+	err = client.CheckImagesExist([]string{registry + "/app:v1", registry + "/app:v2"})
+	testutil.CheckErrorAndDeepEqual(t, true, err, "images not found in their registry: "+registry+"/app:v2", err.Error())
+}
+
+func TestNewRegistryClientCABundle(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+	tmpDir.Write("invalid.pem", "not a certificate")
+
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	tmpDir.Write("ca.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})))
+
+	_, err := NewRegistryClient(tmpDir.Path("ca.pem"))
+	testutil.CheckError(t, false, err)
+
+	_, err = NewRegistryClient(tmpDir.Path("invalid.pem"))
+	testutil.CheckError(t, true, err)
+
+	_, err = NewRegistryClient(tmpDir.Path("missing.pem"))
+	testutil.CheckError(t, true, err)
+}
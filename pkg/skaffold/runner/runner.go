@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -56,6 +57,7 @@ type SkaffoldRunner struct {
 	opts         *config.SkaffoldOptions
 	watchFactory watch.Factory
 	builds       []build.Artifact
+	planner      deploy.Planner
 }
 
 // NewForConfig returns a new SkaffoldRunner for a SkaffoldConfig
@@ -76,11 +78,14 @@ func NewForConfig(opts *config.SkaffoldOptions, cfg *config.SkaffoldConfig) (*Sk
 		return nil, errors.Wrap(err, "parsing skaffold build config")
 	}
 
-	deployer, err := getDeployer(&cfg.Deploy, kubeContext, opts.Namespace)
+	deployer, err := getDeployer(&cfg.Deploy, configDir(opts.ConfigurationFile), kubeContext, opts)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing skaffold deploy config")
 	}
 
+	// The wrapped deployers don't plan.
+	planner, _ := deployer.(deploy.Planner)
+
 	deployer = deploy.WithLabels(deployer, opts, builder, deployer, tagger)
 	builder, deployer = WithTimings(builder, deployer)
 	if opts.Notification {
@@ -93,6 +98,7 @@ func NewForConfig(opts *config.SkaffoldOptions, cfg *config.SkaffoldConfig) (*Sk
 		Tagger:       tagger,
 		opts:         opts,
 		watchFactory: watch.NewWatcher,
+		planner:      planner,
 	}, nil
 }
 
@@ -115,12 +121,37 @@ func getBuilder(cfg *v1alpha3.BuildConfig, kubeContext string) (build.Builder, e
 	}
 }
 
-func getDeployer(cfg *v1alpha3.DeployConfig, kubeContext string, namespace string) (deploy.Deployer, error) {
+// configDir returns the folder containing the skaffold configuration
+// or an empty string if the configuration is not read from a local file.
+func configDir(configurationFile string) string {
+	switch {
+	case configurationFile == "", configurationFile == "-":
+		return ""
+	case strings.HasPrefix(configurationFile, "http://") || strings.HasPrefix(configurationFile, "https://"):
+		return ""
+	default:
+		return filepath.Dir(configurationFile)
+	}
+}
+
+func getDeployer(cfg *v1alpha3.DeployConfig, configDir string, kubeContext string, opts *config.SkaffoldOptions) (deploy.Deployer, error) {
+	if cfg.HelmDeploy != nil || cfg.KubectlDeploy != nil {
+		if opts.DryRun {
+			return nil, errors.New("dry-run is only supported by the kustomize deployer")
+		}
+		if opts.Selector != "" {
+			return nil, errors.New("selector is only supported by the kustomize deployer")
+		}
+		if len(opts.ImageOverrides) > 0 {
+			return nil, errors.New("image overrides are only supported by the kustomize deployer")
+		}
+	}
+
 	deployers := []deploy.Deployer{}
 
 	// HelmDeploy first, in case there are resources in Kubectl that depend on these...
 	if cfg.HelmDeploy != nil {
-		deployers = append(deployers, deploy.NewHelmDeployer(cfg.HelmDeploy, kubeContext, namespace))
+		deployers = append(deployers, deploy.NewHelmDeployer(cfg.HelmDeploy, kubeContext, opts.Namespace))
 	}
 
 	if cfg.KubectlDeploy != nil {
@@ -129,11 +160,11 @@ func getDeployer(cfg *v1alpha3.DeployConfig, kubeContext string, namespace strin
 		if err != nil {
 			return nil, errors.Wrap(err, "finding current directory")
 		}
-		deployers = append(deployers, deploy.NewKubectlDeployer(cwd, cfg.KubectlDeploy, kubeContext, namespace))
+		deployers = append(deployers, deploy.NewKubectlDeployer(cwd, cfg.KubectlDeploy, kubeContext, opts.Namespace))
 	}
 
 	if cfg.KustomizeDeploy != nil {
-		deployers = append(deployers, deploy.NewKustomizeDeployer(cfg.KustomizeDeploy, kubeContext, namespace))
+		deployers = append(deployers, deploy.NewKustomizeDeployer(configDir, cfg.KustomizeDeploy, kubeContext, opts))
 	}
 
 	if len(deployers) == 0 {
@@ -144,7 +175,7 @@ func getDeployer(cfg *v1alpha3.DeployConfig, kubeContext string, namespace strin
 		return deployers[0], nil
 	}
 
-	return deploy.NewMultiDeployer(deployers), nil
+	return deploy.NewMultiDeployer(deployers, cfg.Concurrency), nil
 }
 
 func getTagger(t v1alpha3.TagPolicy, customTag string) (tag.Tagger, error) {
@@ -186,6 +217,20 @@ func (r *SkaffoldRunner) Run(ctx context.Context, out io.Writer, artifacts []*v1
 	return r.TailLogs(ctx, out, artifacts, bRes)
 }
 
+// Plan prints what deploying the given builds would change, in the given format.
+func (r *SkaffoldRunner) Plan(ctx context.Context, out io.Writer, builds []build.Artifact, format string) error {
+	if r.planner == nil {
+		return errors.New("plan is only supported by the kustomize deployer")
+	}
+
+	plan, err := r.planner.Plan(ctx, ioutil.Discard, builds)
+	if err != nil {
+		return errors.Wrap(err, "planning deploy")
+	}
+
+	return plan.Write(out, format)
+}
+
 // TailLogs prints the logs for deployed artifacts.
 func (r *SkaffoldRunner) TailLogs(ctx context.Context, out io.Writer, artifacts []*v1alpha3.Artifact, bRes []build.Artifact) error {
 	if !r.opts.Tail {
@@ -112,7 +112,8 @@ type KanikoBuild struct {
 
 // DeployConfig contains all the configuration needed by the deploy steps
 type DeployConfig struct {
-	DeployType `yaml:",inline"`
+	DeployType  `yaml:",inline"`
+	Concurrency int `yaml:"concurrency,omitempty"`
 }
 
 // DeployType contains the specific implementation and parameters needed
@@ -128,6 +129,7 @@ type KubectlDeploy struct {
 	Manifests       []string     `yaml:"manifests,omitempty"`
 	RemoteManifests []string     `yaml:"remoteManifests,omitempty"`
 	Flags           KubectlFlags `yaml:"flags,omitempty"`
+	StrictParsing   bool         `yaml:"strictParsing,omitempty"`
 }
 
 // KubectlFlags describes additional options flags that are passed on the command
@@ -137,6 +139,10 @@ type KubectlFlags struct {
 	Global []string `yaml:"global,omitempty"`
 	Apply  []string `yaml:"apply,omitempty"`
 	Delete []string `yaml:"delete,omitempty"`
+
+	// Env is added to the environment of kubectl, for the auth proxies and
+	// the exec credential plugins referenced by the kubeconfig.
+	Env []string `yaml:"env,omitempty"`
 }
 
 // HelmDeploy contains the configuration needed for deploying with helm
@@ -145,8 +151,226 @@ type HelmDeploy struct {
 }
 
 type KustomizeDeploy struct {
-	KustomizePath string       `yaml:"kustomizePath,omitempty"`
-	Flags         KubectlFlags `yaml:"flags,omitempty"`
+	KustomizePath            string             `yaml:"kustomizePath,omitempty"`
+	Flags                    KubectlFlags       `yaml:"flags,omitempty"`
+	Owner                    *OwnerReference    `yaml:"owner,omitempty"`
+	Replicas                 *int               `yaml:"replicas,omitempty"`
+	AccurateDependencies     bool               `yaml:"accurateDependencies,omitempty"`
+	WaitForReadiness         *ReadinessConfig   `yaml:"waitForReadiness,omitempty"`
+	DeleteGracePeriodSeconds *int               `yaml:"deleteGracePeriodSeconds,omitempty"`
+	ForceDelete              bool               `yaml:"forceDelete,omitempty"`
+	Overlays                 []KustomizeOverlay `yaml:"overlays,omitempty"`
+	Validation               string             `yaml:"validation,omitempty"`
+	PostDeploy               *PostDeployHook    `yaml:"postDeploy,omitempty"`
+	DeployLock               *DeployLock        `yaml:"deployLock,omitempty"`
+	ServerSideApply          bool               `yaml:"serverSideApply,omitempty"`
+	StrictParsing            bool               `yaml:"strictParsing,omitempty"`
+	ImageLock                *ImageLock         `yaml:"imageLock,omitempty"`
+	LocalCluster             string             `yaml:"localCluster,omitempty"`
+	CRDApply                 *CRDApply          `yaml:"crdApply,omitempty"`
+	ImageNames               map[string]string  `yaml:"imageNames,omitempty"`
+	NoOverwriteKinds         []string           `yaml:"noOverwriteKinds,omitempty"`
+	StripFields              []string           `yaml:"stripFields,omitempty"`
+	ImageCheck               *ImageCheck        `yaml:"imageCheck,omitempty"`
+	ApplyBatchSize           int                `yaml:"applyBatchSize,omitempty"`
+	DevProbes                *DevProbes         `yaml:"devProbes,omitempty"`
+	CleanupByLabel           bool               `yaml:"cleanupByLabel,omitempty"`
+	DeployTimeout            string             `yaml:"deployTimeout,omitempty"`
+	ServerDryRun             bool               `yaml:"serverDryRun,omitempty"`
+	Env                      []string           `yaml:"env,omitempty"`
+	Lint                     *Lint              `yaml:"lint,omitempty"`
+	SkipUnsupportedAPIs      bool               `yaml:"skipUnsupportedAPIs,omitempty"`
+	ApplyByNamespace         bool               `yaml:"applyByNamespace,omitempty"`
+	RetryBudget              int                `yaml:"retryBudget,omitempty"`
+	ApplyRateLimit           *ApplyRateLimit    `yaml:"applyRateLimit,omitempty"`
+	SortKeys                 bool               `yaml:"sortKeys,omitempty"`
+	DeletePropagationPolicy  string             `yaml:"deletePropagationPolicy,omitempty"`
+	RegistryMirrors          *RegistryMirrors   `yaml:"registryMirrors,omitempty"`
+	AnnotateSource           bool               `yaml:"annotateSource,omitempty"`
+	CacheDir                 string             `yaml:"cacheDir,omitempty"`
+	RestartOnConfigChange    bool               `yaml:"restartOnConfigChange,omitempty"`
+	StrictDependencies       bool               `yaml:"strictDependencies,omitempty"`
+	Adopt                    *Adopt             `yaml:"adopt,omitempty"`
+	ApplyOutput              *ApplyOutput       `yaml:"applyOutput,omitempty"`
+	DriftCheck               bool               `yaml:"driftCheck,omitempty"`
+	EmptyBuilds              string             `yaml:"emptyBuilds,omitempty"`
+	History                  *History           `yaml:"history,omitempty"`
+	UnqualifiedImages        string             `yaml:"unqualifiedImages,omitempty"`
+	ForceApply               bool               `yaml:"forceApply,omitempty"`
+	ApplyCascade             string             `yaml:"applyCascade,omitempty"`
+	UnresolvedVars           string             `yaml:"unresolvedVars,omitempty"`
+	ApplyLogFormat           string             `yaml:"applyLogFormat,omitempty"`
+	CheckKustomizations      bool               `yaml:"checkKustomizations,omitempty"`
+	CRDsPath                 string             `yaml:"crdsPath,omitempty"`
+	DeleteCRDs               bool               `yaml:"deleteCRDs,omitempty"`
+	DeleteNamespaces         bool               `yaml:"deleteNamespaces,omitempty"`
+	ImageMatch               string             `yaml:"imageMatch,omitempty"`
+	NamespaceTemplate        string             `yaml:"namespaceTemplate,omitempty"`
+	CreateNamespace          bool               `yaml:"createNamespace,omitempty"`
+	Bundle                   *DeployBundle      `yaml:"bundle,omitempty"`
+	RecreateImmutable        bool               `yaml:"recreateImmutable,omitempty"`
+	ApplyKinds               []string           `yaml:"applyKinds,omitempty"`
+	ValidateSelectors        bool               `yaml:"validateSelectors,omitempty"`
+	ServerSideFallback       bool               `yaml:"serverSideFallback,omitempty"`
+	PruneFieldManager        string             `yaml:"pruneFieldManager,omitempty"`
+	DefaultResources         *DefaultResources  `yaml:"defaultResources,omitempty"`
+	Plugins                  *KustomizePlugins  `yaml:"plugins,omitempty"`
+	SkipContextCheck         bool               `yaml:"skipContextCheck,omitempty"`
+	ReapplyUnchanged         bool               `yaml:"reapplyUnchanged,omitempty"`
+	LabelInjection           string             `yaml:"labelInjection,omitempty"`
+	ReportImageDigests       bool               `yaml:"reportImageDigests,omitempty"`
+	Prune                    bool               `yaml:"prune,omitempty"`
+}
+
+// KustomizePlugins configures the plugins that `kustomize build` may run.
+// Dir is given as KUSTOMIZE_PLUGIN_HOME and enables the legacy Go and exec
+// plugins that it contains. Exec enables the exec KRM functions and Helm
+// the inflation of helm charts.
+type KustomizePlugins struct {
+	Dir  string `yaml:"dir,omitempty"`
+	Exec bool   `yaml:"exec,omitempty"`
+	Helm bool   `yaml:"helm,omitempty"`
+}
+
+// DefaultResources sets the CPU and memory requests and limits of the built
+// containers, or of every container with IncludeSidecars, that don't set them.
+// Requests and limits of the manifests are never overwritten.
+type DefaultResources struct {
+	Requests        map[string]string `yaml:"requests,omitempty"`
+	Limits          map[string]string `yaml:"limits,omitempty"`
+	IncludeSidecars bool              `yaml:"includeSidecars,omitempty"`
+}
+
+// DeployBundle records what a deploy applies to a file, or replays a recorded deploy.
+type DeployBundle struct {
+	Record string `yaml:"record,omitempty"`
+	Replay string `yaml:"replay,omitempty"`
+}
+
+// History records every deploy in a ConfigMap of the target namespace: the hash
+// of the manifests, the images and the resources. Name defaults to
+// `skaffold-history` and only the last Limit deploys are kept, 10 by default.
+type History struct {
+	Name  string `yaml:"name,omitempty"`
+	Limit int    `yaml:"limit,omitempty"`
+}
+
+// ApplyOutput captures the objects that the API server returns to
+// `kubectl apply -o json`, with their uid and resourceVersion, instead
+// of printing them. Format defaults to, and only supports, `json`.
+// The objects of the last deploy are written to File, if set, as a List.
+type ApplyOutput struct {
+	Format string `yaml:"format,omitempty"`
+	File   string `yaml:"file,omitempty"`
+}
+
+// Adopt takes over, on the first deploy, the resources created by other tools.
+// The resources matching Selector, a label selector, are applied server-side
+// with `--force-conflicts`. An empty selector adopts every resource.
+type Adopt struct {
+	Selector string `yaml:"selector,omitempty"`
+}
+
+// RegistryMirrors rewrites image references that go through a registry mirror,
+// such as a pull-through cache. Rewrites maps canonical registry prefixes,
+// like `docker.io`, to their mirror, like `mirror.internal/docker.io`.
+// Images are matched by their canonical name. Write is either `mirror`, the default,
+// or `canonical` and chooses the name that is written to the manifests.
+type RegistryMirrors struct {
+	Rewrites map[string]string `yaml:"rewrites,omitempty"`
+	Write    string            `yaml:"write,omitempty"`
+}
+
+// ApplyRateLimit limits the rate of the `kubectl apply` commands sent to the API server.
+// Burst defaults to 1.
+type ApplyRateLimit struct {
+	QPS   float64 `yaml:"qps,omitempty"`
+	Burst int     `yaml:"burst,omitempty"`
+}
+
+// Lint warns about common kubernetes anti-patterns in the rendered manifests.
+// Rules are: resource-limits, latest-tag, liveness-probe and privileged.
+type Lint struct {
+	Disable []string `yaml:"disable,omitempty"`
+}
+
+// DevProbes shortens the readiness and liveness probes of the built containers
+// during `skaffold dev`, for faster feedback. It is never applied by other commands.
+type DevProbes struct {
+	InitialDelaySeconds *int `yaml:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       *int `yaml:"periodSeconds,omitempty"`
+	IncludeSidecars     bool `yaml:"includeSidecars,omitempty"`
+}
+
+// ImageCheck checks that images exist in their registry before they are deployed.
+type ImageCheck struct {
+	CABundle string `yaml:"caBundle,omitempty"`
+}
+
+// CRDApply applies custom resources with validation disabled, right after
+// the CustomResourceDefinitions that register their schema.
+// With Revalidate, the custom resources are applied again, with validation,
+// once their definitions are established.
+type CRDApply struct {
+	Revalidate bool `yaml:"revalidate,omitempty"`
+}
+
+// ImageLock pins images to the digests listed in a lockfile.
+type ImageLock struct {
+	File   string `yaml:"file,omitempty"`
+	Strict bool   `yaml:"strict,omitempty"`
+}
+
+// DeployLock serializes concurrent deploys to the same namespace using a Lease.
+// Without a Timeout, a deploy fails right away if the lock is already held.
+type DeployLock struct {
+	Name          string `yaml:"name,omitempty"`
+	Timeout       string `yaml:"timeout,omitempty"`
+	LeaseDuration string `yaml:"leaseDuration,omitempty"`
+}
+
+// PostDeployHook lists shell commands that are run after a successful deploy.
+// Failing commands only fail the deploy if FailOnError is true.
+type PostDeployHook struct {
+	Commands    []string `yaml:"commands,omitempty"`
+	FailOnError bool     `yaml:"failOnError,omitempty"`
+}
+
+// KustomizeOverlay is a named kustomization path. Its labels are matched
+// against the overlay selector given at deploy time.
+type KustomizeOverlay struct {
+	Name   string            `yaml:"name"`
+	Path   string            `yaml:"path"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// ReadinessConfig configures how to wait for the deployed resources to be ready.
+// SkipKinds lists the kinds that shouldn't be waited on.
+// Conditions maps kinds, typically of custom resources, to the status
+// condition that is waited on with `kubectl wait --for=condition=...`.
+// ResourceTimeout bounds each check, in which case Timeout bounds the whole wait.
+// ImagePullRetries is the number of times a check is retried, every
+// ImagePullRetryDelay, while a built image can't be pulled from the registry.
+// WaitForClaims waits for the PersistentVolumeClaims mounted by a resource to be
+// bound before waiting on that resource.
+type ReadinessConfig struct {
+	Timeout             string            `yaml:"timeout,omitempty"`
+	ResourceTimeout     string            `yaml:"resourceTimeout,omitempty"`
+	SkipKinds           []string          `yaml:"skipKinds,omitempty"`
+	Conditions          map[string]string `yaml:"conditions,omitempty"`
+	ImagePullRetries    int               `yaml:"imagePullRetries,omitempty"`
+	ImagePullRetryDelay string            `yaml:"imagePullRetryDelay,omitempty"`
+	WaitForClaims       bool              `yaml:"waitForClaims,omitempty"`
+	Parallelism         int               `yaml:"parallelism,omitempty"`
+}
+
+// OwnerReference designates a parent object that every deployed resource
+// references so that they are garbage collected when the parent is deleted.
+type OwnerReference struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+	UID        string `yaml:"uid"`
 }
 
 type HelmRelease struct {
@@ -39,6 +39,9 @@ func (c *SkaffoldConfig) setDefaultValues() error {
 	c.setDefaultCloudBuildDockerImage()
 	c.setDefaultTagger()
 	c.setDefaultKustomizePath()
+	c.setDefaultReadinessTimeout()
+	c.setDefaultDeployLockName()
+	c.setDefaultDevProbes()
 	c.setDefaultKubectlManifests()
 	c.setDefaultKanikoTimeout()
 	if err := c.setDefaultKanikoNamespace(); err != nil {
@@ -100,6 +103,44 @@ func (c *SkaffoldConfig) setDefaultKustomizePath() {
 	}
 }
 
+func (c *SkaffoldConfig) setDefaultReadinessTimeout() {
+	kustomize := c.Deploy.KustomizeDeploy
+	if kustomize == nil || kustomize.WaitForReadiness == nil {
+		return
+	}
+
+	if kustomize.WaitForReadiness.Timeout == "" {
+		kustomize.WaitForReadiness.Timeout = constants.DefaultReadinessTimeout
+	}
+}
+
+func (c *SkaffoldConfig) setDefaultDeployLockName() {
+	kustomize := c.Deploy.KustomizeDeploy
+	if kustomize == nil || kustomize.DeployLock == nil {
+		return
+	}
+
+	if kustomize.DeployLock.Name == "" {
+		kustomize.DeployLock.Name = constants.DefaultDeployLockName
+	}
+}
+
+func (c *SkaffoldConfig) setDefaultDevProbes() {
+	kustomize := c.Deploy.KustomizeDeploy
+	if kustomize == nil || kustomize.DevProbes == nil {
+		return
+	}
+
+	if kustomize.DevProbes.InitialDelaySeconds == nil {
+		initialDelaySeconds := constants.DefaultDevProbeInitialDelaySeconds
+		kustomize.DevProbes.InitialDelaySeconds = &initialDelaySeconds
+	}
+	if kustomize.DevProbes.PeriodSeconds == nil {
+		periodSeconds := constants.DefaultDevProbePeriodSeconds
+		kustomize.DevProbes.PeriodSeconds = &periodSeconds
+	}
+}
+
 func (c *SkaffoldConfig) setDefaultKubectlManifests() {
 	if c.Deploy.KubectlDeploy != nil && len(c.Deploy.KubectlDeploy.Manifests) == 0 {
 		c.Deploy.KubectlDeploy.Manifests = constants.DefaultKubectlManifests
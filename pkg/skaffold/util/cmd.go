@@ -17,8 +17,10 @@ limitations under the License.
 package util
 
 import (
+	"io"
 	"io/ioutil"
 	"os/exec"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -46,6 +48,7 @@ func RunCmd(cmd *exec.Cmd) error {
 type Commander struct{}
 
 // RunCmdOut runs an exec.Command and returns the stdout and error.
+// Stderr is only captured if the command doesn't already write it somewhere.
 func (*Commander) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
 	logrus.Debugf("Running command: %s", cmd.Args)
 	stdoutPipe, err := cmd.StdoutPipe()
@@ -53,9 +56,12 @@ func (*Commander) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
 		return nil, err
 	}
 
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, err
+	var stderrPipe io.Reader = strings.NewReader("")
+	if cmd.Stderr == nil {
+		stderrPipe, err = cmd.StderrPipe()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if err := cmd.Start(); err != nil {
@@ -68,3 +68,42 @@ func (f *FakeCmd) RunCmd(cmd *exec.Cmd) error {
 
 	return f.err
 }
+
+// FakeCmds fakes a sequence of commands that are expected to run in order.
+type FakeCmds struct {
+	cmds []*FakeCmd
+}
+
+func NewFakeCmds(cmds ...*FakeCmd) *FakeCmds {
+	return &FakeCmds{
+		cmds: cmds,
+	}
+}
+
+func (f *FakeCmds) next(cmd *exec.Cmd) (*FakeCmd, error) {
+	if len(f.cmds) == 0 {
+		return nil, fmt.Errorf("Unexpected command: %s", strings.Join(cmd.Args, " "))
+	}
+
+	next := f.cmds[0]
+	f.cmds = f.cmds[1:]
+	return next, nil
+}
+
+func (f *FakeCmds) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	next, err := f.next(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return next.RunCmdOut(cmd)
+}
+
+func (f *FakeCmds) RunCmd(cmd *exec.Cmd) error {
+	next, err := f.next(cmd)
+	if err != nil {
+		return err
+	}
+
+	return next.RunCmd(cmd)
+}